@@ -0,0 +1,70 @@
+package acccore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// String returns the name of the account state
+func (state AccountState) String() string {
+	switch state {
+	case AccountActive:
+		return "active"
+	case AccountDebitFrozen:
+		return "debit-frozen"
+	case AccountCreditFrozen:
+		return "credit-frozen"
+	case AccountFrozen:
+		return "frozen"
+	case AccountClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("state %d", int(state))
+}
+
+// AccountStateChange is the audit record of an account moving from one state into another.
+type AccountStateChange struct {
+	// AccountNumber is the account whose state have changed
+	AccountNumber string
+	// From is the state before the change
+	From AccountState
+	// To is the state after the change
+	To AccountState
+	// Reason explains why the state was changed
+	Reason string
+	// Version is the account version produced by this change
+	Version int64
+	// ChangeTime is the time of the change
+	ChangeTime time.Time
+	// ChangeBy is the user who changed the state
+	ChangeBy string
+}
+
+// checkAccountState returns ErrAccountClosed or ErrAccountFrozen if the account state rejects a transaction of the alignment.
+func checkAccountState(state AccountState, alignment Alignment) error {
+	switch {
+	case state == AccountClosed:
+		return ErrAccountClosed
+	case state == AccountFrozen,
+		state == AccountDebitFrozen && alignment == DEBIT,
+		state == AccountCreditFrozen && alignment == CREDIT:
+		return ErrAccountFrozen
+	}
+	return nil
+}
+
+// validateStateChange checks the account can move from its state into the new state.
+func validateStateChange(from, to AccountState, balance decimal.Decimal) error {
+	if to < AccountActive || to > AccountClosed {
+		return ErrAccountStateUnknown
+	}
+	if from == AccountClosed && to != AccountClosed {
+		return ErrAccountClosed
+	}
+	if to == AccountClosed && !balance.IsZero() {
+		return ErrAccountBalanceNotZero
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package acccore
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// accountStatementTimeFormat is the format of the times in the rendered account statements
+	accountStatementTimeFormat = "2006-01-02 15:04:05"
+)
+
+// AccountStatementLine is a single transaction in an account statement.
+type AccountStatementLine struct {
+	// Transaction is the transaction of this line
+	Transaction Transaction
+	// Debit is the transaction amount if it is a DEBIT transaction, zero otherwise
+	Debit decimal.Decimal
+	// Credit is the transaction amount if it is a CREDIT transaction, zero otherwise
+	Credit decimal.Decimal
+	// Balance is the running balance of the account after this transaction
+	Balance decimal.Decimal
+}
+
+// AccountStatement lists the transactions of an account within a period, from its opening balance to its closing balance.
+type AccountStatement struct {
+	// Account is the account of this statement
+	Account Account
+	// Currency is the currency of the account
+	Currency string
+	// From is the beginning of the period, inclusive
+	From time.Time
+	// Until is the end of the period, inclusive
+	Until time.Time
+	// OpeningBalance is the balance of the account right before the period
+	OpeningBalance decimal.Decimal
+	// Lines are the transactions within the period, ordered by their transaction time
+	Lines []*AccountStatementLine
+	// TotalDebit is the sum of all the DEBIT transactions within the period
+	TotalDebit decimal.Decimal
+	// TotalCredit is the sum of all the CREDIT transactions within the period
+	TotalCredit decimal.Decimal
+	// ClosingBalance is the balance of the account at the end of the period
+	ClosingBalance decimal.Decimal
+}
+
+// CreateAccountStatement creates the statement of the account for the period between the `from` and `until` time inclusive.
+func (acc *Accounting) CreateAccountStatement(context context.Context, accountNumber string, from, until time.Time) (*AccountStatement, error) {
+	account, err := acc.GetAccountManager().GetAccountByID(context, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	opening, err := acc.GetTransactionManager().GetBalanceAt(context, accountNumber, from.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &AccountStatement{
+		Account:        account,
+		Currency:       account.GetCurrency(),
+		From:           from,
+		Until:          until,
+		OpeningBalance: opening,
+		Lines:          make([]*AccountStatementLine, 0),
+		TotalDebit:     decimal.Zero,
+		TotalCredit:    decimal.Zero,
+	}
+	balance := opening
+	for trx, err := range acc.GetTransactionManager().StreamTransactionsOnAccount(context, from, until, account) {
+		if err != nil {
+			return nil, err
+		}
+		line := &AccountStatementLine{
+			Transaction: trx,
+			Debit:       decimal.Zero,
+			Credit:      decimal.Zero,
+		}
+		if trx.GetAlignment() == DEBIT {
+			line.Debit = trx.GetAmount()
+			statement.TotalDebit = statement.TotalDebit.Add(trx.GetAmount())
+		} else {
+			line.Credit = trx.GetAmount()
+			statement.TotalCredit = statement.TotalCredit.Add(trx.GetAmount())
+		}
+		if trx.GetAlignment() == account.GetAlignment() {
+			balance = balance.Add(trx.GetAmount())
+		} else {
+			balance = balance.Sub(trx.GetAmount())
+		}
+		line.Balance = balance
+		statement.Lines = append(statement.Lines, line)
+	}
+	statement.ClosingBalance = balance
+	return statement, nil
+}
+
+// RenderAccountStatement will render the account statement into plain text
+func (acc *Accounting) RenderAccountStatement(context context.Context, statement *AccountStatement) string {
+	var buff bytes.Buffer
+	table := tablewriter.NewWriter(&buff)
+	table.SetHeader([]string{"Time", "TRX ID", "Description", "DEBIT", "CREDIT", "BALANCE"})
+	table.Append([]string{"", "", "Opening Balance", "", "", statement.OpeningBalance.String()})
+	for _, line := range statement.Lines {
+		debit, credit := statementAmounts(line)
+		table.Append([]string{line.Transaction.GetTransactionTime().Format(accountStatementTimeFormat), line.Transaction.GetTransactionID(),
+			line.Transaction.GetDescription(), debit, credit, line.Balance.String()})
+	}
+	table.SetFooter([]string{"", "", "Closing Balance", statement.TotalDebit.String(), statement.TotalCredit.String(), statement.ClosingBalance.String()})
+
+	buff.WriteString(fmt.Sprintf("Account Number    : %s\n", statement.Account.GetAccountNumber()))
+	buff.WriteString(fmt.Sprintf("Account Name      : %s\n", statement.Account.GetName()))
+	buff.WriteString(fmt.Sprintf("Currency          : %s\n", statement.Currency))
+	buff.WriteString(fmt.Sprintf("Period From       : %s\n", statement.From.Format(accountStatementTimeFormat)))
+	buff.WriteString(fmt.Sprintf("             To   : %s\n", statement.Until.Format(accountStatementTimeFormat)))
+	table.Render()
+	return buff.String()
+}
+
+// WriteCSV writes the account statement as CSV, with the opening balance as the first row
+// and the totals with the closing balance as the last row.
+func (statement *AccountStatement) WriteCSV(writer io.Writer) error {
+	w := csv.NewWriter(writer)
+	rows := [][]string{
+		{"time", "transaction_id", "journal_id", "description", "debit", "credit", "balance"},
+		{statement.From.Format(time.RFC3339), "", "", "Opening Balance", "", "", statement.OpeningBalance.String()},
+	}
+	for _, line := range statement.Lines {
+		debit, credit := statementAmounts(line)
+		rows = append(rows, []string{line.Transaction.GetTransactionTime().Format(time.RFC3339), line.Transaction.GetTransactionID(),
+			line.Transaction.GetJournalID(), line.Transaction.GetDescription(), debit, credit, line.Balance.String()})
+	}
+	rows = append(rows, []string{statement.Until.Format(time.RFC3339), "", "", "Closing Balance",
+		statement.TotalDebit.String(), statement.TotalCredit.String(), statement.ClosingBalance.String()})
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// accountStatementHTML is the template of the HTML account statement
+var accountStatementHTML = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format(accountStatementTimeFormat) },
+	"amounts": func(line *AccountStatementLine) []string {
+		debit, credit := statementAmounts(line)
+		return []string{debit, credit}
+	},
+}).Parse(`<table class="account-statement">
+<caption>{{.Account.GetName}} ({{.Account.GetAccountNumber}}) {{.Currency}}, {{time .From}} - {{time .Until}}</caption>
+<thead><tr><th>Time</th><th>Transaction</th><th>Description</th><th>Debit</th><th>Credit</th><th>Balance</th></tr></thead>
+<tbody>
+<tr class="opening"><td></td><td></td><td>Opening Balance</td><td></td><td></td><td>{{.OpeningBalance}}</td></tr>
+{{range .Lines}}{{$amounts := amounts .}}<tr><td>{{time .Transaction.GetTransactionTime}}</td><td>{{.Transaction.GetTransactionID}}</td><td>{{.Transaction.GetDescription}}</td><td>{{index $amounts 0}}</td><td>{{index $amounts 1}}</td><td>{{.Balance}}</td></tr>
+{{end}}</tbody>
+<tfoot><tr class="closing"><td></td><td></td><td>Closing Balance</td><td>{{.TotalDebit}}</td><td>{{.TotalCredit}}</td><td>{{.ClosingBalance}}</td></tr></tfoot>
+</table>
+`))
+
+// WriteHTML writes the account statement as an HTML table. All the texts are escaped.
+func (statement *AccountStatement) WriteHTML(writer io.Writer) error {
+	return accountStatementHTML.Execute(writer, statement)
+}
+
+// statementAmounts returns the debit and credit column texts of the line, the column not used by the transaction is empty.
+func statementAmounts(line *AccountStatementLine) (string, string) {
+	if line.Transaction.GetAlignment() == DEBIT {
+		return line.Debit.String(), ""
+	}
+	return "", line.Credit.String()
+}
@@ -0,0 +1,91 @@
+package acccore
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testAccountStatement(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+
+	reserve, err := acc.CreateNewAccount(ctx, "RESERVE", "Points Reserve", "Points issuance", "1.1", "PTS", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "WALLET", "Points Wallet", "User points", "2.1", "PTS", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	post := func(description string, walletAlignment Alignment, amount int64) {
+		reserveAlignment := DEBIT
+		if walletAlignment == DEBIT {
+			reserveAlignment = CREDIT
+		}
+		_, err := acc.CreateNewJournal(ctx, description, []TransactionInfo{
+			{AccountNumber: reserve.GetAccountNumber(), Description: description, TxType: reserveAlignment, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: wallet.GetAccountNumber(), Description: description, TxType: walletAlignment, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		assert.NoError(t, err)
+	}
+
+	post("Welcome bonus", CREDIT, 100)
+	time.Sleep(2 * time.Millisecond)
+	from := time.Now()
+	post("Purchase reward", CREDIT, 50)
+	post("Redeem voucher", DEBIT, 30)
+	post("<b>Promo</b>, \"spring\"", CREDIT, 5)
+	until := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	post("Next period", CREDIT, 1000)
+
+	statement, err := acc.CreateAccountStatement(ctx, wallet.GetAccountNumber(), from, until)
+	assert.NoError(t, err)
+	t.Log(acc.RenderAccountStatement(ctx, statement))
+	assert.Equal(t, "PTS", statement.Currency)
+	assert.True(t, statement.OpeningBalance.Equal(decimal.NewFromInt(100)))
+	assert.Len(t, statement.Lines, 3)
+	assert.True(t, statement.Lines[0].Balance.Equal(decimal.NewFromInt(150)))
+	assert.True(t, statement.Lines[1].Debit.Equal(decimal.NewFromInt(30)))
+	assert.True(t, statement.Lines[1].Balance.Equal(decimal.NewFromInt(120)))
+	assert.True(t, statement.TotalDebit.Equal(decimal.NewFromInt(30)))
+	assert.True(t, statement.TotalCredit.Equal(decimal.NewFromInt(55)))
+	assert.True(t, statement.ClosingBalance.Equal(decimal.NewFromInt(125)))
+	for _, line := range statement.Lines {
+		assert.True(t, line.Balance.Equal(line.Transaction.GetAccountBalance()))
+	}
+
+	var buff bytes.Buffer
+	assert.NoError(t, statement.WriteCSV(&buff))
+	records, err := csv.NewReader(&buff).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 6)
+	assert.Equal(t, "100", records[1][6])
+	assert.Equal(t, "<b>Promo</b>, \"spring\"", records[4][3])
+	assert.Equal(t, []string{"30", "55", "125"}, records[5][4:])
+
+	buff.Reset()
+	assert.NoError(t, statement.WriteHTML(&buff))
+	t.Log(buff.String())
+	assert.Contains(t, buff.String(), "&lt;b&gt;Promo&lt;/b&gt;")
+	assert.NotContains(t, buff.String(), "<b>")
+	assert.Contains(t, buff.String(), "<td>125</td>")
+
+	statement, err = acc.CreateAccountStatement(ctx, wallet.GetAccountNumber(), until.Add(time.Hour), until.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, statement.Lines, 0)
+	assert.True(t, statement.OpeningBalance.Equal(decimal.NewFromInt(1125)))
+	assert.True(t, statement.ClosingBalance.Equal(statement.OpeningBalance))
+
+	_, err = acc.CreateAccountStatement(ctx, "UNKNOWN", from, until)
+	assert.ErrorIs(t, err, ErrAccountIDNotFound)
+}
+
+func TestAccounting_AccountStatement(t *testing.T) {
+	testAccountStatement(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestAccounting_AccountStatementSQL(t *testing.T) {
+	testAccountStatement(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
@@ -2,8 +2,10 @@ package acccore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
 	"time"
 )
 
@@ -23,6 +25,12 @@ type Accounting struct {
 	transactionManager TransactionManager
 	journalManager     JournalManager
 	uniqueIDGenerator  UniqueIDGenerator
+	coaManager         COAManager
+	holdManager        HoldManager
+	exchangeManager    ExchangeManager
+	fxPositionAccounts map[string]string
+
+	fxRevaluationAccounts map[string]FXRevaluationAccounts
 }
 
 // GetAccountManager returns account manager
@@ -45,8 +53,89 @@ func (acc *Accounting) GetUniqueIDGenerator() UniqueIDGenerator {
 	return acc.uniqueIDGenerator
 }
 
+// GetCOAManager returns the chart of accounts manager, nil if not set
+func (acc *Accounting) GetCOAManager() COAManager {
+	return acc.coaManager
+}
+
+// SetCOAManager sets the chart of accounts manager.
+// Once set, new accounts must refer to a COA code that exist in the chart of accounts.
+func (acc *Accounting) SetCOAManager(coaManager COAManager) *Accounting {
+	acc.coaManager = coaManager
+	return acc
+}
+
+// GetHoldManager returns the fund hold manager, nil if not set
+func (acc *Accounting) GetHoldManager() HoldManager {
+	return acc.holdManager
+}
+
+// SetHoldManager sets the fund hold manager, needed to place, capture and release holds.
+func (acc *Accounting) SetHoldManager(holdManager HoldManager) *Accounting {
+	acc.holdManager = holdManager
+	return acc
+}
+
+// GetExchangeManager returns the exchange manager, nil if not set
+func (acc *Accounting) GetExchangeManager() ExchangeManager {
+	return acc.exchangeManager
+}
+
+// SetExchangeManager sets the exchange manager, needed to transfer between accounts of different currencies.
+func (acc *Accounting) SetExchangeManager(exchangeManager ExchangeManager) *Accounting {
+	acc.exchangeManager = exchangeManager
+	return acc
+}
+
+// GetFXPositionAccount returns the number of the FX position account of the currency, empty if not set
+func (acc *Accounting) GetFXPositionAccount(currency string) string {
+	return acc.fxPositionAccounts[currency]
+}
+
+// SetFXPositionAccount sets the account holding the FX position of the currency.
+// Cross-currency transfers go through the FX position accounts of both currencies, which must have the Currency they hold.
+func (acc *Accounting) SetFXPositionAccount(currency, accountNumber string) *Accounting {
+	if acc.fxPositionAccounts == nil {
+		acc.fxPositionAccounts = make(map[string]string)
+	}
+	acc.fxPositionAccounts[currency] = accountNumber
+	return acc
+}
+
+// CreateNewCOA creates a new node in the chart of accounts, under the parent code. An empty parent code creates a root node.
+// The alignment is the one expected from the accounts under this COA.
+func (acc *Accounting) CreateNewCOA(context context.Context, code, parentCode, name, description string, category COACategory, alignment Alignment, creator string) (COA, error) {
+	if acc.GetCOAManager() == nil {
+		return nil, ErrCOAManagerNotSet
+	}
+	coa := acc.GetCOAManager().NewCOA(context).SetCode(code).SetParentCode(parentCode).
+		SetName(name).SetDescription(description).SetCategory(category).SetAlignment(alignment).
+		SetCreateBy(creator).SetCreateTime(time.Now())
+	err := acc.GetCOAManager().PersistCOA(context, coa)
+	if err != nil {
+		return nil, err
+	}
+	return coa, nil
+}
+
 // CreateNewAccount creates a new account
+// If the chart of accounts manager is set, the COA code must exist in the chart of accounts,
+// and the alignment must be the one expected from the accounts under that COA.
 func (acc *Accounting) CreateNewAccount(context context.Context, accountNumber, name, description, coa string, currency string, alignment Alignment, creator string) (Account, error) {
+	if acc.GetCOAManager() != nil {
+		node, err := acc.GetCOAManager().GetCOAByCode(context, coa)
+		if errors.Is(err, ErrCOANotFound) {
+			logrus.Errorf("error creating account %s. COA %s is not in the chart of accounts", name, coa)
+			return nil, ErrAccountCOANotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if node.GetAlignment() != alignment {
+			logrus.Errorf("error creating account %s. COA %s expects %d alignment, got %d", name, coa, node.GetAlignment(), alignment)
+			return nil, ErrAccountCOAAlignmentMismatch
+		}
+	}
 	account := acc.GetAccountManager().NewAccount(context).
 		SetName(name).SetDescription(description).SetCOA(coa).
 		SetCurrency(currency).SetAlignment(alignment).
@@ -63,6 +152,27 @@ func (acc *Accounting) CreateNewAccount(context context.Context, accountNumber,
 	return account, nil
 }
 
+// FreezeAccount freezes the account for the transactions rejected by the state, AccountDebitFrozen, AccountCreditFrozen or AccountFrozen.
+// The reason is recorded in the account state changes.
+func (acc *Accounting) FreezeAccount(context context.Context, accountNumber string, state AccountState, reason, editor string) error {
+	if state != AccountDebitFrozen && state != AccountCreditFrozen && state != AccountFrozen {
+		return ErrAccountStateUnknown
+	}
+	return acc.GetAccountManager().ChangeAccountState(context, accountNumber, state, reason, editor)
+}
+
+// UnfreezeAccount makes the account accept all transactions again.
+// The reason is recorded in the account state changes.
+func (acc *Accounting) UnfreezeAccount(context context.Context, accountNumber, reason, editor string) error {
+	return acc.GetAccountManager().ChangeAccountState(context, accountNumber, AccountActive, reason, editor)
+}
+
+// CloseAccount closes the account for good, it will not accept any transaction anymore.
+// The account Balance must be zero, otherwise ErrAccountBalanceNotZero is returned.
+func (acc *Accounting) CloseAccount(context context.Context, accountNumber, reason, editor string) error {
+	return acc.GetAccountManager().ChangeAccountState(context, accountNumber, AccountClosed, reason, editor)
+}
+
 // TransactionInfo transaction info details
 type TransactionInfo struct {
 	AccountNumber string
@@ -73,15 +183,48 @@ type TransactionInfo struct {
 
 // CreateNewJournal creates a new journal
 func (acc *Accounting) CreateNewJournal(context context.Context, description string, transactions []TransactionInfo, creator string) (Journal, error) {
+	return acc.CreateNewJournalWithIdempotencyKey(context, "", description, transactions, creator)
+}
+
+// CreateNewJournalWithIdempotencyKey creates a new journal identified by the idempotency key, so a request can be safely retried.
+// If a journal was already created with the same key and the same description and transactions, that original journal is returned.
+// If the journal created with the same key have different description or transactions, ErrJournalIdempotencyKeyConflict is returned.
+// An empty key creates a new journal on every call, just like CreateNewJournal.
+func (acc *Accounting) CreateNewJournalWithIdempotencyKey(context context.Context, idempotencyKey, description string, transactions []TransactionInfo, creator string) (Journal, error) {
+	if len(idempotencyKey) > 0 {
+		original, err := acc.getIdempotentJournal(context, idempotencyKey, description, transactions)
+		if !errors.Is(err, ErrJournalIDNotFound) {
+			return original, err
+		}
+	}
+
+	journal := acc.newJournal(context, idempotencyKey, description, transactions, creator)
+
+	err := acc.postJournal(context, journal)
+	if errors.Is(err, ErrJournalIdempotencyKeyAlreadyUsed) {
+		// a concurrent request with the same key got in first.
+		original, lookupErr := acc.getIdempotentJournal(context, idempotencyKey, description, transactions)
+		if errors.Is(lookupErr, ErrJournalIDNotFound) {
+			return nil, err
+		}
+		return original, lookupErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// newJournal creates the journal and its Transactions, ready to be posted.
+func (acc *Accounting) newJournal(context context.Context, idempotencyKey, description string, transactions []TransactionInfo, creator string) Journal {
 	journal := acc.GetJournalManager().NewJournal(context).SetDescription(description)
 
 	journal.SetJournalID(acc.GetUniqueIDGenerator().NewUniqueID()).SetCreateBy(creator).
 		SetCreateTime(time.Now()).SetJournalingTime(time.Now()).
-		SetReversal(false).SetReversedJournal(nil)
+		SetReversal(false).SetReversedJournal(nil).SetIdempotencyKey(idempotencyKey)
 
 	transacs := make([]Transaction, 0)
 
-	// make sure all Transactions have accounts of the same Currency
 	for _, txinfo := range transactions {
 		newTransaction := acc.GetTransactionManager().NewTransaction(context).SetCreateBy(creator).SetCreateTime(time.Now()).
 			SetDescription(txinfo.Description).SetAccountNumber(txinfo.AccountNumber).SetAmount(txinfo.Amount).
@@ -90,26 +233,74 @@ func (acc *Accounting) CreateNewJournal(context context.Context, description str
 		transacs = append(transacs, newTransaction)
 	}
 
-	journal.SetTransactions(transacs)
+	return journal.SetTransactions(transacs)
+}
 
+// getIdempotentJournal returns the journal created with the idempotency key if it have the same description and transactions.
+// It returns ErrJournalIDNotFound if no journal is created with the key, or ErrJournalIdempotencyKeyConflict if the journal differs.
+func (acc *Accounting) getIdempotentJournal(context context.Context, idempotencyKey, description string, transactions []TransactionInfo) (Journal, error) {
+	original, err := acc.GetJournalManager().GetJournalByIdempotencyKey(context, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if original.GetDescription() != description || len(original.GetTransactions()) != len(transactions) {
+		logrus.Errorf("error creating journal. idempotency key %s is used by journal %s with different content", idempotencyKey, original.GetJournalID())
+		return nil, ErrJournalIdempotencyKeyConflict
+	}
+	for _, txinfo := range transactions {
+		found := false
+		for _, trx := range original.GetTransactions() {
+			if trx.GetAccountNumber() == txinfo.AccountNumber && trx.GetAlignment() == txinfo.TxType &&
+				trx.GetAmount().Equal(txinfo.Amount) && trx.GetDescription() == txinfo.Description {
+				found = true
+				break
+			}
+		}
+		if !found {
+			logrus.Errorf("error creating journal. idempotency key %s is used by journal %s with different transactions", idempotencyKey, original.GetJournalID())
+			return nil, ErrJournalIdempotencyKeyConflict
+		}
+	}
+	return original, nil
+}
+
+// postJournal persists the journal and then commits it. If the commit failed, the persisted journal is cancelled.
+func (acc *Accounting) postJournal(context context.Context, journal Journal) error {
+	return acc.postJournalWith(context, journal, nil)
+}
+
+// postJournalWith persists the journal and then commits it using the commit function, which may change other records
+// along with the journal, or using the journal manager if the commit function is nil. Journal managers acting on
+// committed journals, like HookedJournalManager, commit through the commit function themselves, so they still act on the journal.
+// If the commit failed, the persisted journal is cancelled.
+func (acc *Accounting) postJournalWith(context context.Context, journal Journal, commit func(context context.Context, journal Journal) error) error {
 	err := acc.GetJournalManager().PersistJournal(context, journal)
 	if err != nil {
+		return err
+	}
+	committer, isCommitter := acc.GetJournalManager().(journalCommitter)
+	switch {
+	case commit == nil:
 		err = acc.GetJournalManager().CommitJournal(context, journal)
-		if err != nil {
-			err = acc.GetJournalManager().CancelJournal(context, journal)
-			return nil, err
+	case isCommitter:
+		err = committer.commitJournalWith(context, journal, commit)
+	default:
+		err = commit(context, journal)
+	}
+	if err != nil {
+		if cancelErr := acc.GetJournalManager().CancelJournal(context, journal); cancelErr != nil {
+			logrus.Errorf("error cancelling journal %s after failed commit. got %s", journal.GetJournalID(), cancelErr.Error())
 		}
-		fmt.Println("No error but no content")
-		return nil, fmt.Errorf("commit journal raised no error but no content")
+		return err
 	}
-	return journal, nil
+	return nil
 }
 
 // CreateReversal creats a reversal
 func (acc *Accounting) CreateReversal(context context.Context, description string, reversed Journal, creator string) (Journal, error) {
 	journal := acc.GetJournalManager().NewJournal(context).SetDescription(description)
 	journal.SetJournalID(acc.GetUniqueIDGenerator().NewUniqueID()).SetCreateBy(creator).SetCreateTime(time.Now()).SetJournalingTime(time.Now()).
-		SetReversal(true).SetReversedJournal(reversed)
+		SetReversal(true).SetReversedJournal(reversed).SetExchangeRate(reversed.GetExchangeRate())
 
 	transacs := make([]Transaction, 0)
 
@@ -121,7 +312,7 @@ func (acc *Accounting) CreateReversal(context context.Context, description strin
 		}
 
 		newTransaction := acc.GetTransactionManager().NewTransaction(context).SetCreateBy(creator).SetCreateTime(time.Now()).
-			SetDescription(fmt.Sprintf("%s - reversed", txinfo.GetDescription())).SetAccountNumber(txinfo.GetAccountNumber()).
+			SetDescription(fmt.Sprintf("%s - reversed", txinfo.GetDescription())).SetAccountNumber(txinfo.GetAccountNumber()).SetAmount(txinfo.GetAmount()).
 			SetTransactionTime(time.Now()).SetAlignment(tx).SetTransactionID(acc.GetUniqueIDGenerator().NewUniqueID())
 
 		transacs = append(transacs, newTransaction)
@@ -129,13 +320,8 @@ func (acc *Accounting) CreateReversal(context context.Context, description strin
 
 	journal.SetTransactions(transacs)
 
-	err := acc.GetJournalManager().PersistJournal(context, journal)
+	err := acc.postJournal(context, journal)
 	if err != nil {
-		err = acc.GetJournalManager().CommitJournal(context, journal)
-		if err != nil {
-			err = acc.GetJournalManager().CancelJournal(context, journal)
-			return nil, err
-		}
 		return nil, err
 	}
 	return journal, nil
@@ -2,8 +2,11 @@ package acccore
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 	"time"
 )
@@ -154,3 +157,892 @@ func TestAccounting_CreateNewJournal(t *testing.T) {
 		t.Log(render)
 	}
 }
+
+// testTwoPhaseCommit checks that a persisted journal stays invisible until committed,
+// and that a cancelled journal leaves no trace, using the managers behind the accounting.
+func testTwoPhaseCommit(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	jm := acc.GetJournalManager()
+	tm := acc.GetTransactionManager()
+	am := acc.GetAccountManager()
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	newJournal := func() Journal {
+		journalID := acc.GetUniqueIDGenerator().NewUniqueID()
+		debit := tm.NewTransaction(ctx).SetTransactionID(acc.GetUniqueIDGenerator().NewUniqueID()).SetJournalID(journalID).
+			SetAccountNumber(reserve.GetAccountNumber()).SetAlignment(DEBIT).SetAmount(decimal.NewFromInt(100)).SetCreateBy("aCreator")
+		credit := tm.NewTransaction(ctx).SetTransactionID(acc.GetUniqueIDGenerator().NewUniqueID()).SetJournalID(journalID).
+			SetAccountNumber(wallet.GetAccountNumber()).SetAlignment(CREDIT).SetAmount(decimal.NewFromInt(100)).SetCreateBy("aCreator")
+		return jm.NewJournal(ctx).SetJournalID(journalID).SetDescription("Topup").SetCreateBy("aCreator").
+			SetTransactions([]Transaction{debit, credit})
+	}
+	balanceOf := func(account Account) decimal.Decimal {
+		loaded, err := am.GetAccountByID(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		return loaded.GetBalance()
+	}
+	from, until := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	// a persisted journal is pending, invisible and do not change the balances.
+	journal := newJournal()
+	trxID := journal.GetTransactions()[0].GetTransactionID()
+	assert.NoError(t, jm.PersistJournal(ctx, journal))
+	_, err = jm.GetJournalByID(ctx, journal.GetJournalID())
+	assert.ErrorIs(t, err, ErrJournalIDNotFound)
+	_, err = tm.GetTransactionByID(ctx, trxID)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	_, journals, err := jm.ListJournals(ctx, from, until, PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Len(t, journals, 0)
+	_, transactions, err := tm.ListTransactionsOnAccount(ctx, from, until, reserve, PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 0)
+	assert.True(t, balanceOf(reserve).IsZero())
+	assert.True(t, balanceOf(wallet).IsZero())
+
+	// cancelling the journal leaves no trace.
+	assert.NoError(t, jm.CancelJournal(ctx, journal))
+	exist, err := jm.IsJournalIDExist(ctx, journal.GetJournalID())
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	exist, err = tm.IsTransactionIDExist(ctx, trxID)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	assert.ErrorIs(t, jm.CommitJournal(ctx, journal), ErrJournalIDNotFound)
+	assert.ErrorIs(t, jm.CancelJournal(ctx, journal), ErrJournalIDNotFound)
+	assert.True(t, balanceOf(reserve).IsZero())
+	assert.True(t, balanceOf(wallet).IsZero())
+
+	// committing the journal applies the balances and makes it visible.
+	journal = newJournal()
+	assert.NoError(t, jm.PersistJournal(ctx, journal))
+	assert.NoError(t, jm.CommitJournal(ctx, journal))
+	assert.ErrorIs(t, jm.CommitJournal(ctx, journal), ErrJournalAlreadyCommitted)
+	assert.ErrorIs(t, jm.CancelJournal(ctx, journal), ErrJournalAlreadyCommitted)
+	assert.True(t, journal.GetTransactions()[0].GetAccountBalance().Equal(decimal.NewFromInt(100)))
+	loaded, err := jm.GetJournalByID(ctx, journal.GetJournalID())
+	assert.NoError(t, err)
+	assert.True(t, loaded.GetAmount().Equal(decimal.NewFromInt(100)))
+	_, transactions, err = tm.ListTransactionsOnAccount(ctx, from, until, wallet, PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.True(t, balanceOf(reserve).Equal(decimal.NewFromInt(100)))
+	assert.True(t, balanceOf(wallet).Equal(decimal.NewFromInt(100)))
+
+	// a pending reversal blocks another reversal, but the journal is not reversed until committed.
+	reversal := newJournal().SetReversal(true).SetReversedJournal(loaded)
+	assert.NoError(t, jm.PersistJournal(ctx, reversal))
+	reversed, err := jm.IsJournalIDReversed(ctx, journal.GetJournalID())
+	assert.NoError(t, err)
+	assert.False(t, reversed)
+	assert.ErrorIs(t, jm.PersistJournal(ctx, newJournal().SetReversal(true).SetReversedJournal(loaded)), ErrJournalCanNotDoubleReverse)
+	assert.NoError(t, jm.CancelJournal(ctx, reversal))
+	_, err = acc.CreateReversal(ctx, "Reverse topup", loaded, "aCreator")
+	assert.NoError(t, err)
+	reversed, err = jm.IsJournalIDReversed(ctx, journal.GetJournalID())
+	assert.NoError(t, err)
+	assert.True(t, reversed)
+	assert.True(t, balanceOf(reserve).IsZero())
+	assert.True(t, balanceOf(wallet).IsZero())
+}
+
+// testAccountOptimisticConcurrency checks that an account can only be updated with its current version,
+// so a stale account can not clobber a balance that moved underneath it.
+func testAccountOptimisticConcurrency(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	am := acc.GetAccountManager()
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), reserve.GetVersion())
+	wallet, err := acc.CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	first, err := am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	second, err := am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+
+	assert.NoError(t, am.UpdateAccount(ctx, first.SetName("Renamed Wallet").SetUpdateBy("anEditor")))
+	assert.Equal(t, int64(2), first.GetVersion())
+	assert.ErrorIs(t, am.UpdateAccount(ctx, second.SetDescription("Stale").SetUpdateBy("anEditor")), ErrAccountConcurrentModification)
+
+	// every balance change moves the version.
+	_, err = acc.CreateNewJournal(ctx, "Topup", []TransactionInfo{
+		{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.NewFromInt(10)},
+		{AccountNumber: wallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.NewFromInt(10)},
+	}, "aCreator")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, am.UpdateAccount(ctx, first.SetName("Clobber").SetUpdateBy("anEditor")), ErrAccountConcurrentModification)
+
+	loaded, err := am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), loaded.GetVersion())
+	assert.Equal(t, "Renamed Wallet", loaded.GetName())
+	assert.True(t, loaded.GetBalance().Equal(decimal.NewFromInt(10)))
+	assert.NoError(t, am.UpdateAccount(ctx, loaded.SetName("Wallet").SetUpdateBy("anEditor")))
+
+	loaded, err = am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), loaded.GetVersion())
+	assert.Equal(t, "aCreator", loaded.GetCreateBy())
+	assert.True(t, loaded.GetBalance().Equal(decimal.NewFromInt(10)))
+
+	// the balance, currency and alignment are not written by UpdateAccount.
+	assert.NoError(t, am.UpdateAccount(ctx, loaded.SetBalance(decimal.NewFromInt(1000)).SetCurrency("GOLD").SetAlignment(DEBIT).SetUpdateBy("anEditor")))
+	loaded, err = am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), loaded.GetVersion())
+	assert.True(t, loaded.GetBalance().Equal(decimal.NewFromInt(10)), "balance %s", loaded.GetBalance())
+	assert.Equal(t, "POINT", loaded.GetCurrency())
+	assert.Equal(t, CREDIT, loaded.GetAlignment())
+
+	assert.ErrorIs(t, am.UpdateAccount(ctx, am.NewAccount(ctx).SetAccountNumber("NOT-THERE").SetName("None").
+		SetDescription("None").SetCreateBy("aCreator")), ErrAccountIsNotPersisted)
+}
+
+// testIdempotentJournal checks that retrying a journal creation with the same idempotency key do not double post.
+func testIdempotentJournal(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	topup := func(amount int64) []TransactionInfo {
+		return []TransactionInfo{
+			{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: wallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.NewFromInt(amount)},
+		}
+	}
+
+	journal, err := acc.CreateNewJournalWithIdempotencyKey(ctx, "topup-1", "Topup", topup(100), "aCreator")
+	assert.NoError(t, err)
+	assert.Equal(t, "topup-1", journal.GetIdempotencyKey())
+
+	// the retry returns the original journal.
+	retried, err := acc.CreateNewJournalWithIdempotencyKey(ctx, "topup-1", "Topup", topup(100), "aCreator")
+	assert.NoError(t, err)
+	assert.Equal(t, journal.GetJournalID(), retried.GetJournalID())
+	assert.Equal(t, "topup-1", retried.GetIdempotencyKey())
+
+	// the same key with different lines is a conflict.
+	_, err = acc.CreateNewJournalWithIdempotencyKey(ctx, "topup-1", "Topup", topup(200), "aCreator")
+	assert.ErrorIs(t, err, ErrJournalIdempotencyKeyConflict)
+	_, err = acc.CreateNewJournalWithIdempotencyKey(ctx, "topup-1", "Other Topup", topup(100), "aCreator")
+	assert.ErrorIs(t, err, ErrJournalIdempotencyKeyConflict)
+
+	// a different key, or no key at all, creates a new journal.
+	other, err := acc.CreateNewJournalWithIdempotencyKey(ctx, "topup-2", "Topup", topup(100), "aCreator")
+	assert.NoError(t, err)
+	assert.NotEqual(t, journal.GetJournalID(), other.GetJournalID())
+	_, err = acc.CreateNewJournal(ctx, "Topup", topup(100), "aCreator")
+	assert.NoError(t, err)
+
+	// the key can not be reused by persisting a journal directly.
+	duplicate := acc.GetJournalManager().NewJournal(ctx).SetJournalID(acc.GetUniqueIDGenerator().NewUniqueID()).
+		SetDescription("Topup").SetCreateBy("aCreator").SetIdempotencyKey("topup-1").
+		SetTransactions([]Transaction{
+			acc.GetTransactionManager().NewTransaction(ctx).SetTransactionID(acc.GetUniqueIDGenerator().NewUniqueID()).
+				SetAccountNumber(reserve.GetAccountNumber()).SetAlignment(DEBIT).SetAmount(decimal.NewFromInt(1)).SetCreateBy("aCreator"),
+			acc.GetTransactionManager().NewTransaction(ctx).SetTransactionID(acc.GetUniqueIDGenerator().NewUniqueID()).
+				SetAccountNumber(wallet.GetAccountNumber()).SetAlignment(CREDIT).SetAmount(decimal.NewFromInt(1)).SetCreateBy("aCreator"),
+		})
+	assert.ErrorIs(t, acc.GetJournalManager().PersistJournal(ctx, duplicate), ErrJournalIdempotencyKeyAlreadyUsed)
+
+	// a cancelled journal frees its key.
+	assert.NoError(t, acc.GetJournalManager().PersistJournal(ctx, duplicate.SetIdempotencyKey("topup-3")))
+	assert.NoError(t, acc.GetJournalManager().CancelJournal(ctx, duplicate))
+	_, err = acc.GetJournalManager().GetJournalByIdempotencyKey(ctx, "topup-3")
+	assert.ErrorIs(t, err, ErrJournalIDNotFound)
+	_, err = acc.CreateNewJournalWithIdempotencyKey(ctx, "topup-3", "Topup", topup(100), "aCreator")
+	assert.NoError(t, err)
+
+	wallet, err = acc.GetAccountManager().GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, wallet.GetBalance().Equal(decimal.NewFromInt(400)))
+}
+
+// testChartOfAccounts checks the chart of accounts hierarchy, and the balance rollup of a subtree.
+func testChartOfAccounts(t *testing.T, acc *Accounting, coaManager COAManager) {
+	ctx := context.Background()
+
+	_, err := acc.CreateNewCOA(ctx, "1", "", "Assets", "All assets", ASSET, DEBIT, "aCreator")
+	assert.ErrorIs(t, err, ErrCOAManagerNotSet)
+	acc.SetCOAManager(coaManager)
+
+	_, err = acc.CreateNewCOA(ctx, "1", "", "Assets", "All assets", ASSET, ASSET.NormalAlignment(), "aCreator")
+	assert.NoError(t, err)
+	_, err = acc.CreateNewCOA(ctx, "1.1", "1", "Current Assets", "Current assets", ASSET, DEBIT, "aCreator")
+	assert.NoError(t, err)
+	_, err = acc.CreateNewCOA(ctx, "1.1.1", "1.1", "Cash", "Cash on hand", ASSET, DEBIT, "aCreator")
+	assert.NoError(t, err)
+	_, err = acc.CreateNewCOA(ctx, "1.9", "1", "Accumulated Depreciation", "Contra asset", ASSET, CREDIT, "aCreator")
+	assert.NoError(t, err)
+	_, err = acc.CreateNewCOA(ctx, "3", "", "Equity", "All equities", EQUITY, EQUITY.NormalAlignment(), "aCreator")
+	assert.NoError(t, err)
+
+	_, err = acc.CreateNewCOA(ctx, "1", "", "Assets", "All assets", ASSET, DEBIT, "aCreator")
+	assert.ErrorIs(t, err, ErrCOAAlreadyPersisted)
+	_, err = acc.CreateNewCOA(ctx, "4.1", "4", "Sales", "Sales income", INCOME, CREDIT, "aCreator")
+	assert.ErrorIs(t, err, ErrCOAParentNotFound)
+	_, err = acc.CreateNewCOA(ctx, "1.2", "1", "Payables", "Misplaced liability", LIABILITY, CREDIT, "aCreator")
+	assert.ErrorIs(t, err, ErrCOACategoryMismatch)
+
+	roots, err := coaManager.ListCOAChildren(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, roots, 2)
+	children, err := coaManager.ListCOAChildren(ctx, "1")
+	assert.NoError(t, err)
+	assert.Len(t, children, 2)
+	assert.Equal(t, "1.1", children[0].GetCode())
+	assert.Equal(t, ASSET, children[0].GetCategory())
+	_, err = coaManager.ListCOAChildren(ctx, "9")
+	assert.ErrorIs(t, err, ErrCOANotFound)
+
+	_, err = acc.CreateNewAccount(ctx, "", "Bank", "Bank account", "1.5", "IDR", DEBIT, "aCreator")
+	assert.ErrorIs(t, err, ErrAccountCOANotFound)
+	_, err = acc.CreateNewAccount(ctx, "", "Cash", "Misaligned cash", "1.1.1", "IDR", CREDIT, "aCreator")
+	assert.ErrorIs(t, err, ErrAccountCOAAlignmentMismatch)
+	cash, err := acc.CreateNewAccount(ctx, "", "Cash", "Cash on hand", "1.1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	depreciation, err := acc.CreateNewAccount(ctx, "", "Depreciation", "Accumulated depreciation", "1.9", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "", "Capital", "Owner capital", "3", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	_, err = acc.CreateNewJournal(ctx, "Capital", []TransactionInfo{
+		{AccountNumber: cash.GetAccountNumber(), Description: "Capital", TxType: DEBIT, Amount: decimal.NewFromInt(1000)},
+		{AccountNumber: equity.GetAccountNumber(), Description: "Capital", TxType: CREDIT, Amount: decimal.NewFromInt(1000)},
+	}, "aCreator")
+	assert.NoError(t, err)
+	_, err = acc.CreateNewJournal(ctx, "Depreciation", []TransactionInfo{
+		{AccountNumber: equity.GetAccountNumber(), Description: "Depreciation", TxType: DEBIT, Amount: decimal.NewFromInt(100)},
+		{AccountNumber: depreciation.GetAccountNumber(), Description: "Depreciation", TxType: CREDIT, Amount: decimal.NewFromInt(100)},
+	}, "aCreator")
+	assert.NoError(t, err)
+
+	balance, err := acc.COASubtreeBalance(ctx, "1", "IDR", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt(900)), balance.String())
+	balance, err = acc.COASubtreeBalance(ctx, "1.1", "IDR", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt(1000)), balance.String())
+	balance, err = acc.COASubtreeBalance(ctx, "1.9", "IDR", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt(100)), balance.String())
+	balance, err = acc.COASubtreeBalance(ctx, "3", "IDR", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt(900)), balance.String())
+	balance, err = acc.COASubtreeBalance(ctx, "1", "GOLD", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, balance.IsZero())
+	_, err = acc.COASubtreeBalance(ctx, "9", "IDR", time.Now())
+	assert.ErrorIs(t, err, ErrCOANotFound)
+}
+
+func testBalanceAt(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	tm := acc.GetTransactionManager()
+
+	cash, err := acc.CreateNewAccount(ctx, "CASH", "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "EQUITY", "Equity", "Owner equity", "3.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	idle, err := acc.CreateNewAccount(ctx, "IDLE", "Idle", "Idle account", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+
+	post := func(description string, cashAlignment, equityAlignment Alignment, amount int64) {
+		_, err := acc.CreateNewJournal(ctx, description, []TransactionInfo{
+			{AccountNumber: cash.GetAccountNumber(), Description: description, TxType: cashAlignment, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: equity.GetAccountNumber(), Description: description, TxType: equityAlignment, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		assert.NoError(t, err)
+	}
+	before := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	post("Capital", DEBIT, CREDIT, 1000)
+	time.Sleep(2 * time.Millisecond)
+	middle := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	post("Withdrawal", CREDIT, DEBIT, 300)
+
+	balance, err := tm.GetBalanceAt(ctx, cash.GetAccountNumber(), before)
+	assert.NoError(t, err)
+	assert.True(t, balance.IsZero())
+	balance, err = tm.GetBalanceAt(ctx, cash.GetAccountNumber(), middle)
+	assert.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt(1000)))
+	balance, err = tm.GetBalanceAt(ctx, cash.GetAccountNumber(), time.Now())
+	assert.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt(700)))
+
+	balances, err := tm.GetBalancesAt(ctx, []string{cash.GetAccountNumber(), equity.GetAccountNumber(), idle.GetAccountNumber()}, middle)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	assert.True(t, balances[cash.GetAccountNumber()].Equal(decimal.NewFromInt(1000)))
+	assert.True(t, balances[equity.GetAccountNumber()].Equal(decimal.NewFromInt(1000)))
+	assert.True(t, balances[idle.GetAccountNumber()].IsZero())
+
+	_, err = tm.GetBalanceAt(ctx, "UNKNOWN", time.Now())
+	assert.ErrorIs(t, err, ErrAccountIDNotFound)
+	_, err = tm.GetBalancesAt(ctx, []string{cash.GetAccountNumber(), "UNKNOWN"}, time.Now())
+	assert.ErrorIs(t, err, ErrAccountIDNotFound)
+}
+
+// testBalanceAtEqualTime checks the Balance of an account whose transactions share the same transaction time
+// is the one recorded on the last committed of them. The setTransactionTime callback sets the time of all the transactions.
+func testBalanceAtEqualTime(t *testing.T, acc *Accounting, setTransactionTime func(at time.Time)) {
+	ctx := context.Background()
+	tm := acc.GetTransactionManager()
+
+	cash, err := acc.CreateNewAccount(ctx, "CASH", "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "EQUITY", "Equity", "Owner equity", "3.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	post := func(description string, cashAlignment, equityAlignment Alignment, amount int64) {
+		_, err := acc.CreateNewJournal(ctx, description, []TransactionInfo{
+			{AccountNumber: cash.GetAccountNumber(), Description: description, TxType: cashAlignment, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: equity.GetAccountNumber(), Description: description, TxType: equityAlignment, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		assert.NoError(t, err)
+	}
+	post("Capital", DEBIT, CREDIT, 1000)
+	for i := 0; i < 5; i++ {
+		post("Withdrawal", CREDIT, DEBIT, 100)
+	}
+	at := time.Now().UTC().Truncate(time.Second)
+	setTransactionTime(at)
+
+	for i := 0; i < 3; i++ {
+		balances, err := tm.GetBalancesAt(ctx, []string{cash.GetAccountNumber(), equity.GetAccountNumber()}, at)
+		assert.NoError(t, err)
+		assert.True(t, balances[cash.GetAccountNumber()].Equal(decimal.NewFromInt(500)), "cash balance %s", balances[cash.GetAccountNumber()])
+		assert.True(t, balances[equity.GetAccountNumber()].Equal(decimal.NewFromInt(500)), "equity balance %s", balances[equity.GetAccountNumber()])
+	}
+}
+
+func testListingSorts(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	am := acc.GetAccountManager()
+
+	cash, err := acc.CreateNewAccount(ctx, "A-CASH", "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	bank, err := acc.CreateNewAccount(ctx, "B-BANK", "Bank", "Cash in bank", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "C-EQUITY", "Equity", "Owner equity", "3.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	from := time.Now()
+	post := func(debit Account, amount int64) {
+		_, err := acc.CreateNewJournal(ctx, fmt.Sprintf("Capital %d", amount), []TransactionInfo{
+			{AccountNumber: debit.GetAccountNumber(), Description: "Capital", TxType: DEBIT, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: equity.GetAccountNumber(), Description: "Capital", TxType: CREDIT, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		assert.NoError(t, err)
+	}
+	post(cash, 20)
+	post(bank, 300)
+	post(cash, 100)
+
+	accountNumbers := func(accounts []Account) []string {
+		numbers := make([]string, len(accounts))
+		for i, account := range accounts {
+			numbers[i] = account.GetAccountNumber()
+		}
+		return numbers
+	}
+	_, accounts, err := am.ListAccounts(ctx, PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A-CASH", "B-BANK", "C-EQUITY"}, accountNumbers(accounts))
+	_, accounts, err = am.ListAccounts(ctx, PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "name", Ascending: true}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"B-BANK", "A-CASH", "C-EQUITY"}, accountNumbers(accounts))
+	_, accounts, err = am.ListAccounts(ctx, PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "balance", Ascending: false}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"C-EQUITY", "B-BANK", "A-CASH"}, accountNumbers(accounts))
+	_, accounts, err = am.ListAccountByCOA(ctx, "1.1", PageRequest{PageNo: 1, ItemSize: 1, Sorts: []Sort{{Column: "balance", Ascending: true}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A-CASH"}, accountNumbers(accounts))
+	_, accounts, err = am.FindAccounts(ctx, "a", PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "coa", Ascending: false}, {Column: "account_number", Ascending: false}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"B-BANK", "A-CASH"}, accountNumbers(accounts))
+
+	_, journals, err := acc.GetJournalManager().ListJournals(ctx, from, time.Now(), PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "amount", Ascending: false}}})
+	assert.NoError(t, err)
+	assert.Len(t, journals, 3)
+	assert.Equal(t, "Capital 300", journals[0].GetDescription())
+	assert.Equal(t, "Capital 100", journals[1].GetDescription())
+	assert.Equal(t, "Capital 20", journals[2].GetDescription())
+	assert.Len(t, journals[0].GetTransactions(), 2)
+
+	_, transactions, err := acc.GetTransactionManager().ListTransactionsOnAccount(ctx, from, time.Now(), cash, PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "transaction_time", Ascending: false}}})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.True(t, transactions[0].GetAmount().Equal(decimal.NewFromInt(100)))
+	assert.True(t, transactions[1].GetAmount().Equal(decimal.NewFromInt(20)))
+
+	_, _, err = am.ListAccounts(ctx, PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "name; DROP TABLE acccore_account", Ascending: true}}})
+	assert.ErrorIs(t, err, ErrUnknownSortColumn)
+	var sortErr *UnknownSortColumnError
+	assert.ErrorAs(t, err, &sortErr)
+	assert.Equal(t, "account", sortErr.Entity)
+	_, _, err = acc.GetJournalManager().ListJournals(ctx, from, time.Now(), PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "balance"}}})
+	assert.ErrorIs(t, err, ErrUnknownSortColumn)
+	_, _, err = acc.GetTransactionManager().ListTransactionsOnAccount(ctx, from, time.Now(), cash, PageRequest{PageNo: 1, ItemSize: 10, Sorts: []Sort{{Column: "name"}}})
+	assert.ErrorIs(t, err, ErrUnknownSortColumn)
+}
+
+func testCursorPagination(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	tm := acc.GetTransactionManager()
+	jm := acc.GetJournalManager()
+
+	cash, err := acc.CreateNewAccount(ctx, "CASH", "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "EQUITY", "Equity", "Owner equity", "3.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	post := func(amount int64) {
+		_, err := acc.CreateNewJournal(ctx, "Capital", []TransactionInfo{
+			{AccountNumber: cash.GetAccountNumber(), Description: "Capital", TxType: DEBIT, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: equity.GetAccountNumber(), Description: "Capital", TxType: CREDIT, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		assert.NoError(t, err)
+	}
+	from := time.Now()
+	for amount := int64(1); amount <= 4; amount++ {
+		post(amount)
+	}
+	until := from.Add(time.Hour)
+
+	// page through the transactions while new ones keep coming.
+	amounts := make([]int64, 0)
+	request := CursorRequest{ItemSize: 2}
+	result, transactions, err := tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, request)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.PageSize)
+	assert.True(t, result.HaveNext)
+	assert.False(t, result.HavePrev)
+	firstPage := transactions
+	for _, trx := range transactions {
+		amounts = append(amounts, trx.GetAmount().IntPart())
+	}
+	post(5)
+	for result.HaveNext {
+		request.Cursor = result.NextCursor
+		result, transactions, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, request)
+		assert.NoError(t, err)
+		assert.True(t, result.HavePrev)
+		for _, trx := range transactions {
+			amounts = append(amounts, trx.GetAmount().IntPart())
+		}
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, amounts)
+	assert.Equal(t, 1, result.PageSize)
+
+	// the last next cursor can be polled for new transactions.
+	request.Cursor = result.NextCursor
+	result, transactions, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, request)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 0)
+	assert.False(t, result.HaveNext)
+	assert.Equal(t, request.Cursor, result.NextCursor)
+	post(6)
+	_, transactions, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, request)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.True(t, transactions[0].GetAmount().Equal(decimal.NewFromInt(6)))
+
+	// going back from the second page returns the first page.
+	result, _, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, CursorRequest{ItemSize: 2})
+	assert.NoError(t, err)
+	result, secondPage, err := tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, CursorRequest{ItemSize: 2, Cursor: result.NextCursor})
+	assert.NoError(t, err)
+	assert.Len(t, secondPage, 2)
+	result, transactions, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, CursorRequest{ItemSize: 2, Cursor: result.PreviousCursor})
+	assert.NoError(t, err)
+	assert.False(t, result.HavePrev)
+	assert.True(t, result.HaveNext)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, firstPage[0].GetTransactionID(), transactions[0].GetTransactionID())
+	assert.Equal(t, firstPage[1].GetTransactionID(), transactions[1].GetTransactionID())
+
+	// journals are paged the same way.
+	count := 0
+	journalRequest := CursorRequest{ItemSize: 4}
+	for {
+		result, journals, err := jm.ListJournalsByCursor(ctx, from, until, journalRequest)
+		assert.NoError(t, err)
+		count += len(journals)
+		if len(journals) > 0 {
+			assert.Len(t, journals[0].GetTransactions(), 2)
+		}
+		if !result.HaveNext {
+			break
+		}
+		journalRequest.Cursor = result.NextCursor
+	}
+	assert.Equal(t, 6, count)
+
+	// cursors can not be tampered, nor used on other listing.
+	cursor := result.PreviousCursor
+	_, _, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, CursorRequest{ItemSize: 2, Cursor: "x" + cursor})
+	assert.ErrorIs(t, err, ErrCursorInvalid)
+	_, _, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, CursorRequest{ItemSize: 2, Cursor: "garbage"})
+	assert.ErrorIs(t, err, ErrCursorInvalid)
+	_, _, err = tm.ListTransactionsOnAccountByCursor(ctx, from, until, equity, CursorRequest{ItemSize: 2, Cursor: cursor})
+	assert.ErrorIs(t, err, ErrCursorInvalid)
+	_, _, err = jm.ListJournalsByCursor(ctx, from, until, CursorRequest{ItemSize: 2, Cursor: cursor})
+	assert.ErrorIs(t, err, ErrCursorInvalid)
+}
+
+func testCursorPaginationConcurrent(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	tm := acc.GetTransactionManager()
+	jm := acc.GetJournalManager()
+
+	cash, err := acc.CreateNewAccount(ctx, "", "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "", "Equity", "Owner equity", "3.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	from := time.Now()
+	until := from.Add(time.Hour)
+
+	// journals keep being committed concurrently while the cursors are polled.
+	const writers, journalsPerWriter = 4, 15
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < journalsPerWriter; i++ {
+				_, err := acc.CreateNewJournal(ctx, "Capital", []TransactionInfo{
+					{AccountNumber: cash.GetAccountNumber(), Description: "Capital", TxType: DEBIT, Amount: decimal.NewFromInt(1)},
+					{AccountNumber: equity.GetAccountNumber(), Description: "Capital", TxType: CREDIT, Amount: decimal.NewFromInt(1)},
+				}, "aCreator")
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	seenTransactions := make(map[string]int)
+	seenJournals := make(map[string]int)
+	transactionRequest := CursorRequest{ItemSize: 3}
+	journalRequest := CursorRequest{ItemSize: 3}
+	poll := func() {
+		for {
+			result, transactions, err := tm.ListTransactionsOnAccountByCursor(ctx, from, until, cash, transactionRequest)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, trx := range transactions {
+				seenTransactions[trx.GetTransactionID()]++
+			}
+			transactionRequest.Cursor = result.NextCursor
+			if !result.HaveNext {
+				break
+			}
+		}
+		for {
+			result, journals, err := jm.ListJournalsByCursor(ctx, from, until, journalRequest)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, journal := range journals {
+				seenJournals[journal.GetJournalID()]++
+			}
+			journalRequest.Cursor = result.NextCursor
+			if !result.HaveNext {
+				break
+			}
+		}
+	}
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		poll()
+	}
+
+	assert.Len(t, seenTransactions, writers*journalsPerWriter)
+	for id, count := range seenTransactions {
+		assert.Equal(t, 1, count, "transaction %s listed %d times", id, count)
+	}
+	assert.Len(t, seenJournals, writers*journalsPerWriter)
+	for id, count := range seenJournals {
+		assert.Equal(t, 1, count, "journal %s listed %d times", id, count)
+	}
+}
+
+func testStreaming(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	from := time.Now()
+
+	equity, err := acc.CreateNewAccount(ctx, "EQUITY", "Equity", "Owner equity", "3.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	cash := make([]Account, 0)
+	for i := 0; i < 3; i++ {
+		account, err := acc.CreateNewAccount(ctx, fmt.Sprintf("CASH-%d", i), "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+		assert.NoError(t, err)
+		cash = append(cash, account)
+	}
+	for i := 1; i <= streamBatchSize+50; i++ {
+		_, err := acc.CreateNewJournal(ctx, "Capital", []TransactionInfo{
+			{AccountNumber: cash[i%len(cash)].GetAccountNumber(), Description: "Capital", TxType: DEBIT, Amount: decimal.NewFromInt(int64(i))},
+			{AccountNumber: equity.GetAccountNumber(), Description: "Capital", TxType: CREDIT, Amount: decimal.NewFromInt(int64(i))},
+		}, "aCreator")
+		assert.NoError(t, err)
+	}
+	until := time.Now()
+
+	count := 0
+	total := decimal.Zero
+	var last Transaction
+	for trx, err := range acc.GetTransactionManager().StreamTransactionsOnAccount(ctx, from, until, equity) {
+		assert.NoError(t, err)
+		if last != nil {
+			assert.False(t, trx.GetTransactionTime().Before(last.GetTransactionTime()))
+		}
+		last = trx
+		total = total.Add(trx.GetAmount())
+		count++
+	}
+	assert.Equal(t, streamBatchSize+50, count)
+	assert.True(t, total.Equal(decimal.NewFromInt(int64((streamBatchSize+50)*(streamBatchSize+51)/2))))
+
+	count = 0
+	for journal, err := range acc.GetJournalManager().StreamJournals(ctx, from, until) {
+		assert.NoError(t, err)
+		assert.Len(t, journal.GetTransactions(), 2)
+		count++
+	}
+	assert.Equal(t, streamBatchSize+50, count)
+
+	accountNumbers := make([]string, 0)
+	stream := acc.GetAccountManager().StreamAccounts(ctx)
+	for account, err := range stream {
+		assert.NoError(t, err)
+		accountNumbers = append(accountNumbers, account.GetAccountNumber())
+	}
+	assert.Equal(t, []string{"CASH-0", "CASH-1", "CASH-2", "EQUITY"}, accountNumbers)
+
+	// the same stream can be ranged again, and stopped early.
+	for account, err := range stream {
+		assert.NoError(t, err)
+		assert.Equal(t, "CASH-0", account.GetAccountNumber())
+		break
+	}
+
+	// cancelling the context ends the stream with the context error.
+	cancelled, cancel := context.WithCancel(ctx)
+	defer cancel()
+	count = 0
+	var streamErr error
+	for _, err := range acc.GetJournalManager().StreamJournals(cancelled, from, until) {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	assert.Equal(t, 10, count)
+	assert.ErrorIs(t, streamErr, context.Canceled)
+}
+
+// testBalanceLimit checks that journals taking an account below its balance limit are rejected as a whole.
+func testBalanceLimit(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	am := acc.GetAccountManager()
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet := am.NewAccount(ctx).SetAccountNumber(acc.GetUniqueIDGenerator().NewUniqueID()).SetName("Wallet").
+		SetDescription("Point wallet").SetCOA("2.1").SetCurrency("POINT").SetAlignment(CREDIT).
+		SetBalanceLimit(NonNegativeBalance).SetCreateBy("aCreator")
+	assert.NoError(t, am.PersistAccount(ctx, wallet))
+
+	post := func(walletAlignment Alignment, amount int64) error {
+		reserveAlignment := DEBIT
+		if walletAlignment == DEBIT {
+			reserveAlignment = CREDIT
+		}
+		_, err := acc.CreateNewJournal(ctx, "Points", []TransactionInfo{
+			{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: reserveAlignment, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: wallet.GetAccountNumber(), Description: "Wallet", TxType: walletAlignment, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		return err
+	}
+	balanceOf := func(account Account) decimal.Decimal {
+		loaded, err := am.GetAccountByID(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		return loaded.GetBalance()
+	}
+
+	assert.NoError(t, post(CREDIT, 100))
+	err = post(DEBIT, 150)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	var insufficient *InsufficientBalanceError
+	if assert.True(t, errors.As(err, &insufficient)) {
+		assert.Equal(t, wallet.GetAccountNumber(), insufficient.AccountNumber)
+		assert.True(t, insufficient.Shortfall.Equal(decimal.NewFromInt(50)), insufficient.Shortfall.String())
+		assert.True(t, insufficient.Balance.Equal(decimal.NewFromInt(-50)))
+	}
+	// the other side of the rejected journal is left untouched.
+	assert.True(t, balanceOf(wallet).Equal(decimal.NewFromInt(100)))
+	assert.True(t, balanceOf(reserve).Equal(decimal.NewFromInt(100)))
+	_, journals, err := acc.GetJournalManager().ListJournals(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Len(t, journals, 1)
+
+	assert.NoError(t, post(DEBIT, 100))
+	assert.True(t, balanceOf(wallet).IsZero())
+
+	// allow an overdraft down to -30.
+	loaded, err := am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.ErrorIs(t, am.UpdateAccount(ctx, loaded.SetBalanceLimit(OverdraftBalance).SetOverdraftLimit(decimal.NewFromInt(-30)).SetUpdateBy("anEditor")), ErrAccountInvalidOverdraftLimit)
+	assert.NoError(t, am.UpdateAccount(ctx, loaded.SetOverdraftLimit(decimal.NewFromInt(30)).SetUpdateBy("anEditor")))
+	loaded, err = am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Equal(t, OverdraftBalance, loaded.GetBalanceLimit())
+	assert.True(t, loaded.GetOverdraftLimit().Equal(decimal.NewFromInt(30)))
+
+	assert.NoError(t, post(DEBIT, 30))
+	assert.True(t, balanceOf(wallet).Equal(decimal.NewFromInt(-30)))
+	err = post(DEBIT, 1)
+	if assert.True(t, errors.As(err, &insufficient)) {
+		assert.True(t, insufficient.Shortfall.Equal(decimal.NewFromInt(1)))
+		assert.True(t, insufficient.MinimumBalance.Equal(decimal.NewFromInt(-30)))
+	}
+
+	// lowering the limit below the balance still accepts transactions raising the balance.
+	loaded, err = am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.NoError(t, am.UpdateAccount(ctx, loaded.SetBalanceLimit(NonNegativeBalance).SetUpdateBy("anEditor")))
+	assert.NoError(t, post(CREDIT, 10))
+	assert.True(t, balanceOf(wallet).Equal(decimal.NewFromInt(-20)))
+	assert.ErrorIs(t, post(DEBIT, 1), ErrInsufficientBalance)
+
+	// accounts are unlimited by default.
+	assert.True(t, balanceOf(reserve).Equal(decimal.NewFromInt(-20)))
+}
+
+// testAccountStates checks that frozen and closed accounts reject their transactions, and that state changes are audited.
+func testAccountStates(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	am := acc.GetAccountManager()
+	jm := acc.GetJournalManager()
+	tm := acc.GetTransactionManager()
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	assert.Equal(t, AccountActive, wallet.GetState())
+
+	post := func(walletAlignment Alignment, amount int64) error {
+		reserveAlignment := DEBIT
+		if walletAlignment == DEBIT {
+			reserveAlignment = CREDIT
+		}
+		_, err := acc.CreateNewJournal(ctx, "Points", []TransactionInfo{
+			{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: reserveAlignment, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: wallet.GetAccountNumber(), Description: "Wallet", TxType: walletAlignment, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		return err
+	}
+	balanceOf := func(account Account) decimal.Decimal {
+		loaded, err := am.GetAccountByID(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		return loaded.GetBalance()
+	}
+
+	assert.NoError(t, post(CREDIT, 100))
+
+	assert.NoError(t, acc.FreezeAccount(ctx, wallet.GetAccountNumber(), AccountDebitFrozen, "suspicious redemptions", "support"))
+	assert.ErrorIs(t, post(DEBIT, 10), ErrAccountFrozen)
+	assert.NoError(t, post(CREDIT, 10))
+
+	assert.NoError(t, acc.FreezeAccount(ctx, wallet.GetAccountNumber(), AccountCreditFrozen, "suspicious topups", "support"))
+	assert.ErrorIs(t, post(CREDIT, 10), ErrAccountFrozen)
+	assert.NoError(t, post(DEBIT, 10))
+
+	assert.NoError(t, acc.FreezeAccount(ctx, wallet.GetAccountNumber(), AccountFrozen, "compromised", "support"))
+	assert.ErrorIs(t, post(CREDIT, 10), ErrAccountFrozen)
+	assert.ErrorIs(t, post(DEBIT, 10), ErrAccountFrozen)
+	assert.ErrorIs(t, acc.FreezeAccount(ctx, wallet.GetAccountNumber(), AccountClosed, "not a freeze", "support"), ErrAccountStateUnknown)
+	assert.True(t, balanceOf(wallet).Equal(decimal.NewFromInt(100)))
+	assert.True(t, balanceOf(reserve).Equal(decimal.NewFromInt(100)))
+
+	// an account frozen after the journal was persisted rejects its commit.
+	assert.NoError(t, acc.UnfreezeAccount(ctx, wallet.GetAccountNumber(), "verified", "support"))
+	journalID := acc.GetUniqueIDGenerator().NewUniqueID()
+	journal := jm.NewJournal(ctx).SetJournalID(journalID).SetDescription("Redeem").SetCreateBy("aCreator").SetTransactions([]Transaction{
+		tm.NewTransaction(ctx).SetTransactionID(acc.GetUniqueIDGenerator().NewUniqueID()).SetJournalID(journalID).
+			SetAccountNumber(wallet.GetAccountNumber()).SetAlignment(DEBIT).SetAmount(decimal.NewFromInt(10)).SetCreateBy("aCreator"),
+		tm.NewTransaction(ctx).SetTransactionID(acc.GetUniqueIDGenerator().NewUniqueID()).SetJournalID(journalID).
+			SetAccountNumber(reserve.GetAccountNumber()).SetAlignment(CREDIT).SetAmount(decimal.NewFromInt(10)).SetCreateBy("aCreator"),
+	})
+	assert.NoError(t, jm.PersistJournal(ctx, journal))
+	assert.NoError(t, acc.FreezeAccount(ctx, wallet.GetAccountNumber(), AccountFrozen, "compromised again", "support"))
+	assert.ErrorIs(t, jm.CommitJournal(ctx, journal), ErrAccountFrozen)
+	assert.NoError(t, jm.CancelJournal(ctx, journal))
+	assert.True(t, balanceOf(wallet).Equal(decimal.NewFromInt(100)))
+	assert.True(t, balanceOf(reserve).Equal(decimal.NewFromInt(100)))
+
+	// closing requires a zero balance, and is final.
+	assert.NoError(t, acc.UnfreezeAccount(ctx, wallet.GetAccountNumber(), "verified", "support"))
+	assert.ErrorIs(t, acc.CloseAccount(ctx, wallet.GetAccountNumber(), "user deleted", "support"), ErrAccountBalanceNotZero)
+	assert.NoError(t, post(DEBIT, 100))
+	assert.NoError(t, acc.CloseAccount(ctx, wallet.GetAccountNumber(), "user deleted", "support"))
+	assert.ErrorIs(t, post(CREDIT, 10), ErrAccountClosed)
+	assert.ErrorIs(t, acc.UnfreezeAccount(ctx, wallet.GetAccountNumber(), "reopen", "support"), ErrAccountClosed)
+	assert.NoError(t, acc.CloseAccount(ctx, wallet.GetAccountNumber(), "user deleted", "support"))
+
+	// the state is not written by UpdateAccount.
+	loaded, err := am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Equal(t, AccountClosed, loaded.GetState())
+	assert.NoError(t, am.UpdateAccount(ctx, loaded.SetState(AccountActive).SetName("Deleted Wallet").SetUpdateBy("support")))
+	loaded, err = am.GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Equal(t, AccountClosed, loaded.GetState())
+	assert.Equal(t, "Deleted Wallet", loaded.GetName())
+
+	changes, err := am.ListAccountStateChanges(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Len(t, changes, 7)
+	assert.Equal(t, AccountActive, changes[0].From)
+	assert.Equal(t, AccountDebitFrozen, changes[0].To)
+	assert.Equal(t, "suspicious redemptions", changes[0].Reason)
+	assert.Equal(t, "support", changes[0].ChangeBy)
+	assert.Equal(t, AccountActive, changes[6].From)
+	assert.Equal(t, AccountClosed, changes[6].To)
+	for i := 1; i < len(changes); i++ {
+		assert.Equal(t, changes[i-1].To, changes[i].From)
+		assert.Greater(t, changes[i].Version, changes[i-1].Version)
+	}
+
+	changes, err = am.ListAccountStateChanges(ctx, reserve.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.ErrorIs(t, am.ChangeAccountState(ctx, reserve.GetAccountNumber(), AccountFrozen, "no author", ""), ErrAccountMissingUpdater)
+	assert.ErrorIs(t, am.ChangeAccountState(ctx, "NOT-THERE", AccountFrozen, "unknown", "support"), ErrAccountIDNotFound)
+	_, err = am.ListAccountStateChanges(ctx, "NOT-THERE")
+	assert.ErrorIs(t, err, ErrAccountIDNotFound)
+}
@@ -0,0 +1,65 @@
+package acccore
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// InsufficientBalanceError is returned when committing a journal would take the Balance of an account
+// below what its balance limit allows. It matches ErrInsufficientBalance using errors.Is.
+type InsufficientBalanceError struct {
+	// AccountNumber is the account that would go below its limit
+	AccountNumber string
+	// Balance is the available Balance the account would have after the transaction, its Balance minus the amount held on it
+	Balance decimal.Decimal
+	// MinimumBalance is the lowest Balance the account may have, zero or the negative overdraft limit
+	MinimumBalance decimal.Decimal
+	// Shortfall is the amount the account is missing for the transaction to be accepted
+	Shortfall decimal.Decimal
+}
+
+// Error returns the error message
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("account %s balance is insufficient, short by %s", e.AccountNumber, e.Shortfall.String())
+}
+
+// Is returns true if the target is ErrInsufficientBalance
+func (e *InsufficientBalanceError) Is(target error) bool {
+	return target == ErrInsufficientBalance
+}
+
+// minimumBalance returns the lowest Balance allowed by the balance limit, false if the Balance is unlimited.
+func minimumBalance(limit BalanceLimit, overdraft decimal.Decimal) (decimal.Decimal, bool) {
+	switch limit {
+	case NonNegativeBalance:
+		return decimal.Zero, true
+	case OverdraftBalance:
+		return overdraft.Neg(), true
+	}
+	return decimal.Zero, false
+}
+
+// checkBalanceLimit returns an InsufficientBalanceError if a transaction decreasing the Balance of the account
+// leaves it below what the balance limit allows. Transactions increasing the Balance are always accepted,
+// even if the Balance stays below the limit.
+func checkBalanceLimit(accountNumber string, limit BalanceLimit, overdraft, balance, newBalance decimal.Decimal) error {
+	minimum, limited := minimumBalance(limit, overdraft)
+	if !limited || !newBalance.LessThan(balance) || !newBalance.LessThan(minimum) {
+		return nil
+	}
+	return &InsufficientBalanceError{
+		AccountNumber:  accountNumber,
+		Balance:        newBalance,
+		MinimumBalance: minimum,
+		Shortfall:      minimum.Sub(newBalance),
+	}
+}
+
+// validateOverdraftLimit checks the overdraft limit of the account is not negative.
+func validateOverdraftLimit(account Account) error {
+	if account.GetOverdraftLimit().IsNegative() {
+		return ErrAccountInvalidOverdraftLimit
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package acccore
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateCrossCurrencyTransfer creates a single journal moving the amount out of the from account, and its exchanged value,
+// calculated by the exchange manager, into the to account. The amount is in the Currency of the from account.
+// The journal has a leg in each Currency going through the FX position account of that Currency, so the Transactions
+// of each Currency balance on their own. The applied exchange rate is recorded on the journal.
+// If both accounts have the same Currency, this is a plain transfer without FX legs.
+// Both accounts must have the same Alignment, as moving value out of one into the other otherwise does not balance,
+// and ErrCrossCurrencyAlignmentMismatch is returned whether or not the Currency is the same.
+// The amount must be positive, and its exchanged value must fit the scale of the Currency of the to account,
+// otherwise ErrCrossCurrencyRemainder is returned rather than dropping the rounding remainder.
+func (acc *Accounting) CreateCrossCurrencyTransfer(context context.Context, description, fromAccountNumber, toAccountNumber string, amount decimal.Decimal, creator string) (Journal, error) {
+	if !amount.IsPositive() {
+		return nil, ErrCrossCurrencyInvalidAmount
+	}
+	from, err := acc.GetAccountManager().GetAccountByID(context, fromAccountNumber)
+	if err != nil {
+		return nil, err
+	}
+	to, err := acc.GetAccountManager().GetAccountByID(context, toAccountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if from.GetAlignment() != to.GetAlignment() {
+		logrus.Errorf("error transferring from %s to %s. account alignments %d and %d differ", fromAccountNumber, toAccountNumber, from.GetAlignment(), to.GetAlignment())
+		return nil, ErrCrossCurrencyAlignmentMismatch
+	}
+	if from.GetCurrency() == to.GetCurrency() {
+		return acc.CreateNewJournal(context, description, []TransactionInfo{
+			{AccountNumber: fromAccountNumber, Description: description, TxType: decreasingAlignment(from), Amount: amount},
+			{AccountNumber: toAccountNumber, Description: description, TxType: to.GetAlignment(), Amount: amount},
+		}, creator)
+	}
+
+	if acc.GetExchangeManager() == nil {
+		return nil, ErrExchangeManagerNotSet
+	}
+	fromPosition, toPosition := acc.GetFXPositionAccount(from.GetCurrency()), acc.GetFXPositionAccount(to.GetCurrency())
+	if len(fromPosition) == 0 || len(toPosition) == 0 {
+		logrus.Errorf("error transferring from %s to %s. FX position account of %s or %s is not set", fromAccountNumber, toAccountNumber, from.GetCurrency(), to.GetCurrency())
+		return nil, ErrFXPositionAccountNotSet
+	}
+	rate, err := acc.GetExchangeManager().CalculateExchangeRate(context, from.GetCurrency(), to.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+	toCurrency, err := acc.GetExchangeManager().GetCurrency(context, to.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+	// the exchanged value is derived from the rate read above, so the journal records the rate its legs were exchanged at.
+	exchanged, remainder := RoundCurrencyAmount(toCurrency, rate.Mul(amount))
+	if !remainder.IsZero() {
+		logrus.Errorf("error transferring %s %s from %s to %s. exchanged value leaves a remainder of %s %s", amount, from.GetCurrency(), fromAccountNumber, toAccountNumber, remainder, to.GetCurrency())
+		return nil, ErrCrossCurrencyRemainder
+	}
+
+	// the from account leg is offset by the FX position of its Currency, which hands the exchanged value over
+	// to the FX position of the other Currency, offsetting the to account leg.
+	fromAlignment := decreasingAlignment(from)
+	journal := acc.newJournal(context, "", description, []TransactionInfo{
+		{AccountNumber: fromAccountNumber, Description: description, TxType: fromAlignment, Amount: amount},
+		{AccountNumber: fromPosition, Description: description, TxType: oppositeAlignment(fromAlignment), Amount: amount},
+		{AccountNumber: toPosition, Description: description, TxType: oppositeAlignment(to.GetAlignment()), Amount: exchanged},
+		{AccountNumber: toAccountNumber, Description: description, TxType: to.GetAlignment(), Amount: exchanged},
+	}, creator)
+	journal.SetExchangeRate(rate)
+
+	if err := acc.postJournal(context, journal); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// currencyBalancedAmount checks the Transactions of each Currency balance on their own, and returns the credit sum
+// of the Currency of the first transaction. The currencies are those of the Transactions accounts, in the same order.
+// It returns ErrJournalTransactionMixCurrency if any of the currencies do not balance.
+func currencyBalancedAmount(transactions []Transaction, currencies []string) (decimal.Decimal, error) {
+	debitSums := make(map[string]decimal.Decimal)
+	creditSums := make(map[string]decimal.Decimal)
+	for idx, trx := range transactions {
+		if trx.GetAlignment() == DEBIT {
+			debitSums[currencies[idx]] = debitSums[currencies[idx]].Add(trx.GetAmount())
+		} else {
+			creditSums[currencies[idx]] = creditSums[currencies[idx]].Add(trx.GetAmount())
+		}
+	}
+	for _, currency := range currencies {
+		if !debitSums[currency].Equal(creditSums[currency]) {
+			return decimal.Zero, ErrJournalTransactionMixCurrency
+		}
+	}
+	if len(currencies) == 0 {
+		return decimal.Zero, nil
+	}
+	return creditSums[currencies[0]], nil
+}
+
+// decreasingAlignment returns the Alignment of the transactions decreasing the account Balance.
+func decreasingAlignment(account Account) Alignment {
+	return oppositeAlignment(account.GetAlignment())
+}
+
+// oppositeAlignment returns DEBIT for CREDIT, and CREDIT for DEBIT.
+func oppositeAlignment(alignment Alignment) Alignment {
+	if alignment == DEBIT {
+		return CREDIT
+	}
+	return DEBIT
+}
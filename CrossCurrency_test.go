@@ -0,0 +1,142 @@
+package acccore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCrossCurrencyTransfer(t *testing.T, acc *Accounting, exchangeManager ExchangeManager) {
+	ctx := context.Background()
+	am := acc.GetAccountManager()
+
+	_, err := exchangeManager.CreateCurrency(ctx, "GOLD", "Gold", decimal.NewFromFloat(0.01), "superman")
+	assert.NoError(t, err)
+	point, err := exchangeManager.CreateCurrency(ctx, "POINT", "Point", decimal.NewFromInt(1), "superman")
+	assert.NoError(t, err)
+
+	goldReserve, err := acc.CreateNewAccount(ctx, "", "Gold reserve", "Gold reserve", "1.1", "GOLD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	goldPosition, err := acc.CreateNewAccount(ctx, "", "Gold position", "Gold FX position", "1.2", "GOLD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	pointPosition, err := acc.CreateNewAccount(ctx, "", "Point position", "Point FX position", "1.2", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	pointWallet, err := acc.CreateNewAccount(ctx, "", "Point wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	goldWallet := am.NewAccount(ctx).SetAccountNumber(acc.GetUniqueIDGenerator().NewUniqueID()).SetName("Gold wallet").
+		SetDescription("Gold wallet").SetCOA("2.1").SetCurrency("GOLD").SetAlignment(CREDIT).
+		SetBalanceLimit(NonNegativeBalance).SetCreateBy("aCreator")
+	assert.NoError(t, am.PersistAccount(ctx, goldWallet))
+	_, err = acc.CreateNewJournal(ctx, "Topup", []TransactionInfo{
+		{AccountNumber: goldReserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.NewFromInt(50)},
+		{AccountNumber: goldWallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.NewFromInt(50)},
+	}, "aCreator")
+	assert.NoError(t, err)
+
+	assertBalance := func(account Account, expected int64) {
+		t.Helper()
+		loaded, err := am.GetAccountByID(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		assert.True(t, loaded.GetBalance().Equal(decimal.NewFromInt(expected)), "balance of %s is %s", loaded.GetName(), loaded.GetBalance())
+	}
+
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.NewFromInt(10), "aCreator")
+	assert.ErrorIs(t, err, ErrExchangeManagerNotSet)
+	acc.SetExchangeManager(exchangeManager)
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.NewFromInt(10), "aCreator")
+	assert.ErrorIs(t, err, ErrFXPositionAccountNotSet)
+
+	// a POINT position account holding GOLD leaves each Currency unbalanced.
+	acc.SetFXPositionAccount("GOLD", goldPosition.GetAccountNumber()).SetFXPositionAccount("POINT", goldReserve.GetAccountNumber())
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.NewFromInt(10), "aCreator")
+	assert.ErrorIs(t, err, ErrJournalTransactionMixCurrency)
+	assertBalance(goldWallet, 50)
+
+	acc.SetFXPositionAccount("POINT", pointPosition.GetAccountNumber())
+	journal, err := acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.NewFromInt(10), "aCreator")
+	assert.NoError(t, err)
+	assert.Len(t, journal.GetTransactions(), 4)
+	assertBalance(goldWallet, 40)
+	assertBalance(goldPosition, -10)
+	assertBalance(pointPosition, 1000)
+	assertBalance(pointWallet, 1000)
+
+	loaded, err := acc.GetJournalManager().GetJournalByID(ctx, journal.GetJournalID())
+	assert.NoError(t, err)
+	assert.True(t, loaded.GetExchangeRate().Equal(decimal.NewFromInt(100)), "exchange rate %s", loaded.GetExchangeRate())
+	assert.True(t, loaded.GetAmount().Equal(decimal.NewFromInt(10)), "amount %s", loaded.GetAmount())
+	assert.Len(t, loaded.GetTransactions(), 4)
+
+	// all legs are posted at once, or none of them.
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.NewFromInt(100), "aCreator")
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assertBalance(goldWallet, 40)
+	assertBalance(goldPosition, -10)
+	assertBalance(pointPosition, 1000)
+	assertBalance(pointWallet, 1000)
+
+	reversal, err := acc.CreateReversal(ctx, "Undo convert", loaded, "aCreator")
+	assert.NoError(t, err)
+	assert.True(t, reversal.GetExchangeRate().Equal(decimal.NewFromInt(100)))
+	assertBalance(goldWallet, 50)
+	assertBalance(goldPosition, 0)
+	assertBalance(pointPosition, 0)
+	assertBalance(pointWallet, 0)
+
+	// zero or negative amounts are rejected.
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.Zero, "aCreator")
+	assert.ErrorIs(t, err, ErrCrossCurrencyInvalidAmount)
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.NewFromInt(-1), "aCreator")
+	assert.ErrorIs(t, err, ErrCrossCurrencyInvalidAmount)
+
+	// 0.001 GOLD is 0.1 POINT, which leaves a remainder once POINT is rounded to whole points.
+	assert.NoError(t, exchangeManager.UpdateCurrency(ctx, "POINT", point.SetScale(0).SetRoundingMode(RoundHalfEven), "superman"))
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.RequireFromString("0.001"), "aCreator")
+	assert.ErrorIs(t, err, ErrCrossCurrencyRemainder)
+	journal, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldWallet.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.RequireFromString("0.01"), "aCreator")
+	if assert.NoError(t, err) {
+		assert.True(t, journal.GetTransactions()[3].GetAmount().Equal(decimal.NewFromInt(1)))
+	}
+	reversal, err = acc.CreateReversal(ctx, "Undo convert", journal, "aCreator")
+	assert.NoError(t, err)
+	assertBalance(goldWallet, 50)
+	assertBalance(pointWallet, 0)
+
+	// mixing currencies without balancing each of them is still rejected.
+	_, err = acc.CreateNewJournal(ctx, "Mixed", []TransactionInfo{
+		{AccountNumber: goldWallet.GetAccountNumber(), Description: "Gold", TxType: DEBIT, Amount: decimal.NewFromInt(10)},
+		{AccountNumber: pointWallet.GetAccountNumber(), Description: "Point", TxType: CREDIT, Amount: decimal.NewFromInt(10)},
+	}, "aCreator")
+	assert.ErrorIs(t, err, ErrJournalTransactionMixCurrency)
+
+	// same Currency transfers need no FX legs.
+	goldSaving, err := acc.CreateNewAccount(ctx, "", "Gold saving", "Gold saving", "2.1", "GOLD", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	journal, err = acc.CreateCrossCurrencyTransfer(ctx, "Move", goldWallet.GetAccountNumber(), goldSaving.GetAccountNumber(), decimal.NewFromInt(5), "aCreator")
+	if assert.NoError(t, err) {
+		assert.Len(t, journal.GetTransactions(), 2)
+		assert.True(t, journal.GetExchangeRate().IsZero())
+	}
+	assertBalance(goldWallet, 45)
+	assertBalance(goldSaving, 5)
+
+	// moving value between accounts of opposite alignments does not balance, with or without FX legs.
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Move", goldReserve.GetAccountNumber(), goldSaving.GetAccountNumber(), decimal.NewFromInt(5), "aCreator")
+	assert.ErrorIs(t, err, ErrCrossCurrencyAlignmentMismatch)
+	_, err = acc.CreateCrossCurrencyTransfer(ctx, "Convert", goldReserve.GetAccountNumber(), pointWallet.GetAccountNumber(), decimal.NewFromInt(5), "aCreator")
+	assert.ErrorIs(t, err, ErrCrossCurrencyAlignmentMismatch)
+	assertBalance(goldReserve, 50)
+	assertBalance(goldSaving, 5)
+}
+
+func TestAccounting_CrossCurrencyTransfer(t *testing.T) {
+	store := NewInMemoryStore()
+	testCrossCurrencyTransfer(t, newTestAccounting(store), store.GetExchangeManager())
+}
+
+func TestAccounting_CrossCurrencyTransferSQL(t *testing.T) {
+	store := newTestSQLStore(t)
+	testCrossCurrencyTransfer(t, newTestSQLAccounting(store), store.GetExchangeManager())
+}
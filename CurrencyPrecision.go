@@ -0,0 +1,71 @@
+package acccore
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// String returns the name of the rounding mode
+func (mode RoundingMode) String() string {
+	switch mode {
+	case RoundingNone:
+		return "none"
+	case RoundHalfEven:
+		return "half-even"
+	case RoundHalfUp:
+		return "half-up"
+	case RoundDown:
+		return "down"
+	case RoundUp:
+		return "up"
+	case RoundCeiling:
+		return "ceiling"
+	case RoundFloor:
+		return "floor"
+	}
+	return fmt.Sprintf("rounding %d", int(mode))
+}
+
+// Round rounds the amount to the scale using this rounding mode. RoundingNone returns the amount as is.
+func (mode RoundingMode) Round(amount decimal.Decimal, scale int32) decimal.Decimal {
+	switch mode {
+	case RoundHalfEven:
+		return amount.RoundBank(scale)
+	case RoundHalfUp:
+		return amount.Round(scale)
+	case RoundDown:
+		return amount.Truncate(scale)
+	case RoundUp:
+		return amount.RoundUp(scale)
+	case RoundCeiling:
+		return amount.RoundCeil(scale)
+	case RoundFloor:
+		return amount.RoundFloor(scale)
+	}
+	return amount
+}
+
+// RoundCurrencyAmount rounds the amount to the scale of the Currency using its rounding mode, and returns the rounded amount
+// together with the remainder left by rounding, which is the amount minus the rounded amount.
+func RoundCurrencyAmount(currency Currency, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	rounded := currency.GetRoundingMode().Round(amount, currency.GetScale())
+	return rounded, amount.Sub(rounded)
+}
+
+// validateCurrencyPrecision checks the scale and the rounding mode of the Currency are known.
+func validateCurrencyPrecision(currency Currency) error {
+	if currency.GetScale() < 0 || currency.GetRoundingMode() < RoundingNone || currency.GetRoundingMode() > RoundFloor {
+		return ErrCurrencyInvalidPrecision
+	}
+	return nil
+}
+
+// checkAmountPrecision returns ErrJournalTransactionAmountPrecision if the amount have more decimals than the scale.
+// Amounts of currencies that are not rounded may have any number of decimals.
+func checkAmountPrecision(amount decimal.Decimal, scale int32, mode RoundingMode) error {
+	if mode != RoundingNone && !amount.Equal(amount.Truncate(scale)) {
+		return ErrJournalTransactionAmountPrecision
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package acccore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundingMode_Round(t *testing.T) {
+	cases := []struct {
+		mode               RoundingMode
+		positive, negative string
+	}{
+		{RoundingNone, "2.345", "-2.345"},
+		{RoundHalfEven, "2.34", "-2.34"},
+		{RoundHalfUp, "2.35", "-2.35"},
+		{RoundDown, "2.34", "-2.34"},
+		{RoundUp, "2.35", "-2.35"},
+		{RoundCeiling, "2.35", "-2.34"},
+		{RoundFloor, "2.34", "-2.35"},
+	}
+	for _, c := range cases {
+		positive := c.mode.Round(decimal.RequireFromString("2.345"), 2)
+		negative := c.mode.Round(decimal.RequireFromString("-2.345"), 2)
+		assert.True(t, positive.Equal(decimal.RequireFromString(c.positive)), "%s rounds 2.345 into %s", c.mode, positive)
+		assert.True(t, negative.Equal(decimal.RequireFromString(c.negative)), "%s rounds -2.345 into %s", c.mode, negative)
+	}
+	assert.True(t, RoundHalfEven.Round(decimal.RequireFromString("2.355"), 2).Equal(decimal.RequireFromString("2.36")))
+}
+
+func testCurrencyPrecision(t *testing.T, acc *Accounting, exchangeManager ExchangeManager) {
+	ctx := context.Background()
+
+	usd, err := exchangeManager.CreateCurrency(ctx, "USD", "US Dollar", decimal.NewFromInt(1), "superman")
+	assert.NoError(t, err)
+	assert.Equal(t, RoundingNone, usd.GetRoundingMode())
+	idr, err := exchangeManager.CreateCurrency(ctx, "IDR", "Rupiah", decimal.NewFromInt(15000), "superman")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, exchangeManager.UpdateCurrency(ctx, "USD", usd.SetScale(-1).SetRoundingMode(RoundHalfEven), "superman"), ErrCurrencyInvalidPrecision)
+	assert.ErrorIs(t, exchangeManager.UpdateCurrency(ctx, "USD", usd.SetScale(2).SetRoundingMode(RoundingMode(42)), "superman"), ErrCurrencyInvalidPrecision)
+	assert.NoError(t, exchangeManager.UpdateCurrency(ctx, "USD", usd.SetScale(2).SetRoundingMode(RoundHalfEven), "superman"))
+	assert.NoError(t, exchangeManager.UpdateCurrency(ctx, "IDR", idr.SetScale(0).SetRoundingMode(RoundDown), "superman"))
+	usd, err = exchangeManager.GetCurrency(ctx, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), usd.GetScale())
+	assert.Equal(t, RoundHalfEven, usd.GetRoundingMode())
+
+	// journal amounts must fit the scale of their Currency.
+	cash, err := acc.CreateNewAccount(ctx, "", "Cash", "Cash", "1.1", "USD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "", "Equity", "Equity", "3.1", "USD", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	capital := func(amount string) []TransactionInfo {
+		return []TransactionInfo{
+			{AccountNumber: cash.GetAccountNumber(), Description: "Cash", TxType: DEBIT, Amount: decimal.RequireFromString(amount)},
+			{AccountNumber: equity.GetAccountNumber(), Description: "Capital", TxType: CREDIT, Amount: decimal.RequireFromString(amount)},
+		}
+	}
+	_, err = acc.CreateNewJournal(ctx, "Capital", capital("10.005"), "aCreator")
+	assert.ErrorIs(t, err, ErrJournalTransactionAmountPrecision)
+	_, err = acc.CreateNewJournal(ctx, "Capital", capital("10.010"), "aCreator")
+	assert.NoError(t, err)
+	loaded, err := acc.GetAccountManager().GetAccountByID(ctx, cash.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, loaded.GetBalance().Equal(decimal.RequireFromString("10.01")))
+
+	// exchanges are rounded to the scale of the target Currency, and the remainder is reported.
+	rate, err := exchangeManager.CalculateExchangeRate(ctx, "IDR", "USD")
+	assert.NoError(t, err)
+	exchanged, remainder, err := exchangeManager.CalculateExchangeWithRemainder(ctx, "IDR", "USD", decimal.NewFromInt(100))
+	assert.NoError(t, err)
+	assert.True(t, exchanged.Equal(decimal.RequireFromString("0.01")), "exchanged %s", exchanged)
+	assert.True(t, remainder.IsNegative())
+	assert.True(t, exchanged.Add(remainder).Equal(rate.Mul(decimal.NewFromInt(100))))
+	plain, err := exchangeManager.CalculateExchange(ctx, "IDR", "USD", decimal.NewFromInt(100))
+	assert.NoError(t, err)
+	assert.True(t, plain.Equal(exchanged))
+
+	exchanged, remainder, err = exchangeManager.CalculateExchangeWithRemainder(ctx, "USD", "IDR", decimal.RequireFromString("1.23"))
+	assert.NoError(t, err)
+	assert.True(t, exchanged.Equal(decimal.NewFromInt(18450)), "exchanged %s", exchanged)
+	assert.True(t, remainder.IsZero())
+	exchanged, remainder, err = exchangeManager.CalculateExchangeWithRemainder(ctx, "USD", "IDR", decimal.RequireFromString("0.00001"))
+	assert.NoError(t, err)
+	assert.True(t, exchanged.IsZero(), "exchanged %s", exchanged)
+	assert.True(t, remainder.Equal(decimal.RequireFromString("0.15")), "remainder %s", remainder)
+}
+
+func TestAccounting_CurrencyPrecision(t *testing.T) {
+	store := NewInMemoryStore()
+	testCurrencyPrecision(t, newTestAccounting(store), store.GetExchangeManager())
+}
+
+func TestAccounting_CurrencyPrecisionSQL(t *testing.T) {
+	store := newTestSQLStore(t)
+	testCurrencyPrecision(t, newTestSQLAccounting(store), store.GetExchangeManager())
+}
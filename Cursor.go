@@ -0,0 +1,235 @@
+package acccore
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// journalCursorListing identifies the cursors of the journal listing
+	journalCursorListing = "journal"
+)
+
+// transactionCursorListing identifies the cursors of the transaction listing of the account.
+func transactionCursorListing(account Account) string {
+	return "transaction:" + account.GetAccountNumber()
+}
+
+// CursorRequest define the cursor pagination request when listing a dataset that keeps growing.
+// Unlike PageRequest, a cursor points right after (or before) the last seen item, so items inserted between
+// page fetches never cause duplicates or skipped items. The items are always ordered by the sequence they were
+// committed in and then their ID. Commit sequences are handed out in the order the commits become visible, so an item
+// committed concurrently can never show up behind a cursor already returned. Items committed before the commit sequence
+// was recorded come first, ordered by their time.
+type CursorRequest struct {
+	// Cursor is the NextCursor or PreviousCursor of a previous CursorResult.
+	// An empty cursor requests the first page.
+	Cursor string
+
+	// ItemSize is the maximum item should be contained within a single page
+	ItemSize int
+}
+
+// CursorResult define the cursor pagination result that returned together with the listing.
+type CursorResult struct {
+	// Request define the request that specified the pagination in the first place.
+	Request CursorRequest
+
+	// PageSize shows the current number of items in this page
+	PageSize int
+
+	// NextCursor is the cursor to request the items after this page.
+	// If the page is empty, it is the requested cursor, so it can be used to poll for new items.
+	NextCursor string
+
+	// PreviousCursor is the cursor to request the items before this page.
+	// If the page is empty, it is the requested cursor.
+	PreviousCursor string
+
+	// HaveNext is an indicator if there are more items after this page at the time of the listing
+	HaveNext bool
+
+	// HavePrev is an indicator if there are items before this page at the time of the listing
+	HavePrev bool
+}
+
+// cursorPosition is the content of a cursor, the position of an item in the listing it was created for.
+type cursorPosition struct {
+	// Listing identifies the listing the cursor belongs to, a cursor can not be used on other listing
+	Listing string `json:"l"`
+	// Sequence is the commit sequence of the item
+	Sequence int64 `json:"s,omitempty"`
+	// Time is the time of the item, in unix nano
+	Time int64 `json:"t"`
+	// ID is the ID of the item
+	ID string `json:"i"`
+	// Backward is true if the cursor lists the items before the position
+	Backward bool `json:"b,omitempty"`
+}
+
+// cursorCodec encodes cursor positions into opaque cursors signed using HMAC-SHA256, and decodes them back.
+type cursorCodec struct {
+	secret []byte
+}
+
+// newCursorCodec creates a codec signing with a random secret.
+func newCursorCodec() *cursorCodec {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return &cursorCodec{secret: secret}
+}
+
+// sign returns the signature of the payload.
+func (codec *cursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, codec.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// encode creates the opaque cursor of the position.
+func (codec *cursorCodec) encode(position cursorPosition) string {
+	payload, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(codec.sign(payload))
+}
+
+// decode reads the position out of the cursor. It returns ErrCursorInvalid if the cursor is malformed,
+// if its signature does not match, or if it belongs to other listing.
+func (codec *cursorCodec) decode(cursor, listing string) (cursorPosition, error) {
+	position := cursorPosition{}
+	encodedPayload, encodedSignature, found := strings.Cut(cursor, ".")
+	if !found {
+		return position, ErrCursorInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return position, ErrCursorInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, codec.sign(payload)) {
+		return position, ErrCursorInvalid
+	}
+	if err := json.Unmarshal(payload, &position); err != nil || position.Listing != listing {
+		return position, ErrCursorInvalid
+	}
+	return position, nil
+}
+
+// cursorKey is the key items are listed by, the sequence they were committed in, then their time and their ID.
+type cursorKey struct {
+	Sequence int64
+	Time     time.Time
+	ID       string
+}
+
+// before returns true if the item of this key is listed before the item of the other key.
+func (key cursorKey) before(other cursorKey) bool {
+	if key.Sequence != other.Sequence {
+		return key.Sequence < other.Sequence
+	}
+	if !key.Time.Equal(other.Time) {
+		return key.Time.Before(other.Time)
+	}
+	return key.ID < other.ID
+}
+
+// getKey returns the key of the item at the position.
+func (position cursorPosition) getKey() cursorKey {
+	return cursorKey{Sequence: position.Sequence, Time: time.Unix(0, position.Time).UTC(), ID: position.ID}
+}
+
+// isFirst returns true if this is the position before the first item, requesting the first page.
+func (position cursorPosition) isFirst() bool {
+	return position.Sequence == 0 && position.Time == 0 && len(position.ID) == 0
+}
+
+// includes returns true if the item with the key is listed from this position,
+// which are the items after the position, or before it for a backward position.
+func (position cursorPosition) includes(key cursorKey) bool {
+	if position.isFirst() {
+		return true
+	}
+	if position.Backward {
+		return key.before(position.getKey())
+	}
+	return position.getKey().before(key)
+}
+
+// fetchedBefore returns true if the item of key a is fetched before the item of key b from this position,
+// which is the listing order, or the reverse order for a backward position.
+func (position cursorPosition) fetchedBefore(a, b cursorKey) bool {
+	if position.Backward {
+		return b.before(a)
+	}
+	return a.before(b)
+}
+
+// sqlKeyset returns the SQL condition selecting the items listed from this position, with its arguments,
+// and the ORDER BY expression to fetch them. The condition is empty for the first page.
+func (position cursorPosition) sqlKeyset(sequenceColumn, timeColumn, idColumn string) (string, []any, string) {
+	operator, direction := ">", "ASC"
+	if position.Backward {
+		operator, direction = "<", "DESC"
+	}
+	orderBy := sequenceColumn + " " + direction + ", " + timeColumn + " " + direction + ", " + idColumn + " " + direction
+	if position.isFirst() {
+		return "", nil, orderBy
+	}
+	key := position.getKey()
+	condition := " AND (" + sequenceColumn + " " + operator + " ? OR (" + sequenceColumn + " = ? AND (" +
+		timeColumn + " " + operator + " ? OR (" + timeColumn + " = ? AND " + idColumn + " " + operator + " ?))))"
+	return condition, []any{key.Sequence, key.Sequence, key.Time, key.Time, key.ID}, orderBy
+}
+
+// cursorPage creates the page out of the items fetched for a cursor request. The items must be in the listing
+// order, or in the reverse order for a backward position, and contain up to one more item than the ItemSize
+// to tell if there are more items beyond the page.
+// The items are returned in the listing order.
+func cursorPage[T any](codec *cursorCodec, listing string, request CursorRequest, position cursorPosition, items []T,
+	keyOf func(T) cursorKey) (CursorResult, []T) {
+	result := CursorResult{
+		Request:        request,
+		NextCursor:     request.Cursor,
+		PreviousCursor: request.Cursor,
+	}
+	more := len(items) > request.ItemSize
+	if more {
+		items = items[:request.ItemSize]
+	}
+	if position.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+		result.HavePrev = more
+		result.HaveNext = len(request.Cursor) > 0
+	} else {
+		result.HaveNext = more
+		result.HavePrev = len(request.Cursor) > 0
+	}
+	result.PageSize = len(items)
+	if len(items) > 0 {
+		first, last := keyOf(items[0]), keyOf(items[len(items)-1])
+		result.PreviousCursor = codec.encode(cursorPosition{Listing: listing, Sequence: first.Sequence, Time: first.Time.UnixNano(), ID: first.ID, Backward: true})
+		result.NextCursor = codec.encode(cursorPosition{Listing: listing, Sequence: last.Sequence, Time: last.Time.UnixNano(), ID: last.ID})
+	}
+	return result, items
+}
+
+// decodeCursorRequest decodes the cursor of the request. An empty cursor decodes into the position
+// before the first item. Like PageRequest, an ItemSize less than 1 is treated as 1.
+func decodeCursorRequest(codec *cursorCodec, listing string, request CursorRequest) (CursorRequest, cursorPosition, error) {
+	if request.ItemSize < 1 {
+		request.ItemSize = 1
+	}
+	if len(request.Cursor) == 0 {
+		return request, cursorPosition{Listing: listing}, nil
+	}
+	position, err := codec.decode(request.Cursor, listing)
+	return request, position, err
+}
@@ -0,0 +1,43 @@
+package acccore
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CurrencyRate is the history record of the exchange value of a Currency, in effect from ValidFrom until ValidTo.
+type CurrencyRate struct {
+	// Code is the Currency whose exchange value this is
+	Code string
+	// Exchange is the exchange value of the Currency against the common denominator
+	Exchange decimal.Decimal
+	// ValidFrom is the time this exchange value takes effect, inclusive
+	ValidFrom time.Time
+	// ValidTo is the time this exchange value is replaced, exclusive. Zero while still in effect.
+	ValidTo time.Time
+	// CreateBy is the user who set this exchange value
+	CreateBy string
+}
+
+// IsEffectiveAt returns true if the exchange value is in effect at the specified time.
+func (rate *CurrencyRate) IsEffectiveAt(at time.Time) bool {
+	return !rate.ValidFrom.After(at) && (rate.ValidTo.IsZero() || rate.ValidTo.After(at))
+}
+
+// effectiveRate returns the exchange value in effect at the specified time among the rates, nil if there is none.
+func effectiveRate(rates []*CurrencyRate, at time.Time) *CurrencyRate {
+	for _, rate := range rates {
+		if rate.IsEffectiveAt(at) {
+			return rate
+		}
+	}
+	return nil
+}
+
+// exchangeRate calculates the rate of exchanging between two currencies, out of their exchange value against the common denominator.
+func exchangeRate(denom, fromExchange, toExchange decimal.Decimal) decimal.Decimal {
+	m1 := denom.Div(fromExchange)
+	m2 := m1.Mul(toExchange)
+	return m2.Div(denom)
+}
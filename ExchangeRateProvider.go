@@ -0,0 +1,270 @@
+package acccore
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxExchangeRatesDocumentSize is the size the JSON document of the HTTP provider is limited to, 1 MiB
+	maxExchangeRatesDocumentSize = 1 << 20
+)
+
+// ExchangeRates are the exchange values of currencies against the common denominator, as published by a provider.
+type ExchangeRates struct {
+	// Rates are the exchange values keyed by the Currency code
+	Rates map[string]decimal.Decimal
+	// AsOf is the time the exchange values were published
+	AsOf time.Time
+}
+
+// ExchangeRateProvider provides the latest exchange values of currencies from a source outside the ledger.
+type ExchangeRateProvider interface {
+	// FetchRates fetches the latest exchange values published by the provider.
+	FetchRates(context context.Context) (*ExchangeRates, error)
+}
+
+// NewCSVExchangeRateProvider creates a provider reading the exchange values from a CSV file.
+// Each record has the Currency code and its exchange value, an optional first record `code,exchange` is taken as the header.
+// The exchange values are as of the time the file was last modified.
+func NewCSVExchangeRateProvider(path string) *CSVExchangeRateProvider {
+	return &CSVExchangeRateProvider{path: path}
+}
+
+// CSVExchangeRateProvider is an ExchangeRateProvider reading a CSV file.
+type CSVExchangeRateProvider struct {
+	path string
+}
+
+// FetchRates reads the exchange values from the CSV file.
+func (provider *CSVExchangeRateProvider) FetchRates(context context.Context) (*ExchangeRates, error) {
+	if err := context.Err(); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(provider.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	rates := &ExchangeRates{Rates: make(map[string]decimal.Decimal), AsOf: info.ModTime()}
+	for line := 1; ; line++ {
+		if err := context.Err(); err != nil {
+			return nil, err
+		}
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "code") {
+			continue
+		}
+		exchange, err := decimal.NewFromString(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d of %s: %w", ErrExchangeRateInvalid, line, provider.path, err)
+		}
+		rates.Rates[strings.TrimSpace(record[0])] = exchange
+	}
+	return rates, nil
+}
+
+// NewHTTPExchangeRateProvider creates a provider fetching the exchange values as JSON from the URL, using http.DefaultClient.
+// The JSON document looks like `{"as_of": "2006-01-02T15:04:05Z", "rates": {"GOLD": "0.01", "POINT": "1"}}`,
+// the exchange values may be strings or numbers. Without `as_of`, the exchange values are as of the time they are fetched.
+// Documents larger than 1 MiB are rejected.
+func NewHTTPExchangeRateProvider(url string) *HTTPExchangeRateProvider {
+	return &HTTPExchangeRateProvider{url: url, client: http.DefaultClient}
+}
+
+// HTTPExchangeRateProvider is an ExchangeRateProvider fetching a JSON document over HTTP.
+type HTTPExchangeRateProvider struct {
+	url    string
+	client *http.Client
+}
+
+// SetClient sets the HTTP client used to fetch the exchange values, to set timeouts or authentication.
+func (provider *HTTPExchangeRateProvider) SetClient(client *http.Client) *HTTPExchangeRateProvider {
+	provider.client = client
+	return provider
+}
+
+// FetchRates fetches the exchange values from the URL.
+func (provider *HTTPExchangeRateProvider) FetchRates(context context.Context) (*ExchangeRates, error) {
+	request, err := http.NewRequestWithContext(context, http.MethodGet, provider.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s responded %s", ErrExchangeRateProviderFailed, provider.url, response.Status)
+	}
+
+	document := struct {
+		AsOf  time.Time                  `json:"as_of"`
+		Rates map[string]decimal.Decimal `json:"rates"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxExchangeRatesDocumentSize)).Decode(&document); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrExchangeRateInvalid, provider.url, err)
+	}
+	if document.AsOf.IsZero() {
+		document.AsOf = time.Now()
+	}
+	return &ExchangeRates{Rates: document.Rates, AsOf: document.AsOf}, nil
+}
+
+// NewExchangeRateRefresher creates a refresher pulling the exchange values from the providers into the exchange manager.
+// The providers form a fallback chain, they are tried in order until one provides exchange values that are not stale.
+// The author is recorded as the one updating the currencies. By default, exchange values older than a day are stale.
+func NewExchangeRateRefresher(exchangeManager ExchangeManager, author string, providers ...ExchangeRateProvider) *ExchangeRateRefresher {
+	return &ExchangeRateRefresher{
+		exchangeManager: exchangeManager,
+		author:          author,
+		providers:       providers,
+		maxAge:          24 * time.Hour,
+	}
+}
+
+// ExchangeRateRefresher pulls the exchange values of the currencies known to the exchange manager from a chain of providers.
+// Exchange values of currencies the exchange manager does not know are ignored.
+type ExchangeRateRefresher struct {
+	exchangeManager ExchangeManager
+	author          string
+	providers       []ExchangeRateProvider
+	maxAge          time.Duration
+
+	mutex    sync.RWMutex
+	lastAsOf time.Time
+}
+
+// SetMaxAge sets how old the exchange values may be before they are stale.
+func (refresher *ExchangeRateRefresher) SetMaxAge(maxAge time.Duration) *ExchangeRateRefresher {
+	refresher.maxAge = maxAge
+	return refresher
+}
+
+// GetLastAsOf returns the time the exchange values last refreshed were published, zero if never refreshed.
+func (refresher *ExchangeRateRefresher) GetLastAsOf() time.Time {
+	refresher.mutex.RLock()
+	defer refresher.mutex.RUnlock()
+	return refresher.lastAsOf
+}
+
+// IsStale returns true if the exchange values last refreshed are older than the max age at the specified time,
+// or if they were never refreshed.
+func (refresher *ExchangeRateRefresher) IsStale(at time.Time) bool {
+	lastAsOf := refresher.GetLastAsOf()
+	return lastAsOf.IsZero() || at.Sub(lastAsOf) > refresher.maxAge
+}
+
+// Refresh fetches the exchange values from the first provider of the chain that provides valid exchange values
+// that are not stale, and updates the currencies whose exchange value have changed all at once, the new exchange values
+// taking effect as of the time they were published.
+// If no provider succeeds, the currencies are left untouched and an error wrapping ErrExchangeRateProviderFailed
+// together with the error of each provider is returned.
+func (refresher *ExchangeRateRefresher) Refresh(context context.Context) error {
+	errs := make([]error, 0, len(refresher.providers))
+	for idx, provider := range refresher.providers {
+		rates, err := refresher.fetchRates(context, provider)
+		if err != nil {
+			logrus.Errorf("error refreshing exchange rates from provider %d. got %s", idx, err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		return refresher.apply(context, rates)
+	}
+	return fmt.Errorf("%w: %w", ErrExchangeRateProviderFailed, errors.Join(errs...))
+}
+
+// Run refreshes the exchange values right away and then on every interval, until the context is done.
+// Failed refreshes are logged, and retried on the next interval.
+// It returns ErrExchangeRateInvalidInterval if the interval is not positive.
+func (refresher *ExchangeRateRefresher) Run(context context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrExchangeRateInvalidInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := refresher.Refresh(context); err != nil {
+			logrus.Errorf("error refreshing exchange rates. got %s", err.Error())
+			if refresher.IsStale(time.Now()) {
+				logrus.Warnf("exchange rates are stale, last refreshed as of %s", refresher.GetLastAsOf())
+			}
+		}
+		select {
+		case <-context.Done():
+			return context.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchRates fetches and checks the exchange values of the provider.
+func (refresher *ExchangeRateRefresher) fetchRates(context context.Context, provider ExchangeRateProvider) (*ExchangeRates, error) {
+	rates, err := provider.FetchRates(context)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(rates.AsOf) > refresher.maxAge {
+		return nil, fmt.Errorf("%w: published as of %s", ErrExchangeRatesStale, rates.AsOf)
+	}
+	for code, exchange := range rates.Rates {
+		if !exchange.IsPositive() {
+			return nil, fmt.Errorf("%w: %s is %s", ErrExchangeRateInvalid, code, exchange)
+		}
+	}
+	return rates, nil
+}
+
+// apply updates the currencies known to the exchange manager whose exchange value have changed, all at once.
+// The new exchange values take effect as of the time they were published.
+func (refresher *ExchangeRateRefresher) apply(context context.Context, rates *ExchangeRates) error {
+	currencies, err := refresher.exchangeManager.ListCurrencies(context)
+	if err != nil {
+		return err
+	}
+	changed := make(map[string]decimal.Decimal)
+	for _, currency := range currencies {
+		exchange, exist := rates.Rates[currency.GetCode()]
+		if !exist || exchange.Equal(currency.GetExchange()) {
+			continue
+		}
+		changed[currency.GetCode()] = exchange
+	}
+	if len(changed) > 0 {
+		if err := refresher.exchangeManager.UpdateCurrencyRates(context, changed, rates.AsOf, refresher.author); err != nil {
+			return err
+		}
+	}
+	refresher.mutex.Lock()
+	defer refresher.mutex.Unlock()
+	refresher.lastAsOf = rates.AsOf
+	return nil
+}
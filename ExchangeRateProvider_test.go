@@ -0,0 +1,142 @@
+package acccore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVExchangeRateProvider_FetchRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("code,exchange\nGOLD, 0.02\nPOINT,1\n"), 0600))
+	rates, err := NewCSVExchangeRateProvider(path).FetchRates(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, rates.Rates, 2)
+	assert.True(t, rates.Rates["GOLD"].Equal(decimal.NewFromFloat(0.02)))
+	assert.WithinDuration(t, time.Now(), rates.AsOf, time.Minute)
+
+	assert.NoError(t, os.WriteFile(path, []byte("GOLD,a lot\n"), 0600))
+	_, err = NewCSVExchangeRateProvider(path).FetchRates(context.Background())
+	assert.ErrorIs(t, err, ErrExchangeRateInvalid)
+	_, err = NewCSVExchangeRateProvider(filepath.Join(t.TempDir(), "missing.csv")).FetchRates(context.Background())
+	assert.Error(t, err)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewCSVExchangeRateProvider(path).FetchRates(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestHTTPExchangeRateProvider_FetchRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rates":
+			_, _ = fmt.Fprint(w, `{"as_of": "2026-01-02T03:04:05Z", "rates": {"GOLD": "0.02", "POINT": 1}}`)
+		case "/garbage":
+			_, _ = fmt.Fprint(w, `<html>`)
+		case "/huge":
+			_, _ = fmt.Fprintf(w, `{"rates": {"GOLD": "0.%s"}}`, strings.Repeat("1", maxExchangeRatesDocumentSize))
+		default:
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	rates, err := NewHTTPExchangeRateProvider(server.URL + "/rates").SetClient(server.Client()).FetchRates(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, rates.Rates["GOLD"].Equal(decimal.NewFromFloat(0.02)))
+	assert.True(t, rates.Rates["POINT"].Equal(decimal.NewFromInt(1)))
+	assert.True(t, rates.AsOf.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	_, err = NewHTTPExchangeRateProvider(server.URL + "/garbage").FetchRates(context.Background())
+	assert.ErrorIs(t, err, ErrExchangeRateInvalid)
+	_, err = NewHTTPExchangeRateProvider(server.URL + "/huge").FetchRates(context.Background())
+	assert.ErrorIs(t, err, ErrExchangeRateInvalid)
+	_, err = NewHTTPExchangeRateProvider(server.URL + "/down").FetchRates(context.Background())
+	assert.ErrorIs(t, err, ErrExchangeRateProviderFailed)
+}
+
+func TestExchangeRateRefresher_Refresh(t *testing.T) {
+	ctx := context.Background()
+	exchangeManager := NewInMemoryStore().GetExchangeManager()
+	_, err := exchangeManager.CreateCurrency(ctx, "GOLD", "Gold", decimal.NewFromFloat(0.01), "superman")
+	assert.NoError(t, err)
+	_, err = exchangeManager.CreateCurrency(ctx, "POINT", "Point", decimal.NewFromInt(1), "superman")
+	assert.NoError(t, err)
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		switch r.URL.Path {
+		case "/stale":
+			_, _ = fmt.Fprint(w, `{"as_of": "2020-01-01T00:00:00Z", "rates": {"GOLD": "0.5"}}`)
+		case "/fresh":
+			_, _ = fmt.Fprintf(w, `{"as_of": %q, "rates": {"GOLD": "0.04", "SILVER": "0.1"}}`, time.Now().UTC().Format(time.RFC3339Nano))
+		default:
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("GOLD,0.02\n"), 0600))
+	// the file is published after the currencies were created.
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Millisecond)))
+
+	// the down and stale providers are skipped, falling back on the file.
+	refresher := NewExchangeRateRefresher(exchangeManager, "rate-bot",
+		NewHTTPExchangeRateProvider(server.URL+"/down"), NewHTTPExchangeRateProvider(server.URL+"/stale"), NewCSVExchangeRateProvider(path))
+	assert.True(t, refresher.IsStale(time.Now()))
+	assert.NoError(t, refresher.Refresh(ctx))
+	assert.False(t, refresher.IsStale(time.Now()))
+	rate, err := exchangeManager.CalculateExchangeRate(ctx, "GOLD", "POINT")
+	assert.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(50)), "rate %s", rate)
+	rates, err := exchangeManager.ListCurrencyRates(ctx, "GOLD")
+	assert.NoError(t, err)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	if assert.Len(t, rates, 2) {
+		assert.Equal(t, "rate-bot", rates[1].CreateBy)
+		assert.True(t, rates[1].ValidFrom.Equal(info.ModTime()), "valid from %s", rates[1].ValidFrom)
+	}
+	// unchanged exchange values are not recorded again.
+	assert.NoError(t, refresher.Refresh(ctx))
+	rates, err = exchangeManager.ListCurrencyRates(ctx, "GOLD")
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+	assert.True(t, refresher.SetMaxAge(time.Nanosecond).IsStale(info.ModTime().Add(time.Second)))
+
+	// no provider succeeding leaves the exchange values untouched.
+	failing := NewExchangeRateRefresher(exchangeManager, "rate-bot",
+		NewHTTPExchangeRateProvider(server.URL+"/down"), NewHTTPExchangeRateProvider(server.URL+"/stale"))
+	err = failing.Refresh(ctx)
+	assert.ErrorIs(t, err, ErrExchangeRateProviderFailed)
+	assert.ErrorIs(t, err, ErrExchangeRatesStale)
+	rate, err = exchangeManager.CalculateExchangeRate(ctx, "GOLD", "POINT")
+	assert.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(50)), "rate %s", rate)
+
+	// refreshing on schedule, currencies unknown to the exchange manager are ignored.
+	fetches.Store(0)
+	scheduled := NewExchangeRateRefresher(exchangeManager, "rate-bot", NewHTTPExchangeRateProvider(server.URL+"/fresh"))
+	assert.ErrorIs(t, scheduled.Run(ctx, 0), ErrExchangeRateInvalidInterval)
+	runCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, scheduled.Run(runCtx, 20*time.Millisecond), context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, fetches.Load(), int32(2))
+	rate, err = exchangeManager.CalculateExchangeRate(ctx, "GOLD", "POINT")
+	assert.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(25)), "rate %s", rate)
+	exist, err := exchangeManager.IsCurrencyExist(ctx, "SILVER")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+}
@@ -0,0 +1,102 @@
+package acccore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCurrencyRateHistory(t *testing.T, exchangeManager ExchangeManager) {
+	ctx := context.Background()
+
+	beforeCreate := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	_, err := exchangeManager.CreateCurrency(ctx, "GOLD", "Gold", decimal.NewFromFloat(0.01), "superman")
+	assert.NoError(t, err)
+	_, err = exchangeManager.CreateCurrency(ctx, "POINT", "Point", decimal.NewFromInt(1), "superman")
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	lastWeek := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	gold, err := exchangeManager.GetCurrency(ctx, "GOLD")
+	assert.NoError(t, err)
+	assert.NoError(t, exchangeManager.UpdateCurrency(ctx, "GOLD", gold.SetExchange(decimal.NewFromFloat(0.02)), "batman"))
+	// renaming keeps the exchange value in effect.
+	assert.NoError(t, exchangeManager.UpdateCurrency(ctx, "GOLD", gold.SetName("Gold bar"), "robin"))
+
+	rate, err := exchangeManager.CalculateExchangeRateAt(ctx, "GOLD", "POINT", lastWeek)
+	assert.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(100)), "rate last week %s", rate)
+	rate, err = exchangeManager.CalculateExchangeRateAt(ctx, "GOLD", "POINT", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(50)), "rate now %s", rate)
+	current, err := exchangeManager.CalculateExchangeRate(ctx, "GOLD", "POINT")
+	assert.NoError(t, err)
+	assert.True(t, current.Equal(rate))
+
+	exchanged, err := exchangeManager.CalculateExchangeAt(ctx, "GOLD", "POINT", decimal.NewFromInt(3), lastWeek)
+	assert.NoError(t, err)
+	assert.True(t, exchanged.Equal(decimal.NewFromInt(300)), "exchanged %s", exchanged)
+	exchanged, err = exchangeManager.CalculateExchangeAt(ctx, "POINT", "GOLD", decimal.NewFromInt(300), lastWeek)
+	assert.NoError(t, err)
+	assert.True(t, exchanged.Equal(decimal.NewFromInt(3)), "exchanged %s", exchanged)
+
+	_, err = exchangeManager.CalculateExchangeRateAt(ctx, "GOLD", "POINT", beforeCreate)
+	assert.ErrorIs(t, err, ErrCurrencyRateNotFound)
+	_, err = exchangeManager.CalculateExchangeRateAt(ctx, "GOLD", "SILVER", time.Now())
+	assert.ErrorIs(t, err, ErrCurrencyNotFound)
+
+	rates, err := exchangeManager.ListCurrencyRates(ctx, "GOLD")
+	assert.NoError(t, err)
+	if assert.Len(t, rates, 2) {
+		assert.True(t, rates[0].Exchange.Equal(decimal.NewFromFloat(0.01)))
+		assert.Equal(t, "superman", rates[0].CreateBy)
+		assert.True(t, rates[0].ValidTo.Equal(rates[1].ValidFrom))
+		assert.True(t, rates[1].Exchange.Equal(decimal.NewFromFloat(0.02)))
+		assert.Equal(t, "batman", rates[1].CreateBy)
+		assert.True(t, rates[1].ValidTo.IsZero())
+		assert.True(t, rates[0].IsEffectiveAt(lastWeek))
+		assert.False(t, rates[1].IsEffectiveAt(lastWeek))
+	}
+	_, err = exchangeManager.ListCurrencyRates(ctx, "SILVER")
+	assert.ErrorIs(t, err, ErrCurrencyNotFound)
+
+	// the exchange values of many currencies change all at once, as of the specified time, or not at all.
+	publishedAt := rates[1].ValidFrom.Add(time.Hour)
+	assert.ErrorIs(t, exchangeManager.UpdateCurrencyRates(ctx, map[string]decimal.Decimal{
+		"GOLD": decimal.NewFromFloat(0.04), "SILVER": decimal.NewFromFloat(0.1),
+	}, publishedAt, "rate-bot"), ErrCurrencyNotFound)
+	assert.ErrorIs(t, exchangeManager.UpdateCurrencyRates(ctx, map[string]decimal.Decimal{
+		"GOLD": decimal.NewFromFloat(0.04), "POINT": decimal.NewFromInt(2),
+	}, rates[1].ValidFrom, "rate-bot"), ErrCurrencyRateOutOfOrder)
+	rates, err = exchangeManager.ListCurrencyRates(ctx, "GOLD")
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+	assert.NoError(t, exchangeManager.UpdateCurrencyRates(ctx, map[string]decimal.Decimal{
+		"GOLD": decimal.NewFromFloat(0.04), "POINT": decimal.NewFromInt(2),
+	}, publishedAt, "rate-bot"))
+	for code, count := range map[string]int{"GOLD": 3, "POINT": 2} {
+		rates, err = exchangeManager.ListCurrencyRates(ctx, code)
+		assert.NoError(t, err)
+		if assert.Len(t, rates, count) {
+			assert.True(t, rates[count-1].ValidFrom.Equal(publishedAt), "%s valid from %s", code, rates[count-1].ValidFrom)
+			assert.True(t, rates[count-2].ValidTo.Equal(publishedAt))
+			assert.Equal(t, "rate-bot", rates[count-1].CreateBy)
+		}
+	}
+	rate, err = exchangeManager.CalculateExchangeRateAt(ctx, "GOLD", "POINT", publishedAt)
+	assert.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(50)), "rate when published %s", rate)
+}
+
+func TestInMemoryExchangeManager_RateHistory(t *testing.T) {
+	testCurrencyRateHistory(t, NewInMemoryStore().GetExchangeManager())
+}
+
+func TestSQLExchangeManager_RateHistory(t *testing.T) {
+	testCurrencyRateHistory(t, newTestSQLStore(t).GetExchangeManager())
+}
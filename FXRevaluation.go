@@ -0,0 +1,283 @@
+package acccore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// fxRevaluationPeriodFormat formats the period of a revaluation, periods are calendar months
+	fxRevaluationPeriodFormat = "2006-01"
+	// fxRevaluationReversalRetry is how long the scheduled reversal waits before retrying a failed reversal
+	fxRevaluationReversalRetry = time.Minute
+)
+
+// FXRevaluationAccounts are the accounts, in the reporting Currency, the unrealized FX revaluation journals are booked against.
+type FXRevaluationAccounts struct {
+	// Adjustment is the account carrying the unrealized revaluation of the foreign Currency accounts
+	Adjustment string
+	// Gain is the account receiving the unrealized FX gains, it is credited
+	Gain string
+	// Loss is the account receiving the unrealized FX losses, it is debited
+	Loss string
+}
+
+// FXRevaluationEntry is the revaluation of a single foreign Currency account.
+type FXRevaluationEntry struct {
+	// Account is the revalued account
+	Account Account
+	// Balance is the account Balance at the revaluation time, in the account Currency
+	Balance decimal.Decimal
+	// HistoricalValue is the value of the account Balance in the reporting Currency,
+	// each transaction exchanged at the rate in effect at its transaction time
+	HistoricalValue decimal.Decimal
+	// CurrentValue is the value of the account Balance in the reporting Currency, exchanged at the rate in effect at the revaluation time
+	CurrentValue decimal.Decimal
+	// Difference is the unrealized revaluation, CurrentValue minus HistoricalValue
+	Difference decimal.Decimal
+	// Journal is the revaluation journal booked for the account in the period, nil if there is no difference.
+	// If the account was already revalued within the period, it is the journal booked back then.
+	Journal Journal
+}
+
+// FXRevaluation is the unrealized FX revaluation of foreign Currency accounts into the reporting Currency, for a period.
+type FXRevaluation struct {
+	// ReportingCurrency is the Currency the accounts are valued in
+	ReportingCurrency string
+	// At is the revaluation time
+	At time.Time
+	// ReverseOn is the first day of the next period, when the revaluation journals are reversed by RunFXRevaluationReversals
+	ReverseOn time.Time
+	// Entries are the revaluation of each account
+	Entries []*FXRevaluationEntry
+}
+
+// GetFXRevaluationAccounts returns the accounts revaluations into the reporting currency are booked against, zero if not set
+func (acc *Accounting) GetFXRevaluationAccounts(reportingCurrency string) FXRevaluationAccounts {
+	return acc.fxRevaluationAccounts[reportingCurrency]
+}
+
+// SetFXRevaluationAccounts sets the accounts revaluations into the reporting currency are booked against.
+// The accounts must have the reporting Currency.
+func (acc *Accounting) SetFXRevaluationAccounts(reportingCurrency string, accounts FXRevaluationAccounts) *Accounting {
+	if acc.fxRevaluationAccounts == nil {
+		acc.fxRevaluationAccounts = make(map[string]FXRevaluationAccounts)
+	}
+	acc.fxRevaluationAccounts[reportingCurrency] = accounts
+	return acc
+}
+
+// RevalueFXAccounts values the Balance of the foreign Currency accounts at the specified time in the reporting Currency,
+// at the rates in effect when each of their transactions happened and at the rate in effect at that time,
+// and books the difference as unrealized FX gain or loss in a revaluation journal for each account.
+// If no account numbers are given, all the accounts not in the reporting Currency created not after that time are revalued.
+// Periods are calendar months, and an account is revalued once per period: revaluing it again within the period
+// returns the journal already booked for it. The revaluation journals of all the periods before that are not reversed yet
+// are reversed first, even when some periods were skipped, so the revaluations never pile up.
+// The accounts are booked one by one. Should booking an account fail, the revaluation of the accounts booked so far
+// is returned along with the error. Their journals stay booked, so revaluing again within the period only books
+// the remaining accounts.
+func (acc *Accounting) RevalueFXAccounts(context context.Context, reportingCurrency string, at time.Time, accountNumbers []string, creator string) (*FXRevaluation, error) {
+	if acc.GetExchangeManager() == nil {
+		return nil, ErrExchangeManagerNotSet
+	}
+	revaluationAccounts := acc.GetFXRevaluationAccounts(reportingCurrency)
+	if len(revaluationAccounts.Adjustment) == 0 || len(revaluationAccounts.Gain) == 0 || len(revaluationAccounts.Loss) == 0 {
+		logrus.Errorf("error revaluing accounts into %s. FX revaluation accounts are not set", reportingCurrency)
+		return nil, ErrFXRevaluationAccountsNotSet
+	}
+	accounts, err := acc.listForeignAccounts(context, reportingCurrency, at, accountNumbers)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := acc.reverseFXRevaluations(context, reportingCurrency, accounts, at, creator); err != nil {
+		return nil, err
+	}
+
+	revaluation := &FXRevaluation{
+		ReportingCurrency: reportingCurrency,
+		At:                at,
+		ReverseOn:         startOfPeriod(at).AddDate(0, 1, 0),
+		Entries:           make([]*FXRevaluationEntry, 0, len(accounts)),
+	}
+	for _, account := range accounts {
+		entry, err := acc.revalueFXAccount(context, reportingCurrency, account, at)
+		if err != nil {
+			return revaluation, err
+		}
+		key := fxRevaluationKey(reportingCurrency, account, at)
+		entry.Journal, err = acc.GetJournalManager().GetJournalByIdempotencyKey(context, key)
+		if errors.Is(err, ErrJournalIDNotFound) && !entry.Difference.IsZero() {
+			entry.Journal, err = acc.CreateNewJournalWithIdempotencyKey(context, key,
+				fmt.Sprintf("FX revaluation of %s into %s for %s", account.GetAccountNumber(), reportingCurrency, at.Format(fxRevaluationPeriodFormat)),
+				fxRevaluationTransactions(revaluationAccounts, account, entry.Difference), creator)
+		}
+		if err != nil && !errors.Is(err, ErrJournalIDNotFound) {
+			logrus.Errorf("error revaluing account %s into %s. got %s", account.GetAccountNumber(), reportingCurrency, err.Error())
+			return revaluation, err
+		}
+		revaluation.Entries = append(revaluation.Entries, entry)
+	}
+	return revaluation, nil
+}
+
+// ReverseFXRevaluations reverses the revaluation journals of the periods before the one of the specified time,
+// that are not reversed yet, and returns the reversal journals. RunFXRevaluationReversals calls it on the first day of each period.
+// If no account numbers are given, the revaluations of all the accounts not in the reporting Currency are reversed.
+func (acc *Accounting) ReverseFXRevaluations(context context.Context, reportingCurrency string, at time.Time, accountNumbers []string, creator string) ([]Journal, error) {
+	accounts, err := acc.listForeignAccounts(context, reportingCurrency, at, accountNumbers)
+	if err != nil {
+		return nil, err
+	}
+	return acc.reverseFXRevaluations(context, reportingCurrency, accounts, at, creator)
+}
+
+// RunFXRevaluationReversals reverses the revaluation journals of the previous period right away, and then on the first day
+// of each period in the location, until the context is done. As journals are committed at the time they are booked,
+// the reversals are dated the first day of the period; should the scheduler not be running then, the reversals are
+// booked as soon as it runs again. Failed reversals are logged, and retried after a minute.
+// It returns ErrFXRevaluationLocationNotSet if the location is nil.
+func (acc *Accounting) RunFXRevaluationReversals(context context.Context, reportingCurrency string, location *time.Location, creator string) error {
+	return acc.runFXRevaluationReversals(context, reportingCurrency, location, creator, time.Now)
+}
+
+// runFXRevaluationReversals runs the scheduled reversals, reading the current time from the clock.
+func (acc *Accounting) runFXRevaluationReversals(context context.Context, reportingCurrency string, location *time.Location, creator string, clock func() time.Time) error {
+	if location == nil {
+		return ErrFXRevaluationLocationNotSet
+	}
+	for {
+		now := clock().In(location)
+		wait := startOfPeriod(now).AddDate(0, 1, 0).Sub(now)
+		if _, err := acc.ReverseFXRevaluations(context, reportingCurrency, now, nil, creator); err != nil {
+			logrus.Errorf("error reversing FX revaluations into %s. got %s", reportingCurrency, err.Error())
+			wait = min(wait, fxRevaluationReversalRetry)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-context.Done():
+			timer.Stop()
+			return context.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reverseFXRevaluations reverses the revaluation journals of the accounts for all the periods before the one of the specified time,
+// that are not reversed yet. The periods are looked up from the one the account was created in.
+func (acc *Accounting) reverseFXRevaluations(context context.Context, reportingCurrency string, accounts []Account, at time.Time, creator string) ([]Journal, error) {
+	reversals := make([]Journal, 0)
+	for _, account := range accounts {
+		for period := startOfPeriod(account.GetCreateTime().In(at.Location())); period.Before(startOfPeriod(at)); period = period.AddDate(0, 1, 0) {
+			journal, err := acc.GetJournalManager().GetJournalByIdempotencyKey(context, fxRevaluationKey(reportingCurrency, account, period))
+			if errors.Is(err, ErrJournalIDNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			reversed, err := acc.GetJournalManager().IsJournalIDReversed(context, journal.GetJournalID())
+			if err != nil {
+				return nil, err
+			}
+			if reversed {
+				continue
+			}
+			reversal, err := acc.CreateReversal(context, "Reversal of "+journal.GetDescription(), journal, creator)
+			if err != nil {
+				return nil, err
+			}
+			reversals = append(reversals, reversal)
+		}
+	}
+	return reversals, nil
+}
+
+// revalueFXAccount values the account Balance at the specified time, at the historical rates and at the rate in effect at that time.
+func (acc *Accounting) revalueFXAccount(context context.Context, reportingCurrency string, account Account, at time.Time) (*FXRevaluationEntry, error) {
+	entry := &FXRevaluationEntry{Account: account, Balance: decimal.Zero, HistoricalValue: decimal.Zero}
+	for trx, err := range acc.GetTransactionManager().StreamTransactionsOnAccount(context, time.Time{}, at, account) {
+		if err != nil {
+			return nil, err
+		}
+		value, err := acc.GetExchangeManager().CalculateExchangeAt(context, account.GetCurrency(), reportingCurrency, trx.GetAmount(), trx.GetTransactionTime())
+		if err != nil {
+			return nil, err
+		}
+		if trx.GetAlignment() == account.GetAlignment() {
+			entry.Balance = entry.Balance.Add(trx.GetAmount())
+			entry.HistoricalValue = entry.HistoricalValue.Add(value)
+		} else {
+			entry.Balance = entry.Balance.Sub(trx.GetAmount())
+			entry.HistoricalValue = entry.HistoricalValue.Sub(value)
+		}
+	}
+	current, err := acc.GetExchangeManager().CalculateExchangeAt(context, account.GetCurrency(), reportingCurrency, entry.Balance, at)
+	if err != nil {
+		return nil, err
+	}
+	entry.CurrentValue = current
+	entry.Difference = current.Sub(entry.HistoricalValue)
+	return entry, nil
+}
+
+// listForeignAccounts loads the accounts of the account numbers, or lists all the accounts created not after the specified time
+// if there are none, leaving out the accounts in the reporting Currency.
+func (acc *Accounting) listForeignAccounts(context context.Context, reportingCurrency string, at time.Time, accountNumbers []string) ([]Account, error) {
+	var accounts []Account
+	if len(accountNumbers) == 0 {
+		all, err := acc.listAccountsAsOf(context, at)
+		if err != nil {
+			return nil, err
+		}
+		accounts = all
+	} else {
+		accounts = make([]Account, 0, len(accountNumbers))
+		for _, accountNumber := range accountNumbers {
+			account, err := acc.GetAccountManager().GetAccountByID(context, accountNumber)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, account)
+		}
+	}
+	foreign := make([]Account, 0, len(accounts))
+	for _, account := range accounts {
+		if account.GetCurrency() != reportingCurrency {
+			foreign = append(foreign, account)
+		}
+	}
+	return foreign, nil
+}
+
+// fxRevaluationTransactions books the revaluation difference of the account against the adjustment account.
+// An increase in the value of a DEBIT account or a decrease in the value of a CREDIT account is a gain, otherwise it is a loss.
+func fxRevaluationTransactions(revaluationAccounts FXRevaluationAccounts, account Account, difference decimal.Decimal) []TransactionInfo {
+	adjustment := account.GetAlignment()
+	if difference.IsNegative() {
+		adjustment = oppositeAlignment(adjustment)
+	}
+	counter, description := revaluationAccounts.Gain, "Unrealized FX gain"
+	if adjustment == CREDIT {
+		counter, description = revaluationAccounts.Loss, "Unrealized FX loss"
+	}
+	return []TransactionInfo{
+		{AccountNumber: revaluationAccounts.Adjustment, Description: "FX revaluation of " + account.GetAccountNumber(), TxType: adjustment, Amount: difference.Abs()},
+		{AccountNumber: counter, Description: description, TxType: oppositeAlignment(adjustment), Amount: difference.Abs()},
+	}
+}
+
+// fxRevaluationKey is the idempotency key of the revaluation journal of the account for the period of the specified time.
+func fxRevaluationKey(reportingCurrency string, account Account, at time.Time) string {
+	return fmt.Sprintf("fx-revaluation/%s/%s/%s", reportingCurrency, at.Format(fxRevaluationPeriodFormat), account.GetAccountNumber())
+}
+
+// startOfPeriod returns the first instant of the period of the specified time.
+func startOfPeriod(at time.Time) time.Time {
+	return time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+}
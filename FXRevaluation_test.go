@@ -0,0 +1,146 @@
+package acccore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFXRevaluation(t *testing.T, acc *Accounting, exchangeManager ExchangeManager) {
+	ctx := context.Background()
+	am := acc.GetAccountManager()
+
+	usd, err := exchangeManager.CreateCurrency(ctx, "USD", "US Dollar", decimal.NewFromInt(1), "superman")
+	assert.NoError(t, err)
+	assert.NoError(t, exchangeManager.UpdateCurrency(ctx, "USD", usd.SetScale(2).SetRoundingMode(RoundHalfEven), "superman"))
+	_, err = exchangeManager.CreateCurrency(ctx, "EUR", "Euro", decimal.NewFromFloat(0.5), "superman")
+	assert.NoError(t, err)
+
+	treasury, err := acc.CreateNewAccount(ctx, "", "EUR treasury", "EUR treasury", "1.1", "EUR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "", "EUR equity", "EUR equity", "3.1", "EUR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	adjustment, err := acc.CreateNewAccount(ctx, "", "FX adjustment", "FX revaluation adjustment", "1.9", "USD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	gain, err := acc.CreateNewAccount(ctx, "", "FX gain", "Unrealized FX gain", "4.9", "USD", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	loss, err := acc.CreateNewAccount(ctx, "", "FX loss", "Unrealized FX loss", "5.9", "USD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	funding, err := acc.CreateNewJournal(ctx, "Funding", []TransactionInfo{
+		{AccountNumber: treasury.GetAccountNumber(), Description: "Funding", TxType: DEBIT, Amount: decimal.NewFromInt(100)},
+		{AccountNumber: equity.GetAccountNumber(), Description: "Funding", TxType: CREDIT, Amount: decimal.NewFromInt(100)},
+	}, "aCreator")
+	assert.NoError(t, err)
+	fundedAt := funding.GetTransactions()[0].GetTransactionTime()
+	assert.NoError(t, exchangeManager.UpdateCurrencyRates(ctx, map[string]decimal.Decimal{"EUR": decimal.NewFromFloat(0.4)}, fundedAt.Add(time.Millisecond), "superman"))
+
+	assertBalance := func(account Account, expected int64) {
+		t.Helper()
+		loaded, err := am.GetAccountByID(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		assert.True(t, loaded.GetBalance().Equal(decimal.NewFromInt(expected)), "balance of %s is %s", loaded.GetName(), loaded.GetBalance())
+	}
+
+	accountNumbers := []string{treasury.GetAccountNumber()}
+	now := fundedAt.Add(time.Second).UTC()
+	_, err = acc.RevalueFXAccounts(ctx, "USD", now, accountNumbers, "aCreator")
+	assert.ErrorIs(t, err, ErrExchangeManagerNotSet)
+	acc.SetExchangeManager(exchangeManager)
+	_, err = acc.RevalueFXAccounts(ctx, "USD", now, accountNumbers, "aCreator")
+	assert.ErrorIs(t, err, ErrFXRevaluationAccountsNotSet)
+	acc.SetFXRevaluationAccounts("USD", FXRevaluationAccounts{
+		Adjustment: adjustment.GetAccountNumber(),
+		Gain:       gain.GetAccountNumber(),
+		Loss:       loss.GetAccountNumber(),
+	})
+
+	// 100 EUR bought at 2 USD is now worth 250 USD.
+	revaluation, err := acc.RevalueFXAccounts(ctx, "USD", now, accountNumbers, "aCreator")
+	assert.NoError(t, err)
+	assert.True(t, revaluation.ReverseOn.Equal(startOfPeriod(now).AddDate(0, 1, 0)))
+	assert.ErrorIs(t, acc.RunFXRevaluationReversals(ctx, "USD", nil, "aCreator"), ErrFXRevaluationLocationNotSet)
+	if assert.Len(t, revaluation.Entries, 1) {
+		entry := revaluation.Entries[0]
+		assert.True(t, entry.Balance.Equal(decimal.NewFromInt(100)), "balance %s", entry.Balance)
+		assert.True(t, entry.HistoricalValue.Equal(decimal.NewFromInt(200)), "historical value %s", entry.HistoricalValue)
+		assert.True(t, entry.CurrentValue.Equal(decimal.NewFromInt(250)), "current value %s", entry.CurrentValue)
+		assert.True(t, entry.Difference.Equal(decimal.NewFromInt(50)), "difference %s", entry.Difference)
+		assert.NotNil(t, entry.Journal)
+	}
+	assertBalance(adjustment, 50)
+	assertBalance(gain, 50)
+	assertBalance(loss, 0)
+	assertBalance(treasury, 100)
+
+	// revaluing again within the period, even at another time, books nothing more.
+	again, err := acc.RevalueFXAccounts(ctx, "USD", now.Add(-time.Millisecond), accountNumbers, "aCreator")
+	assert.NoError(t, err)
+	if assert.Len(t, again.Entries, 1) {
+		assert.Equal(t, revaluation.Entries[0].Journal.GetJournalID(), again.Entries[0].Journal.GetJournalID())
+	}
+	assertBalance(gain, 50)
+
+	// accounts in the reporting Currency are left out when revaluing all accounts.
+	all, err := acc.RevalueFXAccounts(ctx, "USD", now, nil, "aCreator")
+	assert.NoError(t, err)
+	assert.Len(t, all.Entries, 2)
+	assertBalance(adjustment, 0)
+	assertBalance(gain, 50)
+	assertBalance(loss, 50)
+
+	// on the first day of the next period, the scheduled reversal reverses the revaluations once.
+	runCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	clock := func() time.Time { return revaluation.ReverseOn }
+	assert.ErrorIs(t, acc.runFXRevaluationReversals(runCtx, "USD", time.UTC, "aCreator", clock), context.DeadlineExceeded)
+	assertBalance(adjustment, 0)
+	assertBalance(gain, 0)
+	assertBalance(loss, 0)
+	reversals, err := acc.ReverseFXRevaluations(ctx, "USD", revaluation.ReverseOn, nil, "aCreator")
+	assert.NoError(t, err)
+	assert.Len(t, reversals, 0)
+
+	// the EUR weakens to 1.6 USD from the next period, 100 EUR bought at 2 USD is then worth 160 USD.
+	assert.NoError(t, exchangeManager.UpdateCurrencyRates(ctx, map[string]decimal.Decimal{"EUR": decimal.NewFromFloat(0.625)}, revaluation.ReverseOn, "superman"))
+	next := revaluation.ReverseOn.AddDate(0, 0, 14)
+	revaluation, err = acc.RevalueFXAccounts(ctx, "USD", next, accountNumbers, "aCreator")
+	assert.NoError(t, err)
+	if assert.Len(t, revaluation.Entries, 1) {
+		assert.True(t, revaluation.Entries[0].Difference.Equal(decimal.NewFromInt(-40)), "difference %s", revaluation.Entries[0].Difference)
+	}
+	assertBalance(adjustment, -40)
+	assertBalance(loss, 40)
+	assertBalance(gain, 0)
+
+	// revaluing the period after reverses the previous period first, when it was not reversed yet.
+	revaluation, err = acc.RevalueFXAccounts(ctx, "USD", revaluation.ReverseOn.AddDate(0, 0, 14), accountNumbers, "aCreator")
+	assert.NoError(t, err)
+	if assert.Len(t, revaluation.Entries, 1) {
+		assert.True(t, revaluation.Entries[0].Difference.Equal(decimal.NewFromInt(-40)), "difference %s", revaluation.Entries[0].Difference)
+	}
+	assertBalance(adjustment, -40)
+	assertBalance(loss, 40)
+
+	// skipping a period still reverses the revaluation left open before revaluing again, so the loss is not counted twice.
+	revaluation, err = acc.RevalueFXAccounts(ctx, "USD", revaluation.ReverseOn.AddDate(0, 1, 14), accountNumbers, "aCreator")
+	assert.NoError(t, err)
+	if assert.Len(t, revaluation.Entries, 1) {
+		assert.True(t, revaluation.Entries[0].Difference.Equal(decimal.NewFromInt(-40)), "difference %s", revaluation.Entries[0].Difference)
+	}
+	assertBalance(adjustment, -40)
+	assertBalance(loss, 40)
+	assertBalance(gain, 0)
+}
+
+func TestAccounting_FXRevaluation(t *testing.T) {
+	store := NewInMemoryStore()
+	testFXRevaluation(t, newTestAccounting(store), store.GetExchangeManager())
+}
+
+func TestAccounting_FXRevaluationSQL(t *testing.T) {
+	store := newTestSQLStore(t)
+	testFXRevaluation(t, newTestSQLAccounting(store), store.GetExchangeManager())
+}
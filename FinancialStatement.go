@@ -0,0 +1,259 @@
+package acccore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// StatementLine is the balance of a single account in a financial statement.
+// The balance is expressed in the normal Alignment of the account COA category,
+// so accounts with the opposite Alignment such as contra accounts have negative balance.
+type StatementLine struct {
+	// Account is the account of this line
+	Account Account
+	// COA is the chart of accounts node the account belongs to
+	COA COA
+	// Balance is the balance of the account
+	Balance decimal.Decimal
+}
+
+// StatementSection groups the lines of a financial statement belonging to the same COA category.
+type StatementSection struct {
+	// Category is the COA category of all the lines in this section
+	Category COACategory
+	// Lines are the account balances, ordered by the account COA and number
+	Lines []*StatementLine
+	// Total is the sum of all the line balances
+	Total decimal.Decimal
+}
+
+// newStatementSection creates an empty section of the category
+func newStatementSection(category COACategory) *StatementSection {
+	return &StatementSection{
+		Category: category,
+		Lines:    make([]*StatementLine, 0),
+		Total:    decimal.Zero,
+	}
+}
+
+// add appends a line into this section, adding its balance into the section total.
+func (section *StatementSection) add(line *StatementLine) {
+	section.Lines = append(section.Lines, line)
+	section.Total = section.Total.Add(line.Balance)
+}
+
+// BalanceSheetReport is the balance sheet of the accounts of a Currency at a point in time.
+type BalanceSheetReport struct {
+	// AsOf is the time of the balances
+	AsOf time.Time
+	// Currency is the currency of all the accounts in this report
+	Currency string
+	// Assets are the balances of the ASSET accounts
+	Assets *StatementSection
+	// Liabilities are the balances of the LIABILITY accounts
+	Liabilities *StatementSection
+	// Equity are the balances of the EQUITY accounts
+	Equity *StatementSection
+	// NetIncome is the total INCOME minus the total EXPENSE up to the `AsOf` time,
+	// that is not yet closed into the EQUITY accounts.
+	NetIncome decimal.Decimal
+}
+
+// IsBalanced verifies the accounting equation, the assets must equal the liabilities plus equity plus net income.
+func (report *BalanceSheetReport) IsBalanced() bool {
+	return report.Assets.Total.Equal(report.Liabilities.Total.Add(report.Equity.Total).Add(report.NetIncome))
+}
+
+// IncomeStatementReport is the income statement of the accounts of a Currency within a time range.
+type IncomeStatementReport struct {
+	// From is the beginning of the time range, inclusive
+	From time.Time
+	// Until is the end of the time range, inclusive
+	Until time.Time
+	// Currency is the currency of all the accounts in this report
+	Currency string
+	// Income are the INCOME accounts balance changes within the time range
+	Income *StatementSection
+	// Expenses are the EXPENSE accounts balance changes within the time range
+	Expenses *StatementSection
+	// NetIncome is the total income minus the total expenses
+	NetIncome decimal.Decimal
+}
+
+// BalanceSheet creates the balance sheet of the accounts at the `asOf` time, one report for each currency ordered by the currency.
+// The chart of accounts manager must be set, as the accounts are grouped by their COA category.
+func (acc *Accounting) BalanceSheet(context context.Context, asOf time.Time) ([]*BalanceSheetReport, error) {
+	lines, err := acc.statementLines(context, asOf, func(account Account) (decimal.Decimal, error) {
+		return acc.accountBalanceAt(context, account, asOf)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*BalanceSheetReport, 0, len(lines))
+	for _, currency := range sortedCurrencies(lines) {
+		report := &BalanceSheetReport{
+			AsOf:        asOf,
+			Currency:    currency,
+			Assets:      newStatementSection(ASSET),
+			Liabilities: newStatementSection(LIABILITY),
+			Equity:      newStatementSection(EQUITY),
+			NetIncome:   decimal.Zero,
+		}
+		for _, line := range lines[currency] {
+			switch line.COA.GetCategory() {
+			case ASSET:
+				report.Assets.add(line)
+			case LIABILITY:
+				report.Liabilities.add(line)
+			case EQUITY:
+				report.Equity.add(line)
+			case INCOME:
+				report.NetIncome = report.NetIncome.Add(line.Balance)
+			case EXPENSE:
+				report.NetIncome = report.NetIncome.Sub(line.Balance)
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// IncomeStatement creates the income statement of the accounts between the `from` and `until` time range inclusive,
+// one report for each currency ordered by the currency.
+// The chart of accounts manager must be set, as the accounts are grouped by their COA category.
+func (acc *Accounting) IncomeStatement(context context.Context, from, until time.Time) ([]*IncomeStatementReport, error) {
+	lines, err := acc.statementLines(context, until, func(account Account) (decimal.Decimal, error) {
+		return acc.accountMovement(context, account, from, until)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*IncomeStatementReport, 0, len(lines))
+	for _, currency := range sortedCurrencies(lines) {
+		report := &IncomeStatementReport{
+			From:      from,
+			Until:     until,
+			Currency:  currency,
+			Income:    newStatementSection(INCOME),
+			Expenses:  newStatementSection(EXPENSE),
+			NetIncome: decimal.Zero,
+		}
+		for _, line := range lines[currency] {
+			switch line.COA.GetCategory() {
+			case INCOME:
+				report.Income.add(line)
+			case EXPENSE:
+				report.Expenses.add(line)
+			}
+		}
+		report.NetIncome = report.Income.Total.Sub(report.Expenses.Total)
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// RenderBalanceSheet will render the balance sheet into string for easy inspection
+func (acc *Accounting) RenderBalanceSheet(context context.Context, report *BalanceSheetReport) string {
+	var buff bytes.Buffer
+	table := tablewriter.NewWriter(&buff)
+	table.SetHeader([]string{"Account", "Name", "COA", "Balance"})
+	appendStatementSection(table, "ASSETS", report.Assets)
+	appendStatementSection(table, "LIABILITIES", report.Liabilities)
+	appendStatementSection(table, "EQUITY", report.Equity)
+	table.Append([]string{"", "Net Income", "", report.NetIncome.String()})
+	table.SetFooter([]string{"", "Liabilities + Equity", "", report.Liabilities.Total.Add(report.Equity.Total).Add(report.NetIncome).String()})
+
+	buff.WriteString(fmt.Sprintf("Balance Sheet : %s\n", report.Currency))
+	buff.WriteString(fmt.Sprintf("As Of         : %s\n", report.AsOf.String()))
+	buff.WriteString(fmt.Sprintf("Balanced      : %t\n", report.IsBalanced()))
+	table.Render()
+	return buff.String()
+}
+
+// RenderIncomeStatement will render the income statement into string for easy inspection
+func (acc *Accounting) RenderIncomeStatement(context context.Context, report *IncomeStatementReport) string {
+	var buff bytes.Buffer
+	table := tablewriter.NewWriter(&buff)
+	table.SetHeader([]string{"Account", "Name", "COA", "Balance"})
+	appendStatementSection(table, "INCOME", report.Income)
+	appendStatementSection(table, "EXPENSES", report.Expenses)
+	table.SetFooter([]string{"", "Net Income", "", report.NetIncome.String()})
+
+	buff.WriteString(fmt.Sprintf("Income Statement : %s\n", report.Currency))
+	buff.WriteString(fmt.Sprintf("From             : %s\n", report.From.String()))
+	buff.WriteString(fmt.Sprintf("Until            : %s\n", report.Until.String()))
+	table.Render()
+	return buff.String()
+}
+
+// appendStatementSection appends the section lines followed by the section total into the table.
+func appendStatementSection(table *tablewriter.Table, title string, section *StatementSection) {
+	table.Append([]string{title, "", "", ""})
+	for _, line := range section.Lines {
+		table.Append([]string{line.Account.GetAccountNumber(), line.Account.GetName(), line.COA.GetCode(), line.Balance.String()})
+	}
+	table.Append([]string{"", "Total " + title, "", section.Total.String()})
+}
+
+// statementLines creates the statement lines of all accounts created not after the `asOf` time, grouped by their currency.
+// The balance function returns the balance of the account in its own Alignment,
+// which is then expressed in the normal Alignment of the account COA category.
+func (acc *Accounting) statementLines(context context.Context, asOf time.Time, balanceOf func(account Account) (decimal.Decimal, error)) (map[string][]*StatementLine, error) {
+	if acc.GetCOAManager() == nil {
+		return nil, ErrCOAManagerNotSet
+	}
+	accounts, err := acc.listAccountsAsOf(context, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	coas := make(map[string]COA)
+	lines := make(map[string][]*StatementLine)
+	for _, account := range accounts {
+		coa, exist := coas[account.GetCOA()]
+		if !exist {
+			coa, err = acc.GetCOAManager().GetCOAByCode(context, account.GetCOA())
+			if errors.Is(err, ErrCOANotFound) {
+				logrus.Errorf("error creating statement. account %s COA %s is not in the chart of accounts", account.GetAccountNumber(), account.GetCOA())
+				return nil, ErrAccountCOANotFound
+			}
+			if err != nil {
+				return nil, err
+			}
+			coas[account.GetCOA()] = coa
+		}
+		balance, err := balanceOf(account)
+		if err != nil {
+			return nil, err
+		}
+		if account.GetAlignment() != coa.GetCategory().NormalAlignment() {
+			balance = balance.Neg()
+		}
+		lines[account.GetCurrency()] = append(lines[account.GetCurrency()], &StatementLine{
+			Account: account,
+			COA:     coa,
+			Balance: balance,
+		})
+	}
+	return lines, nil
+}
+
+// sortedCurrencies returns the currencies of the statement lines, in ascending order.
+func sortedCurrencies(lines map[string][]*StatementLine) []string {
+	currencies := make([]string, 0, len(lines))
+	for currency := range lines {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
@@ -0,0 +1,106 @@
+package acccore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFinancialStatements(t *testing.T, acc *Accounting, coaManager COAManager) {
+	ctx := context.Background()
+
+	_, err := acc.BalanceSheet(ctx, time.Now())
+	assert.ErrorIs(t, err, ErrCOAManagerNotSet)
+	acc.SetCOAManager(coaManager)
+
+	charts := []struct {
+		code, parent, name string
+		category           COACategory
+	}{
+		{"1", "", "Assets", ASSET},
+		{"1.1", "1", "Cash", ASSET},
+		{"2", "", "Liabilities", LIABILITY},
+		{"2.1", "2", "Points Liability", LIABILITY},
+		{"3", "", "Equity", EQUITY},
+		{"4", "", "Income", INCOME},
+		{"5", "", "Expenses", EXPENSE},
+	}
+	for _, chart := range charts {
+		_, err := acc.CreateNewCOA(ctx, chart.code, chart.parent, chart.name, chart.name, chart.category, chart.category.NormalAlignment(), "aCreator")
+		assert.NoError(t, err)
+	}
+
+	cash, err := acc.CreateNewAccount(ctx, "CASH", "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	points, err := acc.CreateNewAccount(ctx, "POINTS", "Points", "Outstanding points", "2.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	capital, err := acc.CreateNewAccount(ctx, "CAPITAL", "Capital", "Owner capital", "3", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	fee, err := acc.CreateNewAccount(ctx, "FEE", "Fee", "Fee income", "4", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	promo, err := acc.CreateNewAccount(ctx, "PROMO", "Promo", "Promotion expense", "5", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	_, err = acc.CreateNewAccount(ctx, "GOLD", "Gold", "Gold reserve", "1.1", "GOLD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+
+	post := func(description string, debit, credit Account, amount int64) {
+		_, err := acc.CreateNewJournal(ctx, description, []TransactionInfo{
+			{AccountNumber: debit.GetAccountNumber(), Description: description, TxType: DEBIT, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: credit.GetAccountNumber(), Description: description, TxType: CREDIT, Amount: decimal.NewFromInt(amount)},
+		}, "aCreator")
+		assert.NoError(t, err)
+	}
+	from := time.Now()
+	post("Capital", cash, capital, 1000)
+	post("Sell points", cash, points, 500)
+	time.Sleep(2 * time.Millisecond)
+	middle := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	post("Fee", cash, fee, 200)
+	post("Promo points", promo, points, 50)
+
+	sheets, err := acc.BalanceSheet(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, sheets, 2)
+	assert.Equal(t, "GOLD", sheets[0].Currency)
+	assert.True(t, sheets[0].IsBalanced())
+	sheet := sheets[1]
+	t.Log(acc.RenderBalanceSheet(ctx, sheet))
+	assert.Equal(t, "IDR", sheet.Currency)
+	assert.True(t, sheet.Assets.Total.Equal(decimal.NewFromInt(1700)))
+	assert.True(t, sheet.Liabilities.Total.Equal(decimal.NewFromInt(550)))
+	assert.True(t, sheet.Equity.Total.Equal(decimal.NewFromInt(1000)))
+	assert.True(t, sheet.NetIncome.Equal(decimal.NewFromInt(150)))
+	assert.True(t, sheet.IsBalanced())
+
+	sheets, err = acc.BalanceSheet(ctx, middle)
+	assert.NoError(t, err)
+	assert.True(t, sheets[1].Assets.Total.Equal(decimal.NewFromInt(1500)))
+	assert.True(t, sheets[1].NetIncome.IsZero())
+	assert.True(t, sheets[1].IsBalanced())
+
+	statements, err := acc.IncomeStatement(ctx, from, time.Now())
+	assert.NoError(t, err)
+	statement := statements[1]
+	t.Log(acc.RenderIncomeStatement(ctx, statement))
+	assert.True(t, statement.Income.Total.Equal(decimal.NewFromInt(200)))
+	assert.True(t, statement.Expenses.Total.Equal(decimal.NewFromInt(50)))
+	assert.True(t, statement.NetIncome.Equal(decimal.NewFromInt(150)))
+
+	statements, err = acc.IncomeStatement(ctx, from, middle)
+	assert.NoError(t, err)
+	assert.True(t, statements[1].NetIncome.IsZero())
+}
+
+func TestAccounting_FinancialStatements(t *testing.T) {
+	store := NewInMemoryStore()
+	testFinancialStatements(t, newTestAccounting(store), store.GetCOAManager())
+}
+
+func TestAccounting_FinancialStatementsSQL(t *testing.T) {
+	store := newTestSQLStore(t)
+	testFinancialStatements(t, newTestSQLAccounting(store), store.GetCOAManager())
+}
@@ -0,0 +1,179 @@
+package acccore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// String returns the name of the hold state
+func (state HoldState) String() string {
+	switch state {
+	case HoldActive:
+		return "active"
+	case HoldCaptured:
+		return "captured"
+	case HoldReleased:
+		return "released"
+	case HoldExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("state %d", int(state))
+}
+
+// PlaceHold reserves the amount from the account available Balance until the expiry time.
+// The account Balance is left untouched until the hold is captured.
+func (acc *Accounting) PlaceHold(context context.Context, accountNumber string, amount decimal.Decimal, expireTime time.Time, description, creator string) (Hold, error) {
+	if acc.GetHoldManager() == nil {
+		return nil, ErrHoldManagerNotSet
+	}
+	hold := acc.GetHoldManager().NewHold(context).SetHoldID(acc.GetUniqueIDGenerator().NewUniqueID()).
+		SetAccountNumber(accountNumber).SetDescription(description).SetAmount(amount).SetCapturedAmount(decimal.Zero).
+		SetState(HoldActive).SetExpireTime(expireTime).SetCreateBy(creator).SetCreateTime(time.Now())
+	err := acc.GetHoldManager().PlaceHold(context, hold)
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// GetAvailableBalance returns the account Balance minus the remaining amount of its active holds that have not expired.
+// Without a hold manager, the available Balance is the account Balance.
+func (acc *Accounting) GetAvailableBalance(context context.Context, accountNumber string) (decimal.Decimal, error) {
+	account, err := acc.GetAccountManager().GetAccountByID(context, accountNumber)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if acc.GetHoldManager() == nil {
+		return account.GetBalance(), nil
+	}
+	held, err := acc.GetHoldManager().GetHeldAmount(context, accountNumber, time.Now())
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return account.GetBalance().Sub(held), nil
+}
+
+// CaptureHold turns the amount of the hold into a journal moving it from the held account into the counter account.
+// The amount may be less than the remaining amount of the hold, the hold then stays active with what remains,
+// until it is captured again, released or expires.
+// The journal is committed and the hold captured at once, so should the hold be captured or released concurrently,
+// the journal is cancelled and the ledger is left untouched. The journal goes through the journal manager like any other,
+// so the hooks of a HookedJournalManager are called on it, PostCommit included.
+func (acc *Accounting) CaptureHold(context context.Context, holdID, counterAccountNumber string, amount decimal.Decimal, description, creator string) (Journal, error) {
+	if acc.GetHoldManager() == nil {
+		return nil, ErrHoldManagerNotSet
+	}
+	hold, err := acc.GetHoldManager().GetHoldByID(context, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHoldCapture(hold, amount, time.Now()); err != nil {
+		logrus.Errorf("error capturing hold %s. got %s", holdID, err.Error())
+		return nil, err
+	}
+	account, err := acc.GetAccountManager().GetAccountByID(context, hold.GetAccountNumber())
+	if err != nil {
+		return nil, err
+	}
+
+	// the held account Balance goes down, while the counter account receives the amount.
+	heldAlignment := decreasingAlignment(account)
+	journal := acc.newJournal(context, "", description, []TransactionInfo{
+		{AccountNumber: hold.GetAccountNumber(), Description: description, TxType: heldAlignment, Amount: amount},
+		{AccountNumber: counterAccountNumber, Description: description, TxType: oppositeAlignment(heldAlignment), Amount: amount},
+	}, creator)
+	capture := &holdCapture{holdID: holdID, amount: amount, capturedBy: creator}
+	if err := acc.postJournalWith(context, journal, capture.commitWith(acc.GetHoldManager())); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// ReleaseHold releases the remaining amount of the hold back into the account available Balance.
+func (acc *Accounting) ReleaseHold(context context.Context, holdID, editor string) error {
+	if acc.GetHoldManager() == nil {
+		return ErrHoldManagerNotSet
+	}
+	return acc.GetHoldManager().ReleaseHold(context, holdID, editor)
+}
+
+// ReleaseExpiredHolds marks all the holds that have expired by now as HoldExpired, and returns how many there were.
+// Expired holds already stop reserving their amount as soon as they expire, this records their state
+// and is meant to be called periodically.
+func (acc *Accounting) ReleaseExpiredHolds(context context.Context) (int, error) {
+	if acc.GetHoldManager() == nil {
+		return 0, ErrHoldManagerNotSet
+	}
+	return acc.GetHoldManager().ExpireHolds(context, time.Now())
+}
+
+// holdCapture is the amount captured from a hold by the journal committed along with it.
+type holdCapture struct {
+	holdID     string
+	amount     decimal.Decimal
+	capturedBy string
+}
+
+// commitWith returns the function committing a journal along with this capture, using the hold manager.
+func (capture *holdCapture) commitWith(holdManager HoldManager) func(context.Context, Journal) error {
+	return func(ctx context.Context, journal Journal) error {
+		return holdManager.CaptureHoldWithJournal(ctx, capture.holdID, capture.amount, capture.capturedBy, journal)
+	}
+}
+
+// holdRemaining returns the amount of the hold not captured yet.
+func holdRemaining(hold Hold) decimal.Decimal {
+	return hold.GetAmount().Sub(hold.GetCapturedAmount())
+}
+
+// isHoldReserving returns true if the hold still reserves its remaining amount at the specified time.
+func isHoldReserving(state HoldState, expireTime, at time.Time) bool {
+	return state == HoldActive && expireTime.After(at)
+}
+
+// validateHold checks the hold content before it is placed.
+func validateHold(hold Hold) error {
+	if len(hold.GetHoldID()) == 0 {
+		return ErrHoldMissingID
+	}
+	if len(hold.GetAccountNumber()) == 0 {
+		return ErrHoldMissingAccount
+	}
+	if len(hold.GetCreateBy()) == 0 {
+		return ErrHoldMissingAuthor
+	}
+	if !hold.GetAmount().IsPositive() {
+		return ErrHoldInvalidAmount
+	}
+	return nil
+}
+
+// checkHoldPlacement checks the account accepts the hold, given the account Balance and the amount already held.
+func checkHoldPlacement(account Account, held, amount decimal.Decimal) error {
+	if err := checkAccountState(account.GetState(), decreasingAlignment(account)); err != nil {
+		return err
+	}
+	available := account.GetBalance().Sub(held)
+	return checkBalanceLimit(account.GetAccountNumber(), account.GetBalanceLimit(), account.GetOverdraftLimit(), available, available.Sub(amount))
+}
+
+// checkHoldCapture checks the amount can be captured from the hold at the specified time.
+func checkHoldCapture(hold Hold, amount decimal.Decimal, at time.Time) error {
+	if !amount.IsPositive() {
+		return ErrHoldInvalidAmount
+	}
+	if hold.GetState() != HoldActive {
+		return ErrHoldNotActive
+	}
+	if !hold.GetExpireTime().After(at) {
+		return ErrHoldExpired
+	}
+	if amount.GreaterThan(holdRemaining(hold)) {
+		return ErrHoldAmountExceeded
+	}
+	return nil
+}
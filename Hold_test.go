@@ -0,0 +1,160 @@
+package acccore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testHolds(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+	am := acc.GetAccountManager()
+	hm := acc.GetHoldManager()
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	merchant, err := acc.CreateNewAccount(ctx, "", "Merchant", "Merchant points", "2.2", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	wallet := am.NewAccount(ctx).SetAccountNumber(acc.GetUniqueIDGenerator().NewUniqueID()).SetName("Wallet").
+		SetDescription("Point wallet").SetCOA("2.1").SetCurrency("POINT").SetAlignment(CREDIT).
+		SetBalanceLimit(NonNegativeBalance).SetCreateBy("aCreator")
+	assert.NoError(t, am.PersistAccount(ctx, wallet))
+	_, err = acc.CreateNewJournal(ctx, "Topup", []TransactionInfo{
+		{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.NewFromInt(100)},
+		{AccountNumber: wallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.NewFromInt(100)},
+	}, "aCreator")
+	assert.NoError(t, err)
+
+	balances := func(account Account) (decimal.Decimal, decimal.Decimal) {
+		loaded, err := am.GetAccountByID(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		available, err := acc.GetAvailableBalance(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		return loaded.GetBalance(), available
+	}
+	assertBalances := func(ledger, available int64) {
+		t.Helper()
+		ledgerBalance, availableBalance := balances(wallet)
+		assert.True(t, ledgerBalance.Equal(decimal.NewFromInt(ledger)), "ledger balance %s", ledgerBalance)
+		assert.True(t, availableBalance.Equal(decimal.NewFromInt(available)), "available balance %s", availableBalance)
+	}
+	expire := time.Now().Add(time.Hour)
+
+	// holds reserve the available balance only.
+	checkout, err := acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.NewFromInt(60), expire, "Checkout #1", "checkout")
+	assert.NoError(t, err)
+	assert.Equal(t, HoldActive, checkout.GetState())
+	assertBalances(100, 40)
+	_, err = acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.NewFromInt(50), expire, "Checkout #2", "checkout")
+	var insufficient *InsufficientBalanceError
+	if assert.ErrorAs(t, err, &insufficient) {
+		assert.True(t, insufficient.Shortfall.Equal(decimal.NewFromInt(10)))
+	}
+	// ordinary journals can not spend the held amount.
+	_, err = acc.CreateNewJournal(ctx, "Spend", []TransactionInfo{
+		{AccountNumber: wallet.GetAccountNumber(), Description: "Spend", TxType: DEBIT, Amount: decimal.NewFromInt(50)},
+		{AccountNumber: merchant.GetAccountNumber(), Description: "Spend", TxType: CREDIT, Amount: decimal.NewFromInt(50)},
+	}, "aCreator")
+	if assert.ErrorAs(t, err, &insufficient) {
+		assert.True(t, insufficient.Shortfall.Equal(decimal.NewFromInt(10)), "shortfall %s", insufficient.Shortfall)
+	}
+	assertBalances(100, 40)
+	_, err = acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.Zero, expire, "Checkout #2", "checkout")
+	assert.ErrorIs(t, err, ErrHoldInvalidAmount)
+	_, err = acc.PlaceHold(ctx, "NOT-THERE", decimal.NewFromInt(1), expire, "Checkout #2", "checkout")
+	assert.ErrorIs(t, err, ErrAccountIDNotFound)
+
+	// partial capture, then release what remains.
+	journal, err := acc.CaptureHold(ctx, checkout.GetHoldID(), merchant.GetAccountNumber(), decimal.NewFromInt(20), "Order #1", "checkout")
+	assert.NoError(t, err)
+	assert.Len(t, journal.GetTransactions(), 2)
+	assertBalances(80, 40)
+	merchantBalance, _ := balances(merchant)
+	assert.True(t, merchantBalance.Equal(decimal.NewFromInt(20)))
+	loaded, err := hm.GetHoldByID(ctx, checkout.GetHoldID())
+	assert.NoError(t, err)
+	assert.Equal(t, HoldActive, loaded.GetState())
+	assert.True(t, loaded.GetCapturedAmount().Equal(decimal.NewFromInt(20)))
+	_, err = acc.CaptureHold(ctx, checkout.GetHoldID(), merchant.GetAccountNumber(), decimal.NewFromInt(50), "Order #1", "checkout")
+	assert.ErrorIs(t, err, ErrHoldAmountExceeded)
+
+	assert.NoError(t, acc.ReleaseHold(ctx, checkout.GetHoldID(), "checkout"))
+	assertBalances(80, 80)
+	assert.ErrorIs(t, acc.ReleaseHold(ctx, checkout.GetHoldID(), "checkout"), ErrHoldNotActive)
+	_, err = acc.CaptureHold(ctx, checkout.GetHoldID(), merchant.GetAccountNumber(), decimal.NewFromInt(10), "Order #1", "checkout")
+	assert.ErrorIs(t, err, ErrHoldNotActive)
+	loaded, err = hm.GetHoldByID(ctx, checkout.GetHoldID())
+	assert.NoError(t, err)
+	assert.Equal(t, HoldReleased, loaded.GetState())
+
+	// the journal of a capture is only committed along with the hold being captured.
+	racing, err := acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.NewFromInt(80), expire, "Checkout #3", "checkout")
+	assert.NoError(t, err)
+	journal = acc.newJournal(ctx, "", "Order #3", []TransactionInfo{
+		{AccountNumber: wallet.GetAccountNumber(), Description: "Order #3", TxType: DEBIT, Amount: decimal.NewFromInt(80)},
+		{AccountNumber: merchant.GetAccountNumber(), Description: "Order #3", TxType: CREDIT, Amount: decimal.NewFromInt(80)},
+	}, "checkout")
+	assert.NoError(t, acc.GetJournalManager().PersistJournal(ctx, journal))
+	assert.NoError(t, acc.ReleaseHold(ctx, racing.GetHoldID(), "checkout"))
+	assert.ErrorIs(t, hm.CaptureHoldWithJournal(ctx, racing.GetHoldID(), decimal.NewFromInt(80), "checkout", journal), ErrHoldNotActive)
+	_, err = acc.GetJournalManager().GetJournalByID(ctx, journal.GetJournalID())
+	assert.ErrorIs(t, err, ErrJournalIDNotFound)
+	assert.NoError(t, acc.GetJournalManager().CancelJournal(ctx, journal))
+	assertBalances(80, 80)
+
+	// full capture.
+	full, err := acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.NewFromInt(30), expire, "Checkout #3", "checkout")
+	assert.NoError(t, err)
+	holds, err := hm.ListActiveHolds(ctx, wallet.GetAccountNumber(), time.Now())
+	assert.NoError(t, err)
+	if assert.Len(t, holds, 1) {
+		assert.Equal(t, full.GetHoldID(), holds[0].GetHoldID())
+	}
+	_, err = acc.CaptureHold(ctx, full.GetHoldID(), merchant.GetAccountNumber(), decimal.NewFromInt(30), "Order #3", "checkout")
+	assert.NoError(t, err)
+	assertBalances(50, 50)
+	loaded, err = hm.GetHoldByID(ctx, full.GetHoldID())
+	assert.NoError(t, err)
+	assert.Equal(t, HoldCaptured, loaded.GetState())
+
+	// expired holds stop reserving right away, and are recorded as expired later on.
+	expiring, err := acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.NewFromInt(50), time.Now().Add(50*time.Millisecond), "Checkout #4", "checkout")
+	assert.NoError(t, err)
+	assertBalances(50, 0)
+	time.Sleep(60 * time.Millisecond)
+	assertBalances(50, 50)
+	_, err = acc.CaptureHold(ctx, expiring.GetHoldID(), merchant.GetAccountNumber(), decimal.NewFromInt(50), "Order #4", "checkout")
+	assert.ErrorIs(t, err, ErrHoldExpired)
+	expired, err := acc.ReleaseExpiredHolds(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	expired, err = acc.ReleaseExpiredHolds(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+	loaded, err = hm.GetHoldByID(ctx, expiring.GetHoldID())
+	assert.NoError(t, err)
+	assert.Equal(t, HoldExpired, loaded.GetState())
+
+	// frozen accounts can not have their balance held.
+	assert.NoError(t, acc.FreezeAccount(ctx, wallet.GetAccountNumber(), AccountDebitFrozen, "compromised", "support"))
+	_, err = acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.NewFromInt(10), expire, "Checkout #5", "checkout")
+	assert.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = hm.GetHoldByID(ctx, "NOT-THERE")
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+	_, err = acc.SetHoldManager(nil).PlaceHold(ctx, wallet.GetAccountNumber(), decimal.NewFromInt(10), expire, "Checkout #5", "checkout")
+	assert.ErrorIs(t, err, ErrHoldManagerNotSet)
+}
+
+func TestAccounting_Holds(t *testing.T) {
+	store := NewInMemoryStore()
+	testHolds(t, newTestAccounting(store).SetHoldManager(store.GetHoldManager()))
+}
+
+func TestAccounting_HoldsSQL(t *testing.T) {
+	store := newTestSQLStore(t)
+	testHolds(t, newTestSQLAccounting(store).SetHoldManager(store.GetHoldManager()))
+}
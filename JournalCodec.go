@@ -0,0 +1,380 @@
+package acccore
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// jsonTypeKey is the JSON key holding the registered type name of a journal or transaction
+	jsonTypeKey = "type"
+	// jsonTransactionsKey is the JSON key holding the transactions of a journal
+	jsonTransactionsKey = "transactions"
+	// jsonReversedJournalKey is the JSON key holding the reversed journal of a journal
+	jsonReversedJournalKey = "reversed_journal"
+
+	// BaseJournalType is the registered type name of BaseJournal
+	BaseJournalType = "BaseJournal"
+	// BaseTransactionType is the registered type name of BaseTransaction
+	BaseTransactionType = "BaseTransaction"
+)
+
+// DefaultJournalCodec is the codec used by BaseJournal to marshal and unmarshal its transactions and reversed journal.
+// Custom Journal and Transaction implementations registered into this codec can be nested in a BaseJournal.
+var DefaultJournalCodec = NewJournalCodec()
+
+// NewJournalCodec creates a new codec with BaseJournal and BaseTransaction registered, which are also the types
+// used to read JSON without a type. The reversed journals are embedded by default, and the journals are written in JSONFormatV2.
+func NewJournalCodec() *JournalCodec {
+	codec := &JournalCodec{
+		journalFactories:     make(map[string]func() Journal),
+		transactionFactories: make(map[string]func() Transaction),
+		typeNames:            make(map[reflect.Type]string),
+		format:               JSONFormatV2,
+	}
+	codec.RegisterJournalType(BaseJournalType, func() Journal { return &BaseJournal{} })
+	codec.RegisterTransactionType(BaseTransactionType, func() Transaction { return &BaseTransaction{} })
+	return codec
+}
+
+// JournalCodec marshals journals into JSON and reads them back, including their transactions and reversed journal
+// which are interfaces that encoding/json can not decode on its own.
+// Each journal and transaction is written with its registered type name under the "type" key, so the codec knows
+// which implementation to create when reading. The transactions are expected under the "transactions" key, and the
+// reversed journal under the "reversed_journal" key, like BaseJournal does.
+type JournalCodec struct {
+	journalFactories     map[string]func() Journal
+	transactionFactories map[string]func() Transaction
+	typeNames            map[reflect.Type]string
+
+	reversedByReference bool
+	journalManager      JournalManager
+	format              JSONFormat
+}
+
+// RegisterJournalType registers a Journal implementation under the type name. The factory creates a blank journal of that implementation.
+func (codec *JournalCodec) RegisterJournalType(name string, factory func() Journal) *JournalCodec {
+	codec.journalFactories[name] = factory
+	codec.typeNames[reflect.TypeOf(factory())] = name
+	return codec
+}
+
+// RegisterTransactionType registers a Transaction implementation under the type name. The factory creates a blank transaction of that implementation.
+func (codec *JournalCodec) RegisterTransactionType(name string, factory func() Transaction) *JournalCodec {
+	codec.transactionFactories[name] = factory
+	codec.typeNames[reflect.TypeOf(factory())] = name
+	return codec
+}
+
+// SetReversedJournalByReference sets whether the reversed journal is written as its journal ID instead of the embedded journal.
+func (codec *JournalCodec) SetReversedJournalByReference(byReference bool) *JournalCodec {
+	codec.reversedByReference = byReference
+	return codec
+}
+
+// SetJSONFormat sets the format the journals and their transactions are written in. Any known format is read regardless of it.
+func (codec *JournalCodec) SetJSONFormat(format JSONFormat) *JournalCodec {
+	codec.format = format
+	return codec
+}
+
+// withJSONFormat returns a copy of this codec writing the journals in the format, sharing the registered types.
+func (codec *JournalCodec) withJSONFormat(format JSONFormat) *JournalCodec {
+	copied := *codec
+	copied.format = format
+	return &copied
+}
+
+// SetJournalManager sets the journal manager used to load the reversed journals written as their journal ID.
+// Without a journal manager, such a reversed journal is read as a blank BaseJournal having only the journal ID.
+func (codec *JournalCodec) SetJournalManager(journalManager JournalManager) *JournalCodec {
+	codec.journalManager = journalManager
+	return codec
+}
+
+// Marshal writes the journal into JSON.
+// It returns ErrJSONFormatUnsupported if the JSON format of the codec is not known.
+func (codec *JournalCodec) Marshal(journal Journal) ([]byte, error) {
+	if !codec.format.isKnown() {
+		return nil, ErrJSONFormatUnsupported
+	}
+	fields, err := codec.encodeJournal(journal)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// Unmarshal reads the journal out of the JSON. The reversed journal written as its journal ID is loaded
+// using the journal manager, if set.
+func (codec *JournalCodec) Unmarshal(context context.Context, data []byte) (Journal, error) {
+	journal, err := codec.decodeJournal(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := codec.resolveReversedJournal(context, journal); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// typeName returns the registered type name of the journal or transaction.
+func (codec *JournalCodec) typeName(value any) (string, error) {
+	name, ok := codec.typeNames[reflect.TypeOf(value)]
+	if !ok {
+		logrus.Errorf("error marshaling %T. type is not registered in the codec", value)
+		return "", ErrJSONUnknownType
+	}
+	return name, nil
+}
+
+// encodeJournal returns the JSON fields of the journal, with its type, transactions and reversed journal
+// written by this codec.
+func (codec *JournalCodec) encodeJournal(journal Journal) (map[string]json.RawMessage, error) {
+	name, err := codec.typeName(journal)
+	if err != nil {
+		return nil, err
+	}
+	// the transactions and reversed journal of a BaseJournal are left out, as they are written by this codec.
+	var fields map[string]json.RawMessage
+	if base, ok := journal.(*BaseJournal); ok {
+		header := *base
+		header.Transactions, header.ReversedJournal = nil, nil
+		var data []byte
+		data, err = header.marshalJSONWith(codec)
+		if err == nil {
+			fields, err = jsonObjectFields(data)
+		}
+	} else {
+		fields, err = jsonFields(journal)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fields[jsonTypeKey], _ = json.Marshal(name)
+
+	transactions, err := codec.encodeTransactions(journal.GetTransactions())
+	if err != nil {
+		return nil, err
+	}
+	fields[jsonTransactionsKey], err = json.Marshal(transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	fields[jsonReversedJournalKey], err = codec.encodeReversedJournal(journal.GetReversedJournal())
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// encodeTransactions returns the JSON of each transaction, with their type.
+func (codec *JournalCodec) encodeTransactions(transactions []Transaction) ([]json.RawMessage, error) {
+	if transactions == nil {
+		return nil, nil
+	}
+	encoded := make([]json.RawMessage, len(transactions))
+	for i, trx := range transactions {
+		name, err := codec.typeName(trx)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := codec.transactionFields(trx)
+		if err != nil {
+			return nil, err
+		}
+		fields[jsonTypeKey], _ = json.Marshal(name)
+		encoded[i], err = json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
+
+// transactionFields returns the JSON fields of the transaction. A BaseTransaction is written in the format of this codec,
+// other implementations are written by their own MarshalJSON.
+func (codec *JournalCodec) transactionFields(trx Transaction) (map[string]json.RawMessage, error) {
+	base, ok := trx.(*BaseTransaction)
+	if !ok {
+		return jsonFields(trx)
+	}
+	data, err := base.marshalJSONFormat(codec.format)
+	if err != nil {
+		return nil, err
+	}
+	return jsonObjectFields(data)
+}
+
+// encodeReversedJournal returns the JSON of the reversed journal, either embedded or as its journal ID.
+func (codec *JournalCodec) encodeReversedJournal(reversed Journal) (json.RawMessage, error) {
+	if isNilJournal(reversed) {
+		return json.RawMessage("null"), nil
+	}
+	if codec.reversedByReference {
+		return json.Marshal(reversed.GetJournalID())
+	}
+	return codec.Marshal(reversed)
+}
+
+// decodeJournal reads a journal of the type named in the JSON, including its transactions and reversed journal.
+// A reversed journal written as its journal ID is read as a blank journal having only the journal ID.
+func (codec *JournalCodec) decodeJournal(data []byte) (Journal, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	name, err := jsonTypeName(fields, BaseJournalType)
+	if err != nil {
+		return nil, err
+	}
+	factory, ok := codec.journalFactories[name]
+	if !ok {
+		logrus.Errorf("error unmarshaling journal. type %s is not registered in the codec", name)
+		return nil, ErrJSONUnknownType
+	}
+	var transactions []json.RawMessage
+	if data, ok := fields[jsonTransactionsKey]; ok {
+		if err := json.Unmarshal(data, &transactions); err != nil {
+			return nil, err
+		}
+	}
+	reversed := fields[jsonReversedJournalKey]
+	delete(fields, jsonTransactionsKey)
+	delete(fields, jsonReversedJournalKey)
+	delete(fields, jsonTypeKey)
+
+	journal := factory()
+	remaining, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(remaining, journal); err != nil {
+		return nil, err
+	}
+	if err := codec.decodeJournalFields(journal, transactions, reversed); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// decodeJournalFields reads the JSON of the transactions and of the reversed journal into the journal.
+// Nil transactions are left untouched.
+func (codec *JournalCodec) decodeJournalFields(journal Journal, encoded []json.RawMessage, reversedData json.RawMessage) error {
+	if encoded != nil {
+		transactions := make([]Transaction, len(encoded))
+		for i, data := range encoded {
+			trx, err := codec.decodeTransaction(data)
+			if err != nil {
+				return err
+			}
+			transactions[i] = trx
+		}
+		journal.SetTransactions(transactions)
+	}
+
+	if len(reversedData) > 0 && string(reversedData) != "null" {
+		var reversedID string
+		if err := json.Unmarshal(reversedData, &reversedID); err == nil {
+			journal.SetReversedJournal(codec.journalFactories[BaseJournalType]().SetJournalID(reversedID))
+			return nil
+		}
+		reversed, err := codec.decodeJournal(reversedData)
+		if err != nil {
+			return err
+		}
+		journal.SetReversedJournal(reversed)
+	}
+	return nil
+}
+
+// decodeTransaction reads a transaction of the type named in the JSON.
+func (codec *JournalCodec) decodeTransaction(data []byte) (Transaction, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	name, err := jsonTypeName(fields, BaseTransactionType)
+	if err != nil {
+		return nil, err
+	}
+	factory, ok := codec.transactionFactories[name]
+	if !ok {
+		logrus.Errorf("error unmarshaling transaction. type %s is not registered in the codec", name)
+		return nil, ErrJSONUnknownType
+	}
+	trx := factory()
+	if err := json.Unmarshal(data, trx); err != nil {
+		return nil, err
+	}
+	return trx, nil
+}
+
+// resolveReversedJournal loads the reversed journals written as their journal ID using the journal manager, if set.
+// The reversed journal of the reversed journal is resolved as well.
+func (codec *JournalCodec) resolveReversedJournal(context context.Context, journal Journal) error {
+	reversed := journal.GetReversedJournal()
+	if isNilJournal(reversed) {
+		return nil
+	}
+	if codec.journalManager != nil && isJournalReference(reversed) {
+		loaded, err := codec.journalManager.GetJournalByID(context, reversed.GetJournalID())
+		if err != nil {
+			return err
+		}
+		journal.SetReversedJournal(loaded)
+		return nil
+	}
+	return codec.resolveReversedJournal(context, reversed)
+}
+
+// isNilJournal returns true if the journal is nil, or a nil pointer of a Journal implementation.
+func isNilJournal(journal Journal) bool {
+	if journal == nil {
+		return true
+	}
+	value := reflect.ValueOf(journal)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
+
+// isJournalReference returns true if the journal is a blank journal having only the journal ID,
+// as read from a reversed journal written as its journal ID.
+func isJournalReference(journal Journal) bool {
+	base, ok := journal.(*BaseJournal)
+	return ok && reflect.DeepEqual(*base, BaseJournal{JournalID: base.JournalID})
+}
+
+// jsonFields returns the JSON object fields of the value.
+func jsonFields(value any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return jsonObjectFields(data)
+}
+
+// jsonObjectFields returns the fields of the JSON object.
+func jsonObjectFields(data []byte) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// jsonTypeName returns the type name in the JSON fields, or the default name if the JSON have no type.
+func jsonTypeName(fields map[string]json.RawMessage, defaultName string) (string, error) {
+	data, ok := fields[jsonTypeKey]
+	if !ok {
+		return defaultName, nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,141 @@
+package acccore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// campaignTransaction is a custom Transaction carrying the campaign that rewarded it.
+type campaignTransaction struct {
+	BaseTransaction
+	Campaign string
+}
+
+func (trx *campaignTransaction) MarshalJSON() ([]byte, error) {
+	fields, err := jsonFields(&trx.BaseTransaction)
+	if err != nil {
+		return nil, err
+	}
+	fields["campaign"], _ = json.Marshal(trx.Campaign)
+	return json.Marshal(fields)
+}
+
+func (trx *campaignTransaction) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &trx.BaseTransaction); err != nil {
+		return err
+	}
+	campaign := struct {
+		Campaign string `json:"campaign"`
+	}{}
+	if err := json.Unmarshal(data, &campaign); err != nil {
+		return err
+	}
+	trx.Campaign = campaign.Campaign
+	return nil
+}
+
+func newCodecTestJournal(id string) *BaseJournal {
+	now := time.Date(2024, time.March, 31, 10, 0, 0, 0, time.UTC)
+	return &BaseJournal{
+		JournalID:      id,
+		JournalingTime: now,
+		Description:    "Reward",
+		Amount:         decimal.RequireFromString("10.125"),
+		CreateTime:     now,
+		CreatedBy:      "aCreator",
+		Transactions: []Transaction{
+			&BaseTransaction{TransactionID: id + "-1", JournalID: id, AccountNumber: "RESERVE", TransactionType: DEBIT, Amount: decimal.RequireFromString("10.125"), TransactionTime: now},
+			&BaseTransaction{TransactionID: id + "-2", JournalID: id, AccountNumber: "WALLET", TransactionType: CREDIT, Amount: decimal.RequireFromString("10.125"), TransactionTime: now},
+		},
+	}
+}
+
+func TestBaseJournal_JSONRoundTrip(t *testing.T) {
+	reversed := newCodecTestJournal("J1")
+	journal := newCodecTestJournal("J2")
+	journal.Reversal = true
+	journal.ReversedJournal = reversed
+
+	bytes, err := json.Marshal(journal)
+	assert.NoError(t, err)
+	t.Log(string(bytes))
+
+	result := &BaseJournal{}
+	assert.NoError(t, json.Unmarshal(bytes, result))
+	assert.Equal(t, journal.JournalID, result.JournalID)
+	assert.True(t, result.Amount.Equal(journal.Amount))
+	assert.Len(t, result.Transactions, 2)
+	assert.Equal(t, "WALLET", result.Transactions[1].GetAccountNumber())
+	assert.Equal(t, CREDIT, result.Transactions[1].GetAlignment())
+	assert.True(t, result.Transactions[1].GetAmount().Equal(decimal.RequireFromString("10.125")))
+	assert.NotNil(t, result.ReversedJournal)
+	assert.Equal(t, "J1", result.ReversedJournal.GetJournalID())
+	assert.Len(t, result.ReversedJournal.GetTransactions(), 2)
+	assert.Nil(t, result.ReversedJournal.GetReversedJournal())
+}
+
+func TestJournalCodec_CustomTypes(t *testing.T) {
+	codec := NewJournalCodec().RegisterTransactionType("CampaignTransaction", func() Transaction { return &campaignTransaction{} })
+	journal := newCodecTestJournal("J1")
+	journal.Transactions[1] = &campaignTransaction{BaseTransaction: *journal.Transactions[1].(*BaseTransaction), Campaign: "RAMADAN"}
+
+	_, err := DefaultJournalCodec.Marshal(journal)
+	assert.ErrorIs(t, err, ErrJSONUnknownType)
+
+	bytes, err := codec.Marshal(journal)
+	assert.NoError(t, err)
+	t.Log(string(bytes))
+	result, err := codec.Unmarshal(context.Background(), bytes)
+	assert.NoError(t, err)
+	assert.IsType(t, &BaseJournal{}, result)
+	assert.IsType(t, &BaseTransaction{}, result.GetTransactions()[0])
+	campaign, ok := result.GetTransactions()[1].(*campaignTransaction)
+	assert.True(t, ok)
+	assert.Equal(t, "RAMADAN", campaign.Campaign)
+	assert.Equal(t, "WALLET", campaign.GetAccountNumber())
+
+	_, err = NewJournalCodec().Unmarshal(context.Background(), bytes)
+	assert.ErrorIs(t, err, ErrJSONUnknownType)
+	_, err = codec.Unmarshal(context.Background(), []byte(`{"type":"UnknownJournal","journal_id":"J1"}`))
+	assert.ErrorIs(t, err, ErrJSONUnknownType)
+}
+
+func TestJournalCodec_ReversedJournalByReference(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	acc := newTestAccounting(store)
+	reserve, err := acc.CreateNewAccount(ctx, "RESERVE", "Reserve", "Points reserve", "1.1", "PTS", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "WALLET", "Wallet", "Points wallet", "2.1", "PTS", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	journal, err := acc.CreateNewJournal(ctx, "Reward", []TransactionInfo{
+		{AccountNumber: reserve.GetAccountNumber(), Description: "Reward", TxType: DEBIT, Amount: decimal.NewFromInt(10)},
+		{AccountNumber: wallet.GetAccountNumber(), Description: "Reward", TxType: CREDIT, Amount: decimal.NewFromInt(10)},
+	}, "aCreator")
+	assert.NoError(t, err)
+	reversal, err := acc.CreateReversal(ctx, "Reward cancelled", journal, "aCreator")
+	assert.NoError(t, err)
+
+	codec := NewJournalCodec().SetReversedJournalByReference(true)
+	bytes, err := codec.Marshal(reversal)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), `"reversed_journal":"`+journal.GetJournalID()+`"`)
+
+	// without a journal manager, only the ID of the reversed journal is known.
+	result, err := codec.Unmarshal(ctx, bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, journal.GetJournalID(), result.GetReversedJournal().GetJournalID())
+	assert.Len(t, result.GetReversedJournal().GetTransactions(), 0)
+
+	result, err = codec.SetJournalManager(store.GetJournalManager()).Unmarshal(ctx, bytes)
+	assert.NoError(t, err)
+	assert.True(t, result.IsReversal())
+	assert.Len(t, result.GetTransactions(), 2)
+	assert.Equal(t, journal.GetJournalID(), result.GetReversedJournal().GetJournalID())
+	assert.Len(t, result.GetReversedJournal().GetTransactions(), 2)
+}
@@ -0,0 +1,189 @@
+package acccore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// JournalHookStage is the stage of the journal posting a JournalHook is called at
+type JournalHookStage int
+
+const (
+	// PreValidate hooks are called first, before the journal is validated
+	PreValidate JournalHookStage = iota
+	// PrePersist hooks are called once the journal is validated, right before it is persisted
+	PrePersist
+	// PostPersist hooks are called once the journal is persisted but not yet committed.
+	// Vetoing at this stage cancels the persisted journal.
+	PostPersist
+	// PostCommit hooks are called once the journal is committed. The journal can no longer be vetoed,
+	// errors returned at this stage are only logged.
+	PostCommit
+)
+
+// String returns the name of the stage
+func (stage JournalHookStage) String() string {
+	switch stage {
+	case PreValidate:
+		return "pre-validate"
+	case PrePersist:
+		return "pre-persist"
+	case PostPersist:
+		return "post-persist"
+	case PostCommit:
+		return "post-commit"
+	}
+	return fmt.Sprintf("stage %d", int(stage))
+}
+
+// JournalHook is called with the journal being posted at a stage of the posting.
+// Returning an error vetoes the journal, the error is returned wrapped together with ErrJournalVetoed.
+type JournalHook func(context context.Context, journal Journal) error
+
+// NewHookedJournalManager wraps the journal manager, so hooks can be called around the journal posting.
+func NewHookedJournalManager(journalManager JournalManager) *HookedJournalManager {
+	return &HookedJournalManager{
+		JournalManager: journalManager,
+		hooks:          make(map[JournalHookStage][]JournalHook),
+	}
+}
+
+// HookedJournalManager is a JournalManager calling a chain of hooks at each stage of the journal posting,
+// around the journal manager it wraps. The hooks of a stage are called in the order they were added,
+// and the first hook returning an error stops the chain.
+type HookedJournalManager struct {
+	JournalManager
+
+	mutex sync.RWMutex
+	hooks map[JournalHookStage][]JournalHook
+}
+
+// AddHook appends the hook into the chain of the stage
+func (hm *HookedJournalManager) AddHook(stage JournalHookStage, hook JournalHook) *HookedJournalManager {
+	hm.mutex.Lock()
+	defer hm.mutex.Unlock()
+	hm.hooks[stage] = append(hm.hooks[stage], hook)
+	return hm
+}
+
+// PersistJournal calls the PreValidate hooks, validates the journal using ValidateJournal, calls the PrePersist hooks,
+// persists the journal using the wrapped journal manager and then calls the PostPersist hooks.
+// If a PostPersist hook vetoes the journal, the persisted journal is cancelled.
+func (hm *HookedJournalManager) PersistJournal(context context.Context, journalToPersist Journal) error {
+	if err := hm.callHooks(context, PreValidate, journalToPersist); err != nil {
+		return err
+	}
+	if err := ValidateJournal(journalToPersist); err != nil {
+		return err
+	}
+	if err := hm.callHooks(context, PrePersist, journalToPersist); err != nil {
+		return err
+	}
+	if err := hm.JournalManager.PersistJournal(context, journalToPersist); err != nil {
+		return err
+	}
+	if err := hm.callHooks(context, PostPersist, journalToPersist); err != nil {
+		if cancelErr := hm.JournalManager.CancelJournal(context, journalToPersist); cancelErr != nil {
+			logrus.Errorf("error cancelling journal %s vetoed after persisted. got %s", journalToPersist.GetJournalID(), cancelErr.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// journalCommitter is implemented by the journal managers that act on committed journals, so journals committed
+// through another commit function, like the one capturing a hold along with its journal, are still acted on.
+type journalCommitter interface {
+	// commitJournalWith commits the journal using the commit function, and then acts on the committed journal.
+	commitJournalWith(context context.Context, journalToCommit Journal, commit func(context context.Context, journal Journal) error) error
+}
+
+// CommitJournal commits the journal using the wrapped journal manager and then calls the PostCommit hooks.
+// As the journal is already committed, errors returned by the PostCommit hooks are only logged.
+func (hm *HookedJournalManager) CommitJournal(context context.Context, journalToCommit Journal) error {
+	return hm.commitJournalWith(context, journalToCommit, hm.JournalManager.CommitJournal)
+}
+
+// commitJournalWith commits the journal using the commit function and then calls the PostCommit hooks,
+// journals committed along with a hold capture go through here.
+func (hm *HookedJournalManager) commitJournalWith(context context.Context, journalToCommit Journal, commit func(context context.Context, journal Journal) error) error {
+	if err := commit(context, journalToCommit); err != nil {
+		return err
+	}
+	if err := hm.callHooks(context, PostCommit, journalToCommit); err != nil {
+		logrus.Errorf("error on hook after journal %s committed. got %s", journalToCommit.GetJournalID(), err.Error())
+	}
+	return nil
+}
+
+// callHooks calls the hooks of the stage in order, stopping at the first hook returning an error.
+func (hm *HookedJournalManager) callHooks(context context.Context, stage JournalHookStage, journal Journal) error {
+	hm.mutex.RLock()
+	hooks := hm.hooks[stage]
+	hm.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(context, journal); err != nil {
+			journalID := ""
+			if journal != nil {
+				journalID = journal.GetJournalID()
+			}
+			logrus.Errorf("error persisting journal %s. vetoed on %s hook. got %s", journalID, stage, err.Error())
+			return fmt.Errorf("%w on %s hook: %w", ErrJournalVetoed, stage, err)
+		}
+	}
+	return nil
+}
+
+// ValidateJournal checks the journal content that can be verified without looking at the database.
+// The journal must have an ID, an author and Transactions, each transaction must have an ID, be either DEBIT or CREDIT
+// and belong to a different account, and the sum of DEBIT must equal the sum of CREDIT.
+// It is the validation journal managers run before persisting a journal.
+func ValidateJournal(journal Journal) error {
+	if journal == nil {
+		return ErrJournalNil
+	}
+	if len(journal.GetJournalID()) == 0 {
+		logrus.Errorf("error persisting journal. journal is missing the JournalID")
+		return ErrJournalMissingID
+	}
+	if len(journal.GetTransactions()) == 0 {
+		logrus.Errorf("error persisting journal %s. journal contains no Transactions.", journal.GetJournalID())
+		return ErrJournalNoTransaction
+	}
+	if len(journal.GetCreateBy()) == 0 {
+		logrus.Errorf("error persisting journal %s. journal author not known.", journal.GetJournalID())
+		return ErrJournalMissingAuthor
+	}
+	debitSum, creditSum := decimal.Zero, decimal.Zero
+	accounts := make(map[string]bool)
+	for idx, trx := range journal.GetTransactions() {
+		if len(trx.GetTransactionID()) == 0 {
+			logrus.Errorf("error persisting journal %s. transaction %d is missing TransactionID.", journal.GetJournalID(), idx)
+			return ErrJournalTransactionMissingID
+		}
+		if accounts[trx.GetAccountNumber()] {
+			logrus.Errorf("error persisting journal %s. multiple transaction belong to the same account (%s)", journal.GetJournalID(), trx.GetAccountNumber())
+			return ErrJournalTransactionAccountDuplicate
+		}
+		accounts[trx.GetAccountNumber()] = true
+		switch trx.GetAlignment() {
+		case DEBIT:
+			debitSum = debitSum.Add(trx.GetAmount())
+		case CREDIT:
+			creditSum = creditSum.Add(trx.GetAmount())
+		default:
+			logrus.Errorf("error persisting journal %s. transaction %d have unknown alignment %d", journal.GetJournalID(), idx, trx.GetAlignment())
+			return ErrJournalTransactionUnknownAlignment
+		}
+	}
+	if !debitSum.Equal(creditSum) {
+		logrus.Errorf("error persisting journal %s. debit (%s) != credit (%s). journal not Balance", journal.GetJournalID(), debitSum, creditSum)
+		return ErrJournalNotBalance
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package acccore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testJournalHooks(t *testing.T, accountManager AccountManager, transactionManager TransactionManager, journalManager JournalManager, holdManager HoldManager) {
+	ctx := context.Background()
+	hooked := NewHookedJournalManager(journalManager)
+	acc := NewAccounting(accountManager, transactionManager, hooked, &RandomGenUniqueIDGenerator{
+		Length:     16,
+		UpperAlpha: true,
+		Numeric:    true,
+	})
+
+	equity, err := acc.CreateNewAccount(ctx, "", "Equity", "Owner equity", "3.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	cash, err := acc.CreateNewAccount(ctx, "", "Cash", "Cash on hand", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	transactions := func(amount int64) []TransactionInfo {
+		return []TransactionInfo{
+			{AccountNumber: cash.GetAccountNumber(), Description: "Cash", TxType: DEBIT, Amount: decimal.NewFromInt(amount)},
+			{AccountNumber: equity.GetAccountNumber(), Description: "Equity", TxType: CREDIT, Amount: decimal.NewFromInt(amount)},
+		}
+	}
+	balanceOf := func(account Account) decimal.Decimal {
+		loaded, err := accountManager.GetAccountByID(ctx, account.GetAccountNumber())
+		assert.NoError(t, err)
+		return loaded.GetBalance()
+	}
+
+	stages := make([]JournalHookStage, 0)
+	for _, stage := range []JournalHookStage{PreValidate, PrePersist, PostPersist, PostCommit} {
+		hooked.AddHook(stage, func(context context.Context, journal Journal) error {
+			stages = append(stages, stage)
+			return nil
+		})
+	}
+	_, err = acc.CreateNewJournal(ctx, "Capital", transactions(1000), "aCreator")
+	assert.NoError(t, err)
+	assert.Equal(t, []JournalHookStage{PreValidate, PrePersist, PostPersist, PostCommit}, stages)
+	assert.True(t, balanceOf(equity).Equal(decimal.NewFromInt(1000)))
+
+	// business rule vetoing the journal before it is validated.
+	errOpsOnly := fmt.Errorf("only ops team can debit equity")
+	hooked.AddHook(PreValidate, func(context context.Context, journal Journal) error {
+		for _, trx := range journal.GetTransactions() {
+			if trx.GetAccountNumber() == equity.GetAccountNumber() && trx.GetAlignment() == DEBIT && journal.GetCreateBy() != "ops" {
+				return errOpsOnly
+			}
+		}
+		return nil
+	})
+	withdraw := []TransactionInfo{
+		{AccountNumber: equity.GetAccountNumber(), Description: "Equity", TxType: DEBIT, Amount: decimal.NewFromInt(100)},
+		{AccountNumber: cash.GetAccountNumber(), Description: "Cash", TxType: CREDIT, Amount: decimal.NewFromInt(100)},
+	}
+	stages = stages[:0]
+	_, err = acc.CreateNewJournal(ctx, "Withdraw", withdraw, "aCreator")
+	assert.ErrorIs(t, err, ErrJournalVetoed)
+	assert.ErrorIs(t, err, errOpsOnly)
+	assert.Equal(t, []JournalHookStage{PreValidate}, stages)
+	assert.True(t, balanceOf(equity).Equal(decimal.NewFromInt(1000)))
+
+	_, err = acc.CreateNewJournal(ctx, "Withdraw", withdraw, "ops")
+	assert.NoError(t, err)
+	assert.True(t, balanceOf(equity).Equal(decimal.NewFromInt(900)))
+
+	// an invalid journal never reaches the pre-persist hooks.
+	stages = stages[:0]
+	_, err = acc.CreateNewJournal(ctx, "Unbalanced", []TransactionInfo{
+		{AccountNumber: cash.GetAccountNumber(), Description: "Cash", TxType: DEBIT, Amount: decimal.NewFromInt(10)},
+		{AccountNumber: equity.GetAccountNumber(), Description: "Equity", TxType: CREDIT, Amount: decimal.NewFromInt(20)},
+	}, "aCreator")
+	assert.ErrorIs(t, err, ErrJournalNotBalance)
+	assert.Equal(t, []JournalHookStage{PreValidate}, stages)
+
+	// vetoing after the journal is persisted cancels it.
+	errLimit := fmt.Errorf("capital injection limit exceeded")
+	var vetoedID string
+	hooked.AddHook(PostPersist, func(context context.Context, journal Journal) error {
+		for _, trx := range journal.GetTransactions() {
+			if trx.GetAccountNumber() == equity.GetAccountNumber() && trx.GetAmount().GreaterThan(decimal.NewFromInt(5000)) {
+				vetoedID = journal.GetJournalID()
+				return errLimit
+			}
+		}
+		return nil
+	})
+	_, err = acc.CreateNewJournal(ctx, "Big capital", transactions(10000), "aCreator")
+	assert.ErrorIs(t, err, ErrJournalVetoed)
+	assert.ErrorIs(t, err, errLimit)
+	assert.NotEmpty(t, vetoedID)
+	exist, err := journalManager.IsJournalIDExist(ctx, vetoedID)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	assert.True(t, balanceOf(equity).Equal(decimal.NewFromInt(900)))
+
+	// capture journals go through the hooks like any other journal, post-commit included.
+	acc.SetHoldManager(holdManager)
+	hold, err := acc.PlaceHold(ctx, cash.GetAccountNumber(), decimal.NewFromInt(50), time.Now().Add(time.Hour), "Deposit", "ops")
+	assert.NoError(t, err)
+	stages = stages[:0]
+	_, err = acc.CaptureHold(ctx, hold.GetHoldID(), equity.GetAccountNumber(), decimal.NewFromInt(50), "Refund", "ops")
+	assert.NoError(t, err)
+	assert.Equal(t, []JournalHookStage{PreValidate, PrePersist, PostPersist, PostCommit}, stages)
+	assert.True(t, balanceOf(equity).Equal(decimal.NewFromInt(850)))
+
+	// the journal is already committed, post-commit errors do not fail the posting.
+	hooked.AddHook(PostCommit, func(context context.Context, journal Journal) error {
+		return fmt.Errorf("notification service is down")
+	})
+	_, err = acc.CreateNewJournal(ctx, "Capital", transactions(100), "aCreator")
+	assert.NoError(t, err)
+	assert.True(t, balanceOf(equity).Equal(decimal.NewFromInt(950)))
+}
+
+func TestValidateJournal(t *testing.T) {
+	trx := func(id, account string, alignment Alignment, amount int64) Transaction {
+		return &BaseTransaction{TransactionID: id, AccountNumber: account, TransactionType: alignment, Amount: decimal.NewFromInt(amount)}
+	}
+	assert.ErrorIs(t, ValidateJournal(nil), ErrJournalNil)
+	assert.ErrorIs(t, ValidateJournal(&BaseJournal{CreatedBy: "aCreator"}), ErrJournalMissingID)
+	assert.ErrorIs(t, ValidateJournal(&BaseJournal{JournalID: "J1", CreatedBy: "aCreator"}), ErrJournalNoTransaction)
+	assert.ErrorIs(t, ValidateJournal(&BaseJournal{JournalID: "J1", Transactions: []Transaction{trx("T1", "A", DEBIT, 10)}}), ErrJournalMissingAuthor)
+	assert.ErrorIs(t, ValidateJournal(&BaseJournal{JournalID: "J1", CreatedBy: "aCreator",
+		Transactions: []Transaction{trx("T1", "A", DEBIT, 10), trx("", "B", CREDIT, 10)}}), ErrJournalTransactionMissingID)
+	assert.ErrorIs(t, ValidateJournal(&BaseJournal{JournalID: "J1", CreatedBy: "aCreator",
+		Transactions: []Transaction{trx("T1", "A", DEBIT, 10), trx("T2", "A", CREDIT, 10)}}), ErrJournalTransactionAccountDuplicate)
+	assert.ErrorIs(t, ValidateJournal(&BaseJournal{JournalID: "J1", CreatedBy: "aCreator",
+		Transactions: []Transaction{trx("T1", "A", DEBIT, 10), trx("T2", "B", CREDIT, 20)}}), ErrJournalNotBalance)
+	unknown := &BaseJournal{JournalID: "J1", CreatedBy: "aCreator",
+		Transactions: []Transaction{trx("T1", "A", DEBIT, 10), trx("T2", "B", CREDIT, 10), trx("T3", "C", Alignment(2), 0)}}
+	assert.ErrorIs(t, ValidateJournal(unknown), ErrJournalTransactionUnknownAlignment)
+	assert.ErrorIs(t, NewInMemoryStore().GetJournalManager().PersistJournal(context.Background(), unknown), ErrJournalTransactionUnknownAlignment)
+	assert.ErrorIs(t, newTestSQLStore(t).GetJournalManager().PersistJournal(context.Background(), unknown), ErrJournalTransactionUnknownAlignment)
+	assert.NoError(t, ValidateJournal(&BaseJournal{JournalID: "J1", CreatedBy: "aCreator",
+		Transactions: []Transaction{trx("T1", "A", DEBIT, 10), trx("T2", "B", CREDIT, 10)}}))
+}
+
+func TestHookedJournalManager_Hooks(t *testing.T) {
+	store := NewInMemoryStore()
+	testJournalHooks(t, store.GetAccountManager(), store.GetTransactionManager(), store.GetJournalManager(), store.GetHoldManager())
+}
+
+func TestHookedJournalManager_HooksSQL(t *testing.T) {
+	store := newTestSQLStore(t)
+	testJournalHooks(t, store.GetAccountManager(), store.GetTransactionManager(), store.GetJournalManager(), store.GetHoldManager())
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"iter"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
@@ -25,6 +27,10 @@ type InMemoryJournalRecords struct {
 	amount            decimal.Decimal
 	createTime        time.Time
 	createBy          string
+	idempotencyKey    string
+	exchangeRate      decimal.Decimal
+	committed         bool
+	commitSequence    int64
 }
 
 // InMemoryAccountRecord is simulating records in Account table
@@ -35,11 +41,15 @@ type InMemoryAccountRecord struct {
 	description         string
 	baseTransactionType Alignment
 	balance             decimal.Decimal
+	balanceLimit        BalanceLimit
+	overdraftLimit      decimal.Decimal
+	state               AccountState
 	coa                 string
 	createTime          time.Time
 	createBy            string
 	updateTime          time.Time
 	updateBy            string
+	version             int64
 }
 
 // InMemoryTransactionRecords is simulating records in Transaction table
@@ -54,47 +64,245 @@ type InMemoryTransactionRecords struct {
 	accountBalance  decimal.Decimal
 	createTime      time.Time
 	createBy        string
+	committed       bool
+	commitSequence  int64
+}
+
+// balancedAfter tells whether the account Balance recorded on this transaction is more recent than the one of the other transaction.
+// Transactions are ordered by their transaction time, then by the order they were committed in, then by their ID.
+func (record *InMemoryTransactionRecords) balancedAfter(other *InMemoryTransactionRecords) bool {
+	if !record.transactionTime.Equal(other.transactionTime) {
+		return record.transactionTime.After(other.transactionTime)
+	}
+	if record.commitSequence != other.commitSequence {
+		return record.commitSequence > other.commitSequence
+	}
+	return record.transactionID > other.transactionID
+}
+
+// InMemoryHoldRecord is simulating records in Hold table
+type InMemoryHoldRecord struct {
+	holdID         string
+	accountNumber  string
+	description    string
+	amount         decimal.Decimal
+	capturedAmount decimal.Decimal
+	state          HoldState
+	expireTime     time.Time
+	createTime     time.Time
+	createBy       string
+	updateTime     time.Time
+	updateBy       string
+}
+
+// InMemoryCOARecord is simulating records in COA table
+type InMemoryCOARecord struct {
+	code        string
+	parentCode  string
+	name        string
+	description string
+	category    COACategory
+	alignment   Alignment
+	createTime  time.Time
+	createBy    string
 }
 
 // InMemoryCurrencyRecords is the in memory data structure
 type InMemoryCurrencyRecords struct {
-	code       string
-	name       string
-	exchange   decimal.Decimal
-	createTime time.Time
-	createBy   string
-	updateTime time.Time
-	updateBy   string
+	code         string
+	name         string
+	exchange     decimal.Decimal
+	scale        int32
+	roundingMode RoundingMode
+	createTime   time.Time
+	createBy     string
+	updateTime   time.Time
+	updateBy     string
+}
+
+// InMemoryStore is an isolated set of simulated tables. Each store owns its own Journal, Account, Transaction and
+// Currency tables, guarded by a single read-write lock, and hands out managers that are bound to it.
+// Two stores never share records, so multiple ledgers can live side by side in one process.
+type InMemoryStore struct {
+	mutex sync.RWMutex
+
+	// journalTable the simulated Journal table
+	journalTable map[string]*InMemoryJournalRecords
+
+	// accountTable the simulated Account table
+	accountTable map[string]*InMemoryAccountRecord
+
+	// transactionTable the simulated Transaction table
+	transactionTable map[string]*InMemoryTransactionRecords
+
+	// currencyTable the simulated Currency table
+	currencyTable map[string]*InMemoryCurrencyRecords
+
+	// currencyRateTable the simulated Currency rate history table, keyed by the Currency code
+	currencyRateTable map[string][]*CurrencyRate
+
+	// coaTable the simulated COA table
+	coaTable map[string]*InMemoryCOARecord
+
+	// accountStateTable the simulated Account state change table, keyed by the account number
+	accountStateTable map[string][]*AccountStateChange
+
+	// holdTable the simulated Hold table
+	holdTable map[string]*InMemoryHoldRecord
+
+	// commitSequence the simulated commit sequence, the sequence of the last committed journal
+	commitSequence int64
+
+	// commonDenominator is the common denominator used by the exchange manager
+	commonDenominator decimal.Decimal
+
+	// cursorCodec signs and verifies the pagination cursors
+	cursorCodec *cursorCodec
+
+	journalManager     *InMemoryJournalManager
+	accountManager     *InMemoryAccountManager
+	transactionManager *InMemoryTransactionManager
+	exchangeManager    *InMemoryExchangeManager
+	coaManager         *InMemoryCOAManager
+	holdManager        *InMemoryHoldManager
+}
+
+// NewInMemoryStore creates a new, empty and isolated in-memory store.
+func NewInMemoryStore() *InMemoryStore {
+	store := &InMemoryStore{
+		commonDenominator: decimal.NewFromInt(1),
+		cursorCodec:       newCursorCodec(),
+	}
+	store.Clear()
+	store.journalManager = &InMemoryJournalManager{store: store}
+	store.accountManager = &InMemoryAccountManager{store: store}
+	store.transactionManager = &InMemoryTransactionManager{store: store}
+	store.exchangeManager = &InMemoryExchangeManager{store: store}
+	store.coaManager = &InMemoryCOAManager{store: store}
+	store.holdManager = &InMemoryHoldManager{store: store}
+	return store
+}
+
+// Clear removes all records from the tables of this store.
+func (store *InMemoryStore) Clear() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.journalTable = make(map[string]*InMemoryJournalRecords, 0)
+	store.accountTable = make(map[string]*InMemoryAccountRecord, 0)
+	store.transactionTable = make(map[string]*InMemoryTransactionRecords, 0)
+	store.currencyTable = make(map[string]*InMemoryCurrencyRecords, 0)
+	store.currencyRateTable = make(map[string][]*CurrencyRate, 0)
+	store.coaTable = make(map[string]*InMemoryCOARecord, 0)
+	store.accountStateTable = make(map[string][]*AccountStateChange, 0)
+	store.holdTable = make(map[string]*InMemoryHoldRecord, 0)
+	store.commitSequence = 0
+}
+
+// SetCursorSecret sets the secret used to sign the pagination cursors. By default a random secret is used.
+func (store *InMemoryStore) SetCursorSecret(secret []byte) *InMemoryStore {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.cursorCodec = &cursorCodec{secret: secret}
+	return store
+}
+
+// getCursorCodec returns the codec signing and verifying the pagination cursors
+func (store *InMemoryStore) getCursorCodec() *cursorCodec {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	return store.cursorCodec
+}
+
+// GetJournalManager returns the journal manager bound to this store
+func (store *InMemoryStore) GetJournalManager() JournalManager {
+	return store.journalManager
+}
+
+// GetAccountManager returns the account manager bound to this store
+func (store *InMemoryStore) GetAccountManager() AccountManager {
+	return store.accountManager
+}
+
+// GetTransactionManager returns the transaction manager bound to this store
+func (store *InMemoryStore) GetTransactionManager() TransactionManager {
+	return store.transactionManager
+}
+
+// GetExchangeManager returns the exchange manager bound to this store
+func (store *InMemoryStore) GetExchangeManager() ExchangeManager {
+	return store.exchangeManager
+}
+
+// GetCOAManager returns the chart of accounts manager bound to this store
+func (store *InMemoryStore) GetCOAManager() COAManager {
+	return store.coaManager
+}
+
+// GetHoldManager returns the fund hold manager bound to this store
+func (store *InMemoryStore) GetHoldManager() HoldManager {
+	return store.holdManager
 }
 
 var (
-	// InMemoryJournalTable the simulated Journal table
+	// defaultInMemoryStore is the store used by managers that are not bound to any store,
+	// such as a zero valued InMemoryJournalManager.
+	defaultInMemoryStore = NewInMemoryStore()
+
+	// InMemoryJournalTable the simulated Journal table of the default in-memory store.
+	//
+	// Deprecated: the table is not guarded by the store lock, use the managers of an InMemoryStore instead.
 	InMemoryJournalTable map[string]*InMemoryJournalRecords
 
-	// InMemoryAccountTable the simulated Account table
+	// InMemoryAccountTable the simulated Account table of the default in-memory store.
+	//
+	// Deprecated: the table is not guarded by the store lock, use the managers of an InMemoryStore instead.
 	InMemoryAccountTable map[string]*InMemoryAccountRecord
 
-	// InMemoryTransactionTable the simulated Transaction table
+	// InMemoryTransactionTable the simulated Transaction table of the default in-memory store.
+	//
+	// Deprecated: the table is not guarded by the store lock, use the managers of an InMemoryStore instead.
 	InMemoryTransactionTable map[string]*InMemoryTransactionRecords
 
-	// InMemoryCurrencyTable the simulated Currency table
+	// InMemoryCurrencyTable the simulated Currency table of the default in-memory store.
+	//
+	// Deprecated: the table is not guarded by the store lock, use the managers of an InMemoryStore instead.
 	InMemoryCurrencyTable map[string]*InMemoryCurrencyRecords
 )
 
 func init() {
-	ClearInMemoryTables()
+	exportDefaultTables()
+}
+
+// exportDefaultTables points the deprecated table variables to the tables of the default in-memory store,
+// which are replaced every time the store is cleared.
+func exportDefaultTables() {
+	defaultInMemoryStore.mutex.RLock()
+	defer defaultInMemoryStore.mutex.RUnlock()
+	InMemoryJournalTable = defaultInMemoryStore.journalTable
+	InMemoryAccountTable = defaultInMemoryStore.accountTable
+	InMemoryTransactionTable = defaultInMemoryStore.transactionTable
+	InMemoryCurrencyTable = defaultInMemoryStore.currencyTable
 }
 
-// ClearInMemoryTables initializes the memory tables
+// ClearInMemoryTables initializes the memory tables of the default in-memory store.
+// Managers created using NewInMemoryStore are not affected, use InMemoryStore.Clear for those.
 func ClearInMemoryTables() {
-	InMemoryJournalTable = make(map[string]*InMemoryJournalRecords, 0)
-	InMemoryAccountTable = make(map[string]*InMemoryAccountRecord, 0)
-	InMemoryTransactionTable = make(map[string]*InMemoryTransactionRecords, 0)
-	InMemoryCurrencyTable = make(map[string]*InMemoryCurrencyRecords, 0)
+	defaultInMemoryStore.Clear()
+	exportDefaultTables()
 }
 
 // InMemoryJournalManager implementation of JournalManager using inmemory Journal table map
 type InMemoryJournalManager struct {
+	store *InMemoryStore
+}
+
+// getStore returns the store this manager is bound to, or the default store if not bound to any.
+func (jm *InMemoryJournalManager) getStore() *InMemoryStore {
+	if jm.store == nil {
+		return defaultInMemoryStore
+	}
+	return jm.store
 }
 
 // NewJournal will create new blank un-persisted journal
@@ -107,219 +315,339 @@ func (jm *InMemoryJournalManager) NewJournal(context context.Context) Journal {
 //
 //	1.NOT BE PERSISTED. (the journal AccountNumber is not exist in DB yet)
 //	2.Pointing or owned by a PERSISTED Account
-//	3.Each of this account must belong to the same Currency
+//	3.Balanced per Currency. The Transactions on the accounts of each Currency balance on their own, so a journal
+//	  may only mix currencies through legs offsetting each other, such as the FX position legs.
 //	4.Balanced. The total sum of DEBIT and total sum of CREDIT is equal.
 //	5.No duplicate transaction that belongs to the same Account.
+//	6.Its idempotency key, if any, is not used by other journal.
 //
-// If your database support 2 phased commit, you can make all Balance changes in
-// accounts and Transactions. If your db do not support this, you can implement your own 2 phase commits mechanism
-// on the CommitJournal and CancelJournal
+// A persisted journal is staged, it is not yet visible and do not change any account Balance
+// until it is committed using CommitJournal, or discarded using CancelJournal.
 func (jm *InMemoryJournalManager) PersistJournal(context context.Context, journalToPersist Journal) error {
 	// First we have to make sure that the journalToPersist is not yet in our database.
-	// 1. Checking if the mandatories is not missing
-	if journalToPersist == nil {
-		return ErrJournalNil
-	}
-	if len(journalToPersist.GetJournalID()) == 0 {
-		logrus.Errorf("error persisting journal. journal is missing the JournalID")
-		return ErrJournalMissingID
-	}
-	if len(journalToPersist.GetTransactions()) == 0 {
-		logrus.Errorf("error persisting journal %s. journal contains no Transactions.", journalToPersist.GetJournalID())
-		return ErrJournalNoTransaction
-	}
-	if len(journalToPersist.GetCreateBy()) == 0 {
-		logrus.Errorf("error persisting journal %s. journal author not known.", journalToPersist.GetJournalID())
-		return ErrJournalMissingAuthor
+	// 1. Checking the mandatories are not missing, the Transactions are IDed, balanced,
+	//    and do not appear twice for the same account.
+	if err := ValidateJournal(journalToPersist); err != nil {
+		return err
 	}
 
+	// The whole validation and insertion is done while holding the write lock,
+	// so concurrent journals can not interleave between the checks and the insertion.
+	store := jm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
 	// 2. Checking if the journal ID must not in the Database (already persisted)
 	//    SQL HINT : SELECT COUNT(*) FROM JOURNAL WHERE JOURNAL.ID = {journalToPersist.GetJournalID()}
 	//    If COUNT(*) is > 0 return error
-	if _, exist := InMemoryJournalTable[journalToPersist.GetJournalID()]; exist {
+	if _, exist := store.journalTable[journalToPersist.GetJournalID()]; exist {
 		logrus.Errorf("error persisting journal %s. journal already exist.", journalToPersist.GetJournalID())
 		return ErrJournalAlreadyPersisted
 	}
-
-	// 3. Make sure all journal Transactions are IDed.
-	for idx, trx := range journalToPersist.GetTransactions() {
-		if len(trx.GetTransactionID()) == 0 {
-			logrus.Errorf("error persisting journal %s. transaction %d is missing TransactionID.", journalToPersist.GetJournalID(), idx)
-			return ErrJournalTransactionMissingID
+	//    The idempotency key must not be used by other journal, not even one that is still waiting to be committed.
+	//    SQL HINT : SELECT COUNT(*) FROM JOURNAL WHERE JOURNAL.IDEMPOTENCY_KEY = {journalToPersist.GetIdempotencyKey()}
+	if len(journalToPersist.GetIdempotencyKey()) > 0 {
+		for _, j := range store.journalTable {
+			if j.idempotencyKey == journalToPersist.GetIdempotencyKey() {
+				logrus.Errorf("error persisting journal %s. idempotency key %s is already used by journal %s.", journalToPersist.GetJournalID(), j.idempotencyKey, j.journalID)
+				return ErrJournalIdempotencyKeyAlreadyUsed
+			}
 		}
 	}
 
-	// 4. Make sure all journal Transactions are not persisted.
+	// 3. Make sure all journal Transactions are not persisted.
 	for idx, trx := range journalToPersist.GetTransactions() {
-		if _, exist := InMemoryTransactionTable[trx.GetTransactionID()]; exist {
+		if _, exist := store.transactionTable[trx.GetTransactionID()]; exist {
 			logrus.Errorf("error persisting journal %s. transaction %d is already exist.", journalToPersist.GetJournalID(), idx)
 			return ErrJournalTransactionAlreadyPersisted
 		}
 	}
 
-	// 5. Make sure Transactions are balanced.
-	var creditSum, debitSum decimal.Decimal
-	for _, trx := range journalToPersist.GetTransactions() {
-		if trx.GetAlignment() == DEBIT {
-			debitSum = debitSum.Add(trx.GetAmount())
-		}
-		if trx.GetAlignment() == CREDIT {
-			creditSum = creditSum.Add(trx.GetAmount())
-		}
-	}
-	if !creditSum.Equal(debitSum) {
-		logrus.Errorf("error persisting journal %s. debit (%d) != credit (%d). journal not Balance", journalToPersist.GetJournalID(), debitSum, creditSum)
-		return ErrJournalNotBalance
-	}
-
-	// 6. Make sure Transactions account are not appear twice in the journal
-	accountDupCheck := make(map[string]bool)
-	for _, trx := range journalToPersist.GetTransactions() {
-		if _, exist := accountDupCheck[trx.GetAccountNumber()]; exist {
-			logrus.Errorf("error persisting journal %s. multiple transaction belong to the same account (%s)", journalToPersist.GetJournalID(), trx.GetAccountNumber())
-			return ErrJournalTransactionAccountDuplicate
-		}
-		accountDupCheck[trx.GetAccountNumber()] = true
-	}
-
-	// 7. Make sure Transactions are all belong to existing accounts
+	// 4. Make sure Transactions are all belong to existing accounts, whose state accepts them
 	for _, trx := range journalToPersist.GetTransactions() {
-		if _, exist := InMemoryAccountTable[trx.GetAccountNumber()]; !exist {
+		accountRecord, exist := store.accountTable[trx.GetAccountNumber()]
+		if !exist {
 			logrus.Errorf("error persisting journal %s. theres a transaction belong to non existent account (%s)", journalToPersist.GetJournalID(), trx.GetAccountNumber())
 			return ErrJournalTransactionAccountNotPersist
 		}
+		if err := checkAccountState(accountRecord.state, trx.GetAlignment()); err != nil {
+			logrus.Errorf("error persisting journal %s. account %s is %s", journalToPersist.GetJournalID(), trx.GetAccountNumber(), accountRecord.state)
+			return err
+		}
 	}
 
-	// 8. Make sure Transactions are all have the same Currency
-	var currency string
-	for idx, trx := range journalToPersist.GetTransactions() {
-		// SELECT CURRENCY FROM ACCOUNT WHERE ACCOUNT_NUMBER = {trx.GetAccountNumber()}
-		cur := InMemoryAccountTable[trx.GetAccountNumber()].currency
-		if idx == 0 {
-			currency = cur
-		} else {
-			if cur != currency {
-				logrus.Errorf("error persisting journal %s. Transactions here uses account with different currencies", journalToPersist.GetJournalID())
-				return ErrJournalTransactionMixCurrency
+	// 5. Make sure the Transactions of each Currency balance on their own, so a journal may only span currencies
+	//    through legs that balance per Currency.
+	//    The Transactions amount must also fit the scale of their Currency.
+	// SELECT CURRENCY FROM ACCOUNT WHERE ACCOUNT_NUMBER = {trx.GetAccountNumber()}
+	currencies := make([]string, 0, len(journalToPersist.GetTransactions()))
+	for _, trx := range journalToPersist.GetTransactions() {
+		currency := store.accountTable[trx.GetAccountNumber()].currency
+		// SELECT SCALE, ROUNDING_MODE FROM CURRENCY WHERE CODE = {currency}
+		if currencyRecord, exist := store.currencyTable[currency]; exist {
+			if err := checkAmountPrecision(trx.GetAmount(), currencyRecord.scale, currencyRecord.roundingMode); err != nil {
+				logrus.Errorf("error persisting journal %s. amount %s of account %s have more than %d decimals", journalToPersist.GetJournalID(), trx.GetAmount(), trx.GetAccountNumber(), currencyRecord.scale)
+				return err
 			}
 		}
+		currencies = append(currencies, currency)
+	}
+	amount, err := currencyBalancedAmount(journalToPersist.GetTransactions(), currencies)
+	if err != nil {
+		logrus.Errorf("error persisting journal %s. Transactions here uses account with different currencies that do not balance per Currency", journalToPersist.GetJournalID())
+		return err
 	}
 
-	// 9. If this is a Reversal journal, make sure the journal being reversed have not been reversed before.
+	// 6. If this is a Reversal journal, make sure the journal being reversed have not been reversed before,
+	//    not even by a reversal journal that is still waiting to be committed.
 	if journalToPersist.GetReversedJournal() != nil {
-		reversed, err := jm.IsJournalIDReversed(context, journalToPersist.GetJournalID())
+		reversedJournalID := journalToPersist.GetReversedJournal().GetJournalID()
+		reversed, err := store.isJournalIDReversed(reversedJournalID, true)
 		if err != nil {
 			return err
 		}
 		if reversed {
-			logrus.Errorf("error persisting journal %s. this journal try to make reverse transaction on journals thats already reversed %s", journalToPersist.GetJournalID(), journalToPersist.GetJournalID())
+			logrus.Errorf("error persisting journal %s. this journal try to make reverse transaction on journals thats already reversed %s", journalToPersist.GetJournalID(), reversedJournalID)
 			return ErrJournalCanNotDoubleReverse
 		}
 	}
 
 	// ALL is OK. So lets start persisting.
-
-	// BEGIN transaction
+	// The journal and its Transactions are staged, they only become visible and affect the account Balance
+	// once the journal is committed using CommitJournal.
 
 	// 1. Save the Journal
 	journalToInsert := &InMemoryJournalRecords{
 		journalID:         journalToPersist.GetJournalID(),
-		journalingTime:    time.Now(), // now is set
+		journalingTime:    time.Now(), // will be set on commit
 		description:       journalToPersist.GetDescription(),
 		reversal:          false,      // will be set
 		reversedJournalID: "",         // will be set
-		amount:            creditSum,  // since we know credit sum and debit sum is equal, lets use one of the sum.
+		amount:            amount,     // the credit sum, in the Currency of the first transaction.
 		createTime:        time.Now(), // now is set
 		createBy:          journalToPersist.GetCreateBy(),
+		idempotencyKey:    journalToPersist.GetIdempotencyKey(),
+		exchangeRate:      journalToPersist.GetExchangeRate(),
+		committed:         false,
 	}
 	if journalToPersist.GetReversedJournal() != nil {
 		journalToInsert.reversedJournalID = journalToPersist.GetReversedJournal().GetJournalID()
 		journalToInsert.reversal = true
 	}
 	// This is when we insert the record into table.
-	InMemoryJournalTable[journalToInsert.journalID] = journalToInsert
+	store.journalTable[journalToInsert.journalID] = journalToInsert
 
 	// 2 Save the Transactions
 	for _, trx := range journalToPersist.GetTransactions() {
 		transactionToInsert := &InMemoryTransactionRecords{
 			transactionID:   trx.GetTransactionID(),
-			transactionTime: time.Now(), // now is set
+			transactionTime: time.Now(), // will be set on commit
 			accountNumber:   trx.GetAccountNumber(),
 			journalID:       journalToInsert.journalID,
 			description:     trx.GetDescription(),
 			transactionType: trx.GetAlignment(),
 			amount:          trx.GetAmount(),
-			accountBalance:  decimal.Zero, // will be updated
+			accountBalance:  decimal.Zero, // will be set on commit
 			createTime:      time.Now(),   // now is set
 			createBy:        trx.GetCreateBy(),
+			committed:       false,
 		}
+
+		// This is when we insert the record into table.
+		store.transactionTable[transactionToInsert.transactionID] = transactionToInsert
+	}
+
+	return nil
+}
+
+// CommitJournal will commit the journal into the system
+// Only non committed journal can be committed.
+// Committing the journal applies its Transactions into the accounts Balance, and makes the journal and its
+// Transactions visible. The provided journal is updated to reflect the committed records.
+func (jm *InMemoryJournalManager) CommitJournal(context context.Context, journalToCommit Journal) error {
+	if journalToCommit == nil {
+		return ErrJournalNil
+	}
+
+	store := jm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.commitJournal(journalToCommit, nil)
+}
+
+// commitJournal applies the journal Transactions into the accounts Balance and makes the journal visible.
+// The Balance an account may go down to is checked against its available Balance, so the amount held on the account
+// can not be spent. If the journal captures a hold, the captured amount is no longer held once the journal is committed.
+// The caller must hold the store write lock.
+func (store *InMemoryStore) commitJournal(journalToCommit Journal, capture *holdCapture) error {
+	// SELECT * FROM JOURNAL WHERE JOURNAL_ID = {journalToCommit.GetJournalID()} FOR UPDATE
+	journalRecord, exist := store.journalTable[journalToCommit.GetJournalID()]
+	if !exist {
+		logrus.Errorf("error committing journal %s. journal not found.", journalToCommit.GetJournalID())
+		return ErrJournalIDNotFound
+	}
+	if journalRecord.committed {
+		logrus.Errorf("error committing journal %s. journal already committed.", journalToCommit.GetJournalID())
+		return ErrJournalAlreadyCommitted
+	}
+
+	// BEGIN transaction
+	now := time.Now()
+
+	// SELECT * FROM TRANSACTION WHERE JOURNAL_ID = {journalRecord.journalID}
+	transactionRecords := make([]*InMemoryTransactionRecords, 0)
+	for _, transactionRecord := range store.transactionTable {
+		if transactionRecord.journalID == journalRecord.journalID {
+			transactionRecords = append(transactionRecords, transactionRecord)
+		}
+	}
+
+	// compute the new Balances first, so no account is touched if any of them rejects its transaction.
+	newBalances := make(map[string]decimal.Decimal, len(transactionRecords))
+	for _, transactionRecord := range transactionRecords {
 		// get the account current Balance
-		// SELECT BALANCE, BASE_TRANSACTION_TYPE FROM ACCOUNT WHERE ACCOUNT_ID = {trx.GetAccountNumber()}
-		balance, accountTrxType := InMemoryAccountTable[trx.GetAccountNumber()].balance, InMemoryAccountTable[trx.GetAccountNumber()].baseTransactionType
+		// SELECT BALANCE, BASE_TRANSACTION_TYPE, BALANCE_LIMIT, OVERDRAFT_LIMIT, STATE FROM ACCOUNT WHERE ACCOUNT_ID = {transactionRecord.accountNumber}
+		accountRecord := store.accountTable[transactionRecord.accountNumber]
+		balance, accountTrxType := accountRecord.balance, accountRecord.baseTransactionType
+
+		// the account state might have changed since the journal was persisted.
+		if err := checkAccountState(accountRecord.state, transactionRecord.transactionType); err != nil {
+			logrus.Errorf("error committing journal %s. account %s is %s", journalRecord.journalID, accountRecord.id, accountRecord.state)
+			return err
+		}
 
 		var newBalance decimal.Decimal
-		if transactionToInsert.transactionType == accountTrxType {
-			newBalance = balance.Add(transactionToInsert.amount)
+		if transactionRecord.transactionType == accountTrxType {
+			newBalance = balance.Add(transactionRecord.amount)
 		} else {
-			newBalance = balance.Sub(transactionToInsert.amount)
+			newBalance = balance.Sub(transactionRecord.amount)
+
+			// the held amount is not available, except for the amount captured by this journal.
+			held := store.heldAmount(accountRecord.id, now)
+			heldAfter := held
+			if capture != nil && store.holdTable[capture.holdID].accountNumber == accountRecord.id {
+				heldAfter = held.Sub(capture.amount)
+			}
+			if err := checkBalanceLimit(accountRecord.id, accountRecord.balanceLimit, accountRecord.overdraftLimit, balance.Sub(held), newBalance.Sub(heldAfter)); err != nil {
+				logrus.Errorf("error committing journal %s. got %s", journalRecord.journalID, err.Error())
+				return err
+			}
 		}
-		transactionToInsert.accountBalance = newBalance
+		newBalances[transactionRecord.transactionID] = newBalance
+	}
 
-		// This is when we insert the record into table.
-		InMemoryTransactionTable[transactionToInsert.transactionID] = transactionToInsert
+	// UPDATE COMMIT_SEQUENCE SET LAST_SEQUENCE = LAST_SEQUENCE + 1
+	store.commitSequence++
+
+	for _, transactionRecord := range transactionRecords {
+		accountRecord := store.accountTable[transactionRecord.accountNumber]
+		newBalance := newBalances[transactionRecord.transactionID]
+
+		// UPDATE TRANSACTION SET ACCOUNT_BALANCE = {newBalance}, TRANSACTION_TIME = {now}, COMMITTED = TRUE, COMMIT_SEQUENCE = {commitSequence} WHERE TRANSACTION_ID = {transactionRecord.transactionID}
+		transactionRecord.accountBalance = newBalance
+		transactionRecord.transactionTime = now
+		transactionRecord.committed = true
+		transactionRecord.commitSequence = store.commitSequence
 
 		// Update Account Balance.
-		// UPDATE ACCOUNT SET BALANCE = {newBalance},  UPDATEBY = {trx.GetCreateBy()}, UPDATE_TIME = {time.Now()} WHERE ACCOUNT_ID = {trx.GetAccountNumber()}
-		InMemoryAccountTable[trx.GetAccountNumber()].balance = newBalance
-		InMemoryAccountTable[trx.GetAccountNumber()].updateTime = time.Now()
-		InMemoryAccountTable[trx.GetAccountNumber()].updateBy = trx.GetCreateBy()
+		// UPDATE ACCOUNT SET BALANCE = {newBalance},  UPDATEBY = {transactionRecord.createBy}, UPDATE_TIME = {now}, VERSION = VERSION + 1 WHERE ACCOUNT_ID = {transactionRecord.accountNumber}
+		accountRecord.balance = newBalance
+		accountRecord.updateTime = now
+		accountRecord.updateBy = transactionRecord.createBy
+		accountRecord.version++
 	}
 
+	// UPDATE JOURNAL SET JOURNALING_TIME = {now}, COMMITTED = TRUE, COMMIT_SEQUENCE = {commitSequence} WHERE JOURNAL_ID = {journalRecord.journalID}
+	journalRecord.journalingTime = now
+	journalRecord.committed = true
+	journalRecord.commitSequence = store.commitSequence
+
 	// COMMIT transaction
 
-	return nil
-}
+	journalToCommit.SetJournalingTime(now).SetAmount(journalRecord.amount)
+	for _, trx := range journalToCommit.GetTransactions() {
+		if balance, ok := newBalances[trx.GetTransactionID()]; ok {
+			trx.SetJournalID(journalRecord.journalID).SetTransactionTime(now).SetAccountBalance(balance)
+		}
+	}
 
-// CommitJournal will commit the journal into the system
-// Only non committed journal can be committed.
-// use this if the implementation database do not support 2 phased commit.
-// if your database support 2 phased commit, you should do all commit in the PersistJournal function
-// and this function should simply return nil.
-func (jm *InMemoryJournalManager) CommitJournal(context context.Context, journalToCommit Journal) error {
 	return nil
 }
 
 // CancelJournal Cancel a journal
-// Only non committed journal can be committed.
-// use this if the implementation database do not support 2 phased commit.
-// if your database do not support 2 phased commit, you should do all roll back in the PersistJournal function
-// and this function should simply return nil.
+// Only non committed journal can be cancelled.
+// Cancelling the journal discards the journal and all of its Transactions, as if they were never persisted.
 func (jm *InMemoryJournalManager) CancelJournal(context context.Context, journalToCancel Journal) error {
+	if journalToCancel == nil {
+		return ErrJournalNil
+	}
+
+	store := jm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	journalRecord, exist := store.journalTable[journalToCancel.GetJournalID()]
+	if !exist {
+		logrus.Errorf("error cancelling journal %s. journal not found.", journalToCancel.GetJournalID())
+		return ErrJournalIDNotFound
+	}
+	if journalRecord.committed {
+		logrus.Errorf("error cancelling journal %s. journal already committed.", journalToCancel.GetJournalID())
+		return ErrJournalAlreadyCommitted
+	}
+
+	// DELETE FROM TRANSACTION WHERE JOURNAL_ID = {journalRecord.journalID}
+	for id, transactionRecord := range store.transactionTable {
+		if transactionRecord.journalID == journalRecord.journalID {
+			delete(store.transactionTable, id)
+		}
+	}
+	// DELETE FROM JOURNAL WHERE JOURNAL_ID = {journalRecord.journalID}
+	delete(store.journalTable, journalRecord.journalID)
+
 	return nil
 }
 
 // IsJournalIDExist will check if a Journal ID/number is exist in the database.
+// Journals that are not yet committed are also taken into account, as their ID is already taken.
 func (jm *InMemoryJournalManager) IsJournalIDExist(context context.Context, id string) (bool, error) {
+	store := jm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
 	// SELECT COUNT(*) FROM JOURNAL WHERE JOURNAL_ID = <AccountNumber>
 	// return true if COUNT > 0
 	// return false if COUNT == 0
-	_, exist := InMemoryJournalTable[id]
+	_, exist := store.journalTable[id]
 	return exist, nil
 }
 
 // GetJournalByID retrieved a Journal information identified by its ID.
 // the provided ID must be exactly the same, not uses the LIKE select expression.
+// Journals that are not yet committed are not found.
 func (jm *InMemoryJournalManager) GetJournalByID(context context.Context, journalID string) (Journal, error) {
-	journalRecord, exist := InMemoryJournalTable[journalID]
-	if !exist {
+	store := jm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.getJournalByID(context, journalID)
+}
+
+// getJournalByID loads a committed journal and its transactions from the tables.
+// The caller must hold the store lock.
+func (store *InMemoryStore) getJournalByID(context context.Context, journalID string) (Journal, error) {
+	journalRecord, exist := store.journalTable[journalID]
+	if !exist || !journalRecord.committed {
 		return nil, ErrJournalIDNotFound
 	}
-	journal := jm.NewJournal(context).SetDescription(journalRecord.description).SetCreateTime(journalRecord.createTime).
+	journal := store.journalManager.NewJournal(context).SetDescription(journalRecord.description).SetCreateTime(journalRecord.createTime).
 		SetCreateBy(journalRecord.createBy).SetReversal(journalRecord.reversal).
-		SetJournalingTime(journalRecord.journalingTime).SetJournalID(journalRecord.journalID).SetAmount(journalRecord.amount)
+		SetJournalingTime(journalRecord.journalingTime).SetJournalID(journalRecord.journalID).SetAmount(journalRecord.amount).
+		SetIdempotencyKey(journalRecord.idempotencyKey).SetExchangeRate(journalRecord.exchangeRate)
 
 	if journalRecord.reversal {
-		reversed, err := jm.GetJournalByID(context, journalRecord.reversedJournalID)
+		reversed, err := store.getJournalByID(context, journalRecord.reversedJournalID)
 		if err != nil {
 			return nil, ErrJournalLoadReversalInconsistent
 		}
@@ -329,21 +657,9 @@ func (jm *InMemoryJournalManager) GetJournalByID(context context.Context, journa
 	// Populate all Transactions from DB.
 	transactions := make([]Transaction, 0)
 	// SELECT * FROM TRANSACTION WHERE JOURNAL_ID = {journalRecord.JournalID}
-	for _, trx := range InMemoryTransactionTable {
+	for _, trx := range store.transactionTable {
 		if trx.journalID == journalRecord.journalID {
-			transaction := &BaseTransaction{
-				TransactionID:   trx.transactionID,
-				TransactionTime: trx.transactionTime,
-				AccountNumber:   trx.accountNumber,
-				JournalID:       trx.journalID,
-				Description:     trx.description,
-				TransactionType: trx.transactionType,
-				Amount:          trx.amount,
-				AccountBalance:  trx.accountBalance,
-				CreateTime:      trx.createTime,
-				CreateBy:        trx.createBy,
-			}
-			transactions = append(transactions, transaction)
+			transactions = append(transactions, trx.toTransaction())
 		}
 	}
 
@@ -352,27 +668,52 @@ func (jm *InMemoryJournalManager) GetJournalByID(context context.Context, journa
 	return journal, nil
 }
 
+// GetJournalByIdempotencyKey retrieved the Journal created with the specified idempotency key.
+// Journals that are not yet committed are not found.
+func (jm *InMemoryJournalManager) GetJournalByIdempotencyKey(context context.Context, idempotencyKey string) (Journal, error) {
+	if len(idempotencyKey) == 0 {
+		return nil, ErrJournalIDNotFound
+	}
+
+	store := jm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	// SELECT JOURNAL_ID FROM JOURNAL WHERE IDEMPOTENCY_KEY = {idempotencyKey} AND COMMITTED = TRUE
+	for _, j := range store.journalTable {
+		if j.idempotencyKey == idempotencyKey && j.committed {
+			return store.getJournalByID(context, j.journalID)
+		}
+	}
+	return nil, ErrJournalIDNotFound
+}
+
 // ListJournals retrieve list of journals with transaction date between the `from` and `until` time range inclusive.
+// Journals that are not yet committed are not listed.
 // This function uses pagination.
 func (jm *InMemoryJournalManager) ListJournals(context context.Context, from time.Time, until time.Time, request PageRequest) (PageResult, []Journal, error) {
-	// SELECT COUNT(*) FROM JOURNAL WHERE JOURNALING_TIME < {until} AND JOURNALING_TIME > {from}
-	allResult := make([]*InMemoryJournalRecords, 0)
-	for _, j := range InMemoryJournalTable {
-		if j.journalingTime.After(from) && j.journalingTime.Before(until) {
-			allResult = append(allResult, j)
+	store := jm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	// SELECT COUNT(*) FROM JOURNAL WHERE JOURNALING_TIME <= {until} AND JOURNALING_TIME >= {from} AND COMMITTED = TRUE
+	allResult := make([]Journal, 0)
+	for _, j := range store.journalTable {
+		if j.committed && !j.journalingTime.Before(from) && !j.journalingTime.After(until) {
+			allResult = append(allResult, j.toJournalHeader())
 		}
 	}
 	count := len(allResult)
 	pageResult := PageResultFor(request, count)
 
-	// SELECT COUNT(*) FROM JOURNAL WHERE JOURNALING_TIME < {until} AND JOURNALING_TIME > {from} ORDER BY JOURNALING TIME LIMIT {pageResult.offset}, {pageResult.pageSize}
-	sort.SliceStable(allResult, func(i, j int) bool {
-		return allResult[i].journalingTime.Before(allResult[j].journalingTime)
-	})
+	// SELECT * FROM JOURNAL WHERE JOURNALING_TIME <= {until} AND JOURNALING_TIME >= {from} AND COMMITTED = TRUE ORDER BY {request.Sorts} LIMIT {pageResult.offset}, {pageResult.pageSize}
+	if err := journalSortRegistry.sortSlice(request, allResult); err != nil {
+		return PageResult{}, nil, err
+	}
 
 	journals := make([]Journal, pageResult.PageSize)
 	for i, r := range allResult[pageResult.Offset : pageResult.Offset+pageResult.PageSize] {
-		journal, err := jm.GetJournalByID(context, r.journalID)
+		journal, err := store.getJournalByID(context, r.GetJournalID())
 		if err != nil {
 			return PageResult{}, nil, err
 		}
@@ -381,6 +722,76 @@ func (jm *InMemoryJournalManager) ListJournals(context context.Context, from tim
 	return pageResult, journals, nil
 }
 
+// ListJournalsByCursor retrieve list of journals with transaction date between the `from` and `until` time range inclusive,
+// ordered by the sequence they were committed in, then by their journaling time and ID.
+// Journals that are not yet committed are not listed.
+// This function uses cursor pagination.
+func (jm *InMemoryJournalManager) ListJournalsByCursor(context context.Context, from time.Time, until time.Time, request CursorRequest) (CursorResult, []Journal, error) {
+	store := jm.getStore()
+	codec := store.getCursorCodec()
+	request, position, err := decodeCursorRequest(codec, journalCursorListing, request)
+	if err != nil {
+		return CursorResult{}, nil, err
+	}
+
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	// SELECT * FROM JOURNAL WHERE JOURNALING_TIME <= {until} AND JOURNALING_TIME >= {from} AND COMMITTED = TRUE AND {position keyset} ORDER BY COMMIT_SEQUENCE, JOURNALING_TIME, JOURNAL_ID LIMIT {request.ItemSize + 1}
+	records := make([]*InMemoryJournalRecords, 0)
+	for _, j := range store.journalTable {
+		if j.committed && !j.journalingTime.Before(from) && !j.journalingTime.After(until) && position.includes(j.cursorKey()) {
+			records = append(records, j)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return position.fetchedBefore(records[i].cursorKey(), records[j].cursorKey())
+	})
+	if len(records) > request.ItemSize+1 {
+		records = records[:request.ItemSize+1]
+	}
+
+	result, records := cursorPage(codec, journalCursorListing, request, position, records, (*InMemoryJournalRecords).cursorKey)
+	journals := make([]Journal, len(records))
+	for i, r := range records {
+		journal, err := store.getJournalByID(context, r.journalID)
+		if err != nil {
+			return CursorResult{}, nil, err
+		}
+		journals[i] = journal
+	}
+	return result, journals, nil
+}
+
+// StreamJournals streams all the journals with transaction date between the `from` and `until` time range inclusive,
+// ordered by the sequence they were committed in, then by their journaling time and ID, without loading them all at once.
+// Journals that are not yet committed are not streamed.
+func (jm *InMemoryJournalManager) StreamJournals(context context.Context, from time.Time, until time.Time) iter.Seq2[Journal, error] {
+	return streamCursor(context, func(request CursorRequest) (CursorResult, []Journal, error) {
+		return jm.ListJournalsByCursor(context, from, until, request)
+	})
+}
+
+// cursorKey returns the key the journal is listed by using cursors
+func (j *InMemoryJournalRecords) cursorKey() cursorKey {
+	return cursorKey{Sequence: j.commitSequence, Time: j.journalingTime, ID: j.journalID}
+}
+
+// toJournalHeader creates a new BaseJournal out of this record, without its Transactions and reversed journal.
+func (j *InMemoryJournalRecords) toJournalHeader() Journal {
+	return &BaseJournal{
+		JournalID:      j.journalID,
+		JournalingTime: j.journalingTime,
+		Description:    j.description,
+		Reversal:       j.reversal,
+		Amount:         j.amount,
+		CreateTime:     j.createTime,
+		CreatedBy:      j.createBy,
+		IdempotencyKey: j.idempotencyKey,
+		ExchangeRate:   j.exchangeRate,
+	}
+}
+
 // GetTotalDebit returns sum of all transaction in the DEBIT Alignment
 func GetTotalDebit(journal Journal) decimal.Decimal {
 	total := decimal.Zero
@@ -405,26 +816,39 @@ func GetTotalCredit(journal Journal) decimal.Decimal {
 
 // IsJournalIDReversed check if the journal with specified ID has been reversed
 func (jm *InMemoryJournalManager) IsJournalIDReversed(context context.Context, journalID string) (bool, error) {
+	store := jm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.isJournalIDReversed(journalID, false)
+}
+
+// isJournalIDReversed check if the committed journal with specified ID has been reversed.
+// If includePending is true, reversal journals that are not yet committed are also taken into account.
+// The caller must hold the store lock.
+func (store *InMemoryStore) isJournalIDReversed(journalID string, includePending bool) (bool, error) {
 	// SELECT COUNT(*) FROM JOURNAL WHERE REVERSED_JOURNAL_ID = {JournalID}
 	// return false if COUNT = 0
 	// return true if COUNT > 0
-	_, exist := InMemoryJournalTable[journalID]
-	if exist {
-		for _, j := range InMemoryJournalTable {
-			if j.reversedJournalID == journalID {
+	if journalRecord, exist := store.journalTable[journalID]; exist && journalRecord.committed {
+		for _, j := range store.journalTable {
+			if j.reversedJournalID == journalID && (j.committed || includePending) {
 				return true, nil
 			}
 		}
 		return false, nil
 	}
-	// todo emit error logs just before returning with errors.
+	logrus.Errorf("error checking journal reversal. journal %s not found", journalID)
 	return false, ErrJournalIDNotFound
-
 }
 
 // RenderJournal will render this journal into string for easy inspection
 func (jm *InMemoryJournalManager) RenderJournal(context context.Context, journal Journal) string {
+	return renderJournal(journal)
+}
 
+// renderJournal will render the journal into string for easy inspection
+func renderJournal(journal Journal) string {
 	var buff bytes.Buffer
 	table := tablewriter.NewWriter(&buff)
 	table.SetHeader([]string{"TRX ID", "Account", "Description", "DEBIT", "CREDIT"})
@@ -449,6 +873,15 @@ func (jm *InMemoryJournalManager) RenderJournal(context context.Context, journal
 
 // InMemoryAccountManager implementation of AccountManager using inmemory Account table map
 type InMemoryAccountManager struct {
+	store *InMemoryStore
+}
+
+// getStore returns the store this manager is bound to, or the default store if not bound to any.
+func (am *InMemoryAccountManager) getStore() *InMemoryStore {
+	if am.store == nil {
+		return defaultInMemoryStore
+	}
+	return am.store
 }
 
 // NewAccount will create a new blank un-persisted account.
@@ -471,13 +904,17 @@ func (am *InMemoryAccountManager) PersistAccount(context context.Context, Accoun
 	if len(AccountToPersist.GetCreateBy()) == 0 {
 		return ErrAccountMissingCreator
 	}
-
-	// First make sure that The account have never been created in DB.
-	exist, err := am.IsAccountIDExist(context, AccountToPersist.GetAccountNumber())
-	if err != nil {
+	if err := validateOverdraftLimit(AccountToPersist); err != nil {
 		return err
 	}
-	if exist {
+
+	store := am.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// First make sure that The account have never been created in DB.
+	// SELECT COUNT(*) FROM ACCOUNT WHERE ACCOUNT_NUMBER = {AccountNumber}
+	if _, exist := store.accountTable[AccountToPersist.GetAccountNumber()]; exist {
 		return ErrAccountAlreadyPersisted
 	}
 
@@ -488,20 +925,27 @@ func (am *InMemoryAccountManager) PersistAccount(context context.Context, Accoun
 		description:         AccountToPersist.GetDescription(),
 		baseTransactionType: AccountToPersist.GetAlignment(),
 		balance:             AccountToPersist.GetBalance(),
+		balanceLimit:        AccountToPersist.GetBalanceLimit(),
+		overdraftLimit:      AccountToPersist.GetOverdraftLimit(),
+		state:               AccountActive,
 		coa:                 AccountToPersist.GetCOA(),
 		createTime:          time.Now(),
 		createBy:            AccountToPersist.GetCreateBy(),
 		updateTime:          time.Now(),
 		updateBy:            AccountToPersist.GetUpdateBy(),
+		version:             1,
 	}
 
-	InMemoryAccountTable[accountRecord.id] = accountRecord
+	store.accountTable[accountRecord.id] = accountRecord
+	AccountToPersist.SetVersion(accountRecord.version).SetState(accountRecord.state)
 
 	return nil
 }
 
 // UpdateAccount will update the account database to reflect to the provided account information.
 // This update account function will fail if the account ID/number is not existing in the database.
+// It will fail with ErrAccountConcurrentModification if the account have been modified since it was read.
+// The Balance, Currency and Alignment of the account are not written, the Balance only moves by committing journals.
 func (am *InMemoryAccountManager) UpdateAccount(context context.Context, AccountToUpdate Account) error {
 	if len(AccountToUpdate.GetAccountNumber()) == 0 {
 		return ErrAccountMissingID
@@ -515,172 +959,371 @@ func (am *InMemoryAccountManager) UpdateAccount(context context.Context, Account
 	if len(AccountToUpdate.GetCreateBy()) == 0 {
 		return ErrAccountMissingCreator
 	}
-
-	// First make sure that The account have never been created in DB.
-	exist, err := am.IsAccountIDExist(context, AccountToUpdate.GetAccountNumber())
-	if err != nil {
+	if err := validateOverdraftLimit(AccountToUpdate); err != nil {
 		return err
 	}
+
+	store := am.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// First make sure that The account have been created in DB.
+	// SELECT * FROM ACCOUNT WHERE ACCOUNT_NUMBER = {AccountNumber}
+	existing, exist := store.accountTable[AccountToUpdate.GetAccountNumber()]
 	if !exist {
 		return ErrAccountIsNotPersisted
 	}
+	// Then make sure nobody have modified the account since it was read.
+	if existing.version != AccountToUpdate.GetVersion() {
+		logrus.Errorf("error updating account %s. account version %d is not the current version %d", AccountToUpdate.GetAccountNumber(), AccountToUpdate.GetVersion(), existing.version)
+		return ErrAccountConcurrentModification
+	}
 
+	// UPDATE ACCOUNT SET ..., VERSION = VERSION + 1 WHERE ACCOUNT_NUMBER = {AccountNumber} AND VERSION = {Version}
 	accountRecord := &InMemoryAccountRecord{
-		currency:            AccountToUpdate.GetCurrency(),
+		currency:            existing.currency,
 		id:                  AccountToUpdate.GetAccountNumber(),
 		name:                AccountToUpdate.GetName(),
 		description:         AccountToUpdate.GetDescription(),
-		baseTransactionType: AccountToUpdate.GetAlignment(),
-		balance:             AccountToUpdate.GetBalance(),
+		baseTransactionType: existing.baseTransactionType,
+		balance:             existing.balance,
+		balanceLimit:        AccountToUpdate.GetBalanceLimit(),
+		overdraftLimit:      AccountToUpdate.GetOverdraftLimit(),
+		state:               existing.state,
 		coa:                 AccountToUpdate.GetCOA(),
-		createTime:          time.Now(),
-		createBy:            AccountToUpdate.GetCreateBy(),
+		createTime:          existing.createTime,
+		createBy:            existing.createBy,
 		updateTime:          time.Now(),
 		updateBy:            AccountToUpdate.GetUpdateBy(),
+		version:             existing.version + 1,
 	}
 
-	InMemoryAccountTable[accountRecord.id] = accountRecord
+	store.accountTable[accountRecord.id] = accountRecord
+	AccountToUpdate.SetVersion(accountRecord.version)
 
 	return nil
 }
 
 // IsAccountIDExist will check if an account ID/number is exist in the database.
 func (am *InMemoryAccountManager) IsAccountIDExist(context context.Context, id string) (bool, error) {
+	store := am.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
 	// SELECT COUNT(*) FROM ACCOUNT WHERE ACCOUNT_NUMBER = {AccountNumber}
-	_, exist := InMemoryAccountTable[id]
+	_, exist := store.accountTable[id]
 	return exist, nil
 }
 
 // GetAccountByID retrieve an account information by specifying the ID/number
 func (am *InMemoryAccountManager) GetAccountByID(context context.Context, id string) (Account, error) {
-	accountRecord, exist := InMemoryAccountTable[id]
+	store := am.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	accountRecord, exist := store.accountTable[id]
 	if !exist {
 		return nil, ErrAccountIDNotFound
 	}
-	return &BaseAccount{
-		Currency:      accountRecord.currency,
-		AccountNumber: accountRecord.id,
-		Name:          accountRecord.name,
-		Description:   accountRecord.description,
-		Alignment:     accountRecord.baseTransactionType,
-		Balance:       accountRecord.balance,
-		COA:           accountRecord.coa,
-		CreateTime:    accountRecord.createTime,
-		CreateBy:      accountRecord.createBy,
-		UpdateTime:    accountRecord.updateTime,
-		UpdateBy:      accountRecord.updateBy,
-	}, nil
+	return accountRecord.toAccount(), nil
 }
 
 // ListAccounts list all account in the database.
 // This function uses pagination
 func (am *InMemoryAccountManager) ListAccounts(context context.Context, request PageRequest) (PageResult, []Account, error) {
-	resultSlice := make([]*InMemoryAccountRecord, 0)
-	for _, r := range InMemoryAccountTable {
-		resultSlice = append(resultSlice, r)
-	}
-	sort.SliceStable(resultSlice, func(i, j int) bool {
-		return resultSlice[i].createTime.Before(resultSlice[j].createTime)
+	return am.listAccounts(request, func(r *InMemoryAccountRecord) bool {
+		return true
 	})
+}
 
-	pageResult := PageResultFor(request, len(resultSlice))
-	accounts := make([]Account, pageResult.PageSize)
-
-	for i, s := range resultSlice[pageResult.Offset : pageResult.Offset+pageResult.PageSize] {
-		bacc := &BaseAccount{
-			Currency:      s.currency,
-			AccountNumber: s.id,
-			Name:          s.name,
-			Description:   s.description,
-			Alignment:     s.baseTransactionType,
-			Balance:       s.balance,
-			COA:           s.coa,
-			CreateTime:    s.createTime,
-			CreateBy:      s.createBy,
-			UpdateTime:    s.updateTime,
-			UpdateBy:      s.updateBy,
+// StreamAccounts streams all account in the database ordered by their account number, without loading them all at once.
+func (am *InMemoryAccountManager) StreamAccounts(context context.Context) iter.Seq2[Account, error] {
+	return streamAccounts(context, func(afterAccountNumber string, limit int) ([]Account, error) {
+		store := am.getStore()
+		store.mutex.RLock()
+		defer store.mutex.RUnlock()
+
+		// SELECT * FROM ACCOUNT WHERE ACCOUNT_NUMBER > {afterAccountNumber} ORDER BY ACCOUNT_NUMBER LIMIT {limit}
+		accountNumbers := make([]string, 0)
+		for accountNumber := range store.accountTable {
+			if accountNumber > afterAccountNumber {
+				accountNumbers = append(accountNumbers, accountNumber)
+			}
 		}
-		accounts[i] = bacc
-	}
-
-	return pageResult, accounts, nil
+		sort.Strings(accountNumbers)
+		if len(accountNumbers) > limit {
+			accountNumbers = accountNumbers[:limit]
+		}
+		accounts := make([]Account, len(accountNumbers))
+		for i, accountNumber := range accountNumbers {
+			accounts[i] = store.accountTable[accountNumber].toAccount()
+		}
+		return accounts, nil
+	})
 }
 
 // ListAccountByCOA returns list of accounts that have the same COA number.
 // This function uses pagination
 func (am *InMemoryAccountManager) ListAccountByCOA(context context.Context, coa string, request PageRequest) (PageResult, []Account, error) {
-	resultSlice := make([]*InMemoryAccountRecord, 0)
-	for _, r := range InMemoryAccountTable {
-		if r.coa == coa {
-			resultSlice = append(resultSlice, r)
+	return am.listAccounts(request, func(r *InMemoryAccountRecord) bool {
+		return r.coa == coa
+	})
+}
+
+// FindAccounts returns list of accounts that have their Name contains a substring of specified parameter.
+// this search should  be case insensitive.
+func (am *InMemoryAccountManager) FindAccounts(context context.Context, nameLike string, request PageRequest) (PageResult, []Account, error) {
+	lookup := strings.ToUpper(strings.ReplaceAll(nameLike, "%", ""))
+	return am.listAccounts(request, func(r *InMemoryAccountRecord) bool {
+		return strings.Contains(strings.ToUpper(r.name), lookup)
+	})
+}
+
+// ChangeAccountState moves the account into the new state, and records the change with its reason and author for audit.
+// A closed account can not change its state anymore, and an account can only be closed once its Balance is zero.
+// Changing the state increments the account version. Changing into the current state does nothing.
+func (am *InMemoryAccountManager) ChangeAccountState(context context.Context, accountNumber string, state AccountState, reason, changedBy string) error {
+	if len(changedBy) == 0 {
+		return ErrAccountMissingUpdater
+	}
+
+	store := am.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// SELECT * FROM ACCOUNT WHERE ACCOUNT_NUMBER = {accountNumber} FOR UPDATE
+	accountRecord, exist := store.accountTable[accountNumber]
+	if !exist {
+		return ErrAccountIDNotFound
+	}
+	if accountRecord.state == state {
+		return nil
+	}
+	if err := validateStateChange(accountRecord.state, state, accountRecord.balance); err != nil {
+		logrus.Errorf("error changing account %s state from %s into %s. got %s", accountNumber, accountRecord.state, state, err.Error())
+		return err
+	}
+
+	// UPDATE ACCOUNT SET STATE = {state}, UPDATE_TIME = {now}, UPDATE_BY = {changedBy}, VERSION = VERSION + 1 WHERE ACCOUNT_NUMBER = {accountNumber}
+	now := time.Now()
+	change := &AccountStateChange{
+		AccountNumber: accountNumber,
+		From:          accountRecord.state,
+		To:            state,
+		Reason:        reason,
+		Version:       accountRecord.version + 1,
+		ChangeTime:    now,
+		ChangeBy:      changedBy,
+	}
+	accountRecord.state = state
+	accountRecord.updateTime = now
+	accountRecord.updateBy = changedBy
+	accountRecord.version++
+
+	// INSERT INTO ACCOUNT_STATE_CHANGE ...
+	store.accountStateTable[accountNumber] = append(store.accountStateTable[accountNumber], change)
+	return nil
+}
+
+// ListAccountStateChanges returns all the state changes of the account, oldest first.
+func (am *InMemoryAccountManager) ListAccountStateChanges(context context.Context, accountNumber string) ([]*AccountStateChange, error) {
+	store := am.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if _, exist := store.accountTable[accountNumber]; !exist {
+		return nil, ErrAccountIDNotFound
+	}
+	changes := make([]*AccountStateChange, 0, len(store.accountStateTable[accountNumber]))
+	for _, change := range store.accountStateTable[accountNumber] {
+		copied := *change
+		changes = append(changes, &copied)
+	}
+	return changes, nil
+}
+
+// listAccounts list the accounts matching the filter, ordered following the request sorts.
+func (am *InMemoryAccountManager) listAccounts(request PageRequest, filter func(r *InMemoryAccountRecord) bool) (PageResult, []Account, error) {
+	store := am.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	resultSlice := make([]Account, 0)
+	for _, r := range store.accountTable {
+		if filter(r) {
+			resultSlice = append(resultSlice, r.toAccount())
 		}
 	}
-	sort.SliceStable(resultSlice, func(i, j int) bool {
-		return resultSlice[i].createTime.Before(resultSlice[j].createTime)
-	})
+	if err := accountSortRegistry.sortSlice(request, resultSlice); err != nil {
+		return PageResult{}, nil, err
+	}
 
 	pageResult := PageResultFor(request, len(resultSlice))
-	accounts := make([]Account, pageResult.PageSize)
+	return pageResult, resultSlice[pageResult.Offset : pageResult.Offset+pageResult.PageSize], nil
+}
 
-	for i, s := range resultSlice[pageResult.Offset : pageResult.Offset+pageResult.PageSize] {
-		bacc := &BaseAccount{
-			Currency:      s.currency,
-			AccountNumber: s.id,
-			Name:          s.name,
-			Description:   s.description,
-			Alignment:     s.baseTransactionType,
-			Balance:       s.balance,
-			COA:           s.coa,
-			CreateTime:    s.createTime,
-			CreateBy:      s.createBy,
-			UpdateTime:    s.updateTime,
-			UpdateBy:      s.updateBy,
-		}
-		accounts[i] = bacc
+// toAccount creates a new BaseAccount out of this record.
+func (r *InMemoryAccountRecord) toAccount() Account {
+	return &BaseAccount{
+		Currency:      r.currency,
+		AccountNumber: r.id,
+		Name:          r.name,
+		Description:   r.description,
+		Alignment:     r.baseTransactionType,
+		Balance:       r.balance,
+		BalanceLimit:  r.balanceLimit,
+		Overdraft:     r.overdraftLimit,
+		State:         r.state,
+		COA:           r.coa,
+		CreateTime:    r.createTime,
+		CreateBy:      r.createBy,
+		UpdateTime:    r.updateTime,
+		UpdateBy:      r.updateBy,
+		Version:       r.version,
 	}
+}
 
-	return pageResult, accounts, nil
+// InMemoryCOAManager implementation of COAManager using inmemory COA table map
+type InMemoryCOAManager struct {
+	store *InMemoryStore
 }
 
-// FindAccounts returns list of accounts that have their Name contains a substring of specified parameter.
-// this search should  be case insensitive.
-func (am *InMemoryAccountManager) FindAccounts(context context.Context, nameLike string, request PageRequest) (PageResult, []Account, error) {
-	resultSlice := make([]*InMemoryAccountRecord, 0)
-	lookup := strings.ToUpper(strings.ReplaceAll(nameLike, "%", ""))
-	for _, r := range InMemoryAccountTable {
-		if strings.Contains(strings.ToUpper(r.name), lookup) {
-			resultSlice = append(resultSlice, r)
+// getStore returns the store this manager is bound to, or the default store if not bound to any.
+func (cm *InMemoryCOAManager) getStore() *InMemoryStore {
+	if cm.store == nil {
+		return defaultInMemoryStore
+	}
+	return cm.store
+}
+
+// NewCOA will create a new blank un-persisted COA.
+func (cm *InMemoryCOAManager) NewCOA(context context.Context) COA {
+	return &BaseCOA{}
+}
+
+// PersistCOA will save the COA into database.
+// will throw error if the COA already persisted, if its parent is not persisted,
+// or if its category is not the same as its parent category.
+func (cm *InMemoryCOAManager) PersistCOA(context context.Context, coaToPersist COA) error {
+	if len(coaToPersist.GetCode()) == 0 {
+		return ErrCOAMissingCode
+	}
+	if len(coaToPersist.GetName()) == 0 {
+		return ErrCOAMissingName
+	}
+	if len(coaToPersist.GetCreateBy()) == 0 {
+		return ErrCOAMissingCreator
+	}
+
+	store := cm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// SELECT COUNT(*) FROM COA WHERE CODE = {code}
+	if _, exist := store.coaTable[coaToPersist.GetCode()]; exist {
+		return ErrCOAAlreadyPersisted
+	}
+	if len(coaToPersist.GetParentCode()) > 0 {
+		// SELECT CATEGORY FROM COA WHERE CODE = {parentCode}
+		parent, exist := store.coaTable[coaToPersist.GetParentCode()]
+		if !exist {
+			logrus.Errorf("error persisting COA %s. parent %s not found", coaToPersist.GetCode(), coaToPersist.GetParentCode())
+			return ErrCOAParentNotFound
+		}
+		if parent.category != coaToPersist.GetCategory() {
+			logrus.Errorf("error persisting COA %s. category %d is not the parent %s category %d", coaToPersist.GetCode(), coaToPersist.GetCategory(), parent.code, parent.category)
+			return ErrCOACategoryMismatch
 		}
 	}
-	sort.SliceStable(resultSlice, func(i, j int) bool {
-		return resultSlice[i].createTime.Before(resultSlice[j].createTime)
-	})
 
-	pageResult := PageResultFor(request, len(resultSlice))
-	accounts := make([]Account, pageResult.PageSize)
+	store.coaTable[coaToPersist.GetCode()] = &InMemoryCOARecord{
+		code:        coaToPersist.GetCode(),
+		parentCode:  coaToPersist.GetParentCode(),
+		name:        coaToPersist.GetName(),
+		description: coaToPersist.GetDescription(),
+		category:    coaToPersist.GetCategory(),
+		alignment:   coaToPersist.GetAlignment(),
+		createTime:  time.Now(),
+		createBy:    coaToPersist.GetCreateBy(),
+	}
+	return nil
+}
 
-	for i, s := range resultSlice[pageResult.Offset : pageResult.Offset+pageResult.PageSize] {
-		bacc := &BaseAccount{
-			Currency:      s.currency,
-			AccountNumber: s.id,
-			Name:          s.name,
-			Description:   s.description,
-			Alignment:     s.baseTransactionType,
-			Balance:       s.balance,
-			COA:           s.coa,
-			CreateTime:    s.createTime,
-			CreateBy:      s.createBy,
-			UpdateTime:    s.updateTime,
-			UpdateBy:      s.updateBy,
+// IsCOAExist will check if a COA code is exist in the database.
+func (cm *InMemoryCOAManager) IsCOAExist(context context.Context, code string) (bool, error) {
+	store := cm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	_, exist := store.coaTable[code]
+	return exist, nil
+}
+
+// GetCOAByCode retrieve a COA information by specifying its code
+func (cm *InMemoryCOAManager) GetCOAByCode(context context.Context, code string) (COA, error) {
+	store := cm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	coaRecord, exist := store.coaTable[code]
+	if !exist {
+		return nil, ErrCOANotFound
+	}
+	return coaRecord.toCOA(), nil
+}
+
+// ListCOAChildren returns the direct children of the COA with the specified code, ordered by their code.
+// An empty parent code lists the roots of the chart.
+func (cm *InMemoryCOAManager) ListCOAChildren(context context.Context, parentCode string) ([]COA, error) {
+	store := cm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if _, exist := store.coaTable[parentCode]; len(parentCode) > 0 && !exist {
+		return nil, ErrCOANotFound
+	}
+	// SELECT * FROM COA WHERE PARENT_CODE = {parentCode} ORDER BY CODE
+	children := make([]*InMemoryCOARecord, 0)
+	for _, coaRecord := range store.coaTable {
+		if coaRecord.parentCode == parentCode {
+			children = append(children, coaRecord)
 		}
-		accounts[i] = bacc
 	}
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].code < children[j].code
+	})
+	ret := make([]COA, len(children))
+	for i, coaRecord := range children {
+		ret[i] = coaRecord.toCOA()
+	}
+	return ret, nil
+}
 
-	return pageResult, accounts, nil
+// toCOA creates a new BaseCOA out of this record.
+func (r *InMemoryCOARecord) toCOA() COA {
+	return &BaseCOA{
+		Code:        r.code,
+		ParentCode:  r.parentCode,
+		Name:        r.name,
+		Description: r.description,
+		Category:    r.category,
+		Alignment:   r.alignment,
+		CreateTime:  r.createTime,
+		CreateBy:    r.createBy,
+	}
 }
 
 // InMemoryTransactionManager implementation of TransactionManager using inmemory Account table map
 type InMemoryTransactionManager struct {
+	store *InMemoryStore
+}
+
+// getStore returns the store this manager is bound to, or the default store if not bound to any.
+func (tm *InMemoryTransactionManager) getStore() *InMemoryStore {
+	if tm.store == nil {
+		return defaultInMemoryStore
+	}
+	return tm.store
 }
 
 // NewTransaction will create new blank un-persisted Transaction
@@ -689,66 +1332,153 @@ func (tm *InMemoryTransactionManager) NewTransaction(context context.Context) Tr
 }
 
 // IsTransactionIDExist will check if an Transaction ID/number is exist in the database.
+// Transactions that are not yet committed are also taken into account, as their ID is already taken.
 func (tm *InMemoryTransactionManager) IsTransactionIDExist(context context.Context, id string) (bool, error) {
-	_, exist := InMemoryTransactionTable[id]
+	store := tm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	_, exist := store.transactionTable[id]
 	return exist, nil
 }
 
 // GetTransactionByID will retrieve one single transaction that identified by some ID
+// Transactions that are not yet committed are not found.
 func (tm *InMemoryTransactionManager) GetTransactionByID(context context.Context, id string) (Transaction, error) {
-	trx, exist := InMemoryTransactionTable[id]
-	if !exist {
+	store := tm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	trx, exist := store.transactionTable[id]
+	if !exist || !trx.committed {
 		return nil, ErrTransactionNotFound
 	}
-	transaction := &BaseTransaction{
-		TransactionID:   trx.transactionID,
-		TransactionTime: trx.transactionTime,
-		AccountNumber:   trx.accountNumber,
-		JournalID:       trx.journalID,
-		Description:     trx.description,
-		TransactionType: trx.transactionType,
-		Amount:          trx.amount,
-		AccountBalance:  trx.accountBalance,
-		CreateTime:      trx.createTime,
-		CreateBy:        trx.createBy,
-	}
-
-	return transaction, nil
+	return trx.toTransaction(), nil
 }
 
 // ListTransactionsOnAccount retrieves list of Transactions that belongs to this account
-// that transaction happens between the `from` and `until` time range.
+// that transaction happens between the `from` and `until` time range inclusive.
+// Transactions that are not yet committed are not listed.
 // This function uses pagination
 func (tm *InMemoryTransactionManager) ListTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account, request PageRequest) (PageResult, []Transaction, error) {
-	resultRecord := make([]*InMemoryTransactionRecords, 0)
-	for _, trx := range InMemoryTransactionTable {
-		if trx.accountNumber == account.GetAccountNumber() {
-			resultRecord = append(resultRecord, trx)
+	store := tm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	// SELECT * FROM TRANSACTION WHERE ACCOUNT_NUMBER = {account.GetAccountNumber()} AND TRANSACTION_TIME >= {from} AND TRANSACTION_TIME <= {until} AND COMMITTED = TRUE ORDER BY {request.Sorts}
+	resultRecord := make([]Transaction, 0)
+	for _, trx := range store.transactionTable {
+		if trx.committed && trx.accountNumber == account.GetAccountNumber() &&
+			!trx.transactionTime.Before(from) && !trx.transactionTime.After(until) {
+			resultRecord = append(resultRecord, trx.toTransaction())
 		}
 	}
-	sort.SliceStable(resultRecord, func(i, j int) bool {
-		return resultRecord[i].createTime.Before(resultRecord[j].createTime)
-	})
+	if err := transactionSortRegistry.sortSlice(request, resultRecord); err != nil {
+		return PageResult{}, nil, err
+	}
 
 	pageResult := PageResultFor(request, len(resultRecord))
+	return pageResult, resultRecord[pageResult.Offset : pageResult.Offset+pageResult.PageSize], nil
+}
+
+// ListTransactionsOnAccountByCursor retrieves list of Transactions that belongs to this account
+// that transaction happens between the `from` and `until` time range inclusive, ordered by the sequence they were committed in,
+// then by their transaction time and ID.
+// Transactions that are not yet committed are not listed.
+// This function uses cursor pagination.
+func (tm *InMemoryTransactionManager) ListTransactionsOnAccountByCursor(context context.Context, from time.Time, until time.Time, account Account, request CursorRequest) (CursorResult, []Transaction, error) {
+	store := tm.getStore()
+	listing := transactionCursorListing(account)
+	codec := store.getCursorCodec()
+	request, position, err := decodeCursorRequest(codec, listing, request)
+	if err != nil {
+		return CursorResult{}, nil, err
+	}
 
-	transactions := make([]Transaction, len(resultRecord))
-	for idx, trx := range resultRecord {
-		transaction := &BaseTransaction{
-			TransactionID:   trx.transactionID,
-			TransactionTime: trx.transactionTime,
-			AccountNumber:   trx.accountNumber,
-			JournalID:       trx.journalID,
-			Description:     trx.description,
-			TransactionType: trx.transactionType,
-			Amount:          trx.amount,
-			AccountBalance:  trx.accountBalance,
-			CreateTime:      trx.createTime,
-			CreateBy:        trx.createBy,
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	// SELECT * FROM TRANSACTION WHERE ACCOUNT_NUMBER = {account.GetAccountNumber()} AND TRANSACTION_TIME >= {from} AND TRANSACTION_TIME <= {until} AND COMMITTED = TRUE AND {position keyset} ORDER BY COMMIT_SEQUENCE, TRANSACTION_TIME, TRANSACTION_ID LIMIT {request.ItemSize + 1}
+	records := make([]*InMemoryTransactionRecords, 0)
+	for _, trx := range store.transactionTable {
+		if trx.committed && trx.accountNumber == account.GetAccountNumber() &&
+			!trx.transactionTime.Before(from) && !trx.transactionTime.After(until) && position.includes(trx.cursorKey()) {
+			records = append(records, trx)
 		}
-		transactions[idx] = transaction
 	}
-	return pageResult, transactions, nil
+	sort.Slice(records, func(i, j int) bool {
+		return position.fetchedBefore(records[i].cursorKey(), records[j].cursorKey())
+	})
+	if len(records) > request.ItemSize+1 {
+		records = records[:request.ItemSize+1]
+	}
+
+	result, records := cursorPage(codec, listing, request, position, records, (*InMemoryTransactionRecords).cursorKey)
+	transactions := make([]Transaction, len(records))
+	for i, trx := range records {
+		transactions[i] = trx.toTransaction()
+	}
+	return result, transactions, nil
+}
+
+// StreamTransactionsOnAccount streams all the Transactions that belongs to this account
+// that transaction happens between the `from` and `until` time range inclusive, ordered by the sequence they were committed in,
+// then by their transaction time and ID, without loading them all at once.
+// Transactions that are not yet committed are not streamed.
+func (tm *InMemoryTransactionManager) StreamTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account) iter.Seq2[Transaction, error] {
+	return streamCursor(context, func(request CursorRequest) (CursorResult, []Transaction, error) {
+		return tm.ListTransactionsOnAccountByCursor(context, from, until, account, request)
+	})
+}
+
+// GetBalanceAt retrieves the Balance of the account at the specified time, which is the account Balance
+// recorded on its last committed transaction at or before that time.
+// The Balance is zero if the account have no transaction up to that time.
+func (tm *InMemoryTransactionManager) GetBalanceAt(context context.Context, accountNumber string, at time.Time) (decimal.Decimal, error) {
+	balances, err := tm.GetBalancesAt(context, []string{accountNumber}, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return balances[accountNumber], nil
+}
+
+// GetBalancesAt retrieves the Balance of many accounts at the specified time, keyed by their account number.
+// All the balances are taken from the same snapshot of the store.
+func (tm *InMemoryTransactionManager) GetBalancesAt(context context.Context, accountNumbers []string, at time.Time) (map[string]decimal.Decimal, error) {
+	store := tm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	latest := make(map[string]*InMemoryTransactionRecords, len(accountNumbers))
+	for _, accountNumber := range accountNumbers {
+		if _, exist := store.accountTable[accountNumber]; !exist {
+			logrus.Errorf("error getting balance of account %s. account not found.", accountNumber)
+			return nil, ErrAccountIDNotFound
+		}
+		latest[accountNumber] = nil
+	}
+
+	// SELECT ACCOUNT_BALANCE FROM TRANSACTION WHERE ACCOUNT_NUMBER = {accountNumber} AND TRANSACTION_TIME <= {at} AND COMMITTED = TRUE
+	// ORDER BY TRANSACTION_TIME DESC, COMMIT_SEQUENCE DESC, TRANSACTION_ID DESC LIMIT 1
+	for _, trx := range store.transactionTable {
+		last, wanted := latest[trx.accountNumber]
+		if !wanted || !trx.committed || trx.transactionTime.After(at) {
+			continue
+		}
+		if last == nil || trx.balancedAfter(last) {
+			latest[trx.accountNumber] = trx
+		}
+	}
+
+	balances := make(map[string]decimal.Decimal, len(latest))
+	for accountNumber, trx := range latest {
+		if trx == nil {
+			balances[accountNumber] = decimal.Zero
+		} else {
+			balances[accountNumber] = trx.accountBalance
+		}
+	}
+	return balances, nil
 }
 
 // RenderTransactionsOnAccount Render list of transaction been down on an account in a time span
@@ -759,13 +1489,18 @@ func (tm *InMemoryTransactionManager) RenderTransactionsOnAccount(context contex
 		return "Error rendering", err
 	}
 
+	return renderTransactionsOnAccount(from, until, account, result, transactions), nil
+}
+
+// renderTransactionsOnAccount will render a page of transactions on an account into string for easy inspection
+func renderTransactionsOnAccount(from time.Time, until time.Time, account Account, result PageResult, transactions []Transaction) string {
 	var buff bytes.Buffer
 	table := tablewriter.NewWriter(&buff)
 	table.SetHeader([]string{"TRX ID", "TIME", "JOURNAL ID", "Description", "DEBIT", "CREDIT", "BALANCE"})
 
 	for _, t := range transactions {
 		if t.GetAlignment() == DEBIT {
-			table.Append([]string{t.GetTransactionID(), t.GetTransactionTime().String(), t.GetJournalID(), t.GetDescription(), "%s", t.GetAmount().String(), "", "%s", t.GetAccountBalance().String()})
+			table.Append([]string{t.GetTransactionID(), t.GetTransactionTime().String(), t.GetJournalID(), t.GetDescription(), t.GetAmount().String(), "", t.GetAccountBalance().String()})
 		}
 		if t.GetAlignment() == CREDIT {
 			table.Append([]string{t.GetTransactionID(), t.GetTransactionTime().String(), t.GetJournalID(), t.GetDescription(), "", t.GetAmount().String(), t.GetAccountBalance().String()})
@@ -782,52 +1517,87 @@ func (tm *InMemoryTransactionManager) RenderTransactionsOnAccount(context contex
 	buff.WriteString(fmt.Sprintf("#Transactions     : %d\n", result.TotalEntries))
 	buff.WriteString(fmt.Sprintf("Showing page      : %d/%d\n", result.Page, result.TotalPages))
 	table.Render()
-	return buff.String(), err
+	return buff.String()
 }
 
-// NewInMemoryExchangeManager initializes a new excahnge manager in memory
-func NewInMemoryExchangeManager() ExchangeManager {
-	return &InMemoryExchangeManager{
-		commonDenominator: decimal.NewFromInt(1),
+// cursorKey returns the key the transaction is listed by using cursors
+func (trx *InMemoryTransactionRecords) cursorKey() cursorKey {
+	return cursorKey{Sequence: trx.commitSequence, Time: trx.transactionTime, ID: trx.transactionID}
+}
+
+// toTransaction creates a new BaseTransaction out of this record.
+func (trx *InMemoryTransactionRecords) toTransaction() Transaction {
+	return &BaseTransaction{
+		TransactionID:   trx.transactionID,
+		TransactionTime: trx.transactionTime,
+		AccountNumber:   trx.accountNumber,
+		JournalID:       trx.journalID,
+		Description:     trx.description,
+		TransactionType: trx.transactionType,
+		Amount:          trx.amount,
+		AccountBalance:  trx.accountBalance,
+		CreateTime:      trx.createTime,
+		CreateBy:        trx.createBy,
 	}
 }
 
+// NewInMemoryExchangeManager initializes a new excahnge manager bound to the default in-memory store.
+// Use InMemoryStore.GetExchangeManager to obtain an exchange manager of an isolated store.
+func NewInMemoryExchangeManager() ExchangeManager {
+	return &InMemoryExchangeManager{}
+}
+
 // InMemoryExchangeManager is a base implementation of ExchangeManager.
 type InMemoryExchangeManager struct {
-	commonDenominator decimal.Decimal
+	store *InMemoryStore
+}
+
+// getStore returns the store this manager is bound to, or the default store if not bound to any.
+func (em *InMemoryExchangeManager) getStore() *InMemoryStore {
+	if em.store == nil {
+		return defaultInMemoryStore
+	}
+	return em.store
 }
 
 // IsCurrencyExist will check in the exchange system for a Currency existance
 // non-existent Currency means that the Currency is not supported.
 // error should be thrown if only there's an underlying error such as db error.
 func (em *InMemoryExchangeManager) IsCurrencyExist(context context.Context, currency string) (bool, error) {
-	_, exist := InMemoryCurrencyTable[currency]
+	store := em.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	_, exist := store.currencyTable[currency]
 	return exist, nil
 }
 
 // GetDenom get the current common denominator used in the exchange
 func (em *InMemoryExchangeManager) GetDenom(context context.Context) decimal.Decimal {
-	return em.commonDenominator
+	store := em.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.commonDenominator
 }
 
 // SetDenom set the current common denominator value into the specified value
 func (em *InMemoryExchangeManager) SetDenom(context context.Context, denom decimal.Decimal) {
-	em.commonDenominator = denom
+	store := em.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.commonDenominator = denom
 }
 
 // GetCurrency retrieve currency data indicated by the code argument
 func (em *InMemoryExchangeManager) GetCurrency(context context.Context, code string) (Currency, error) {
-	if curRec, exist := InMemoryCurrencyTable[code]; exist {
-		cur := &BaseCurrency{
-			Code:       curRec.code,
-			Name:       curRec.name,
-			Exchange:   curRec.exchange,
-			CreateTime: curRec.createTime,
-			CreateBy:   curRec.createBy,
-			UpdateTime: curRec.updateTime,
-			UpdateBy:   curRec.updateBy,
-		}
-		return cur, nil
+	store := em.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if curRec, exist := store.currencyTable[code]; exist {
+		return curRec.toCurrency(), nil
 	}
 	return nil, ErrCurrencyNotFound
 
@@ -836,95 +1606,474 @@ func (em *InMemoryExchangeManager) GetCurrency(context context.Context, code str
 // CreateCurrency set the specified value as denominator value for that speciffic Currency.
 // This function should return error if the Currency specified is not exist.
 func (em *InMemoryExchangeManager) CreateCurrency(context context.Context, code, name string, exchange decimal.Decimal, author string) (Currency, error) {
-	if _, exist := InMemoryCurrencyTable[code]; exist {
+	store := em.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, exist := store.currencyTable[code]; exist {
 		return nil, ErrCurrencyAlreadyPersisted
 	}
+	now := time.Now()
 	bc := &InMemoryCurrencyRecords{
 		code:       code,
 		name:       name,
 		exchange:   exchange,
-		createTime: time.Now(),
+		createTime: now,
 		createBy:   author,
-		updateTime: time.Now(),
+		updateTime: now,
 		updateBy:   author,
 	}
-	InMemoryCurrencyTable[code] = bc
-	return &BaseCurrency{
-		Code:       code,
-		Name:       name,
-		Exchange:   exchange,
-		CreateTime: time.Now(),
-		CreateBy:   author,
-		UpdateTime: time.Now(),
-		UpdateBy:   author,
-	}, nil
+	store.currencyTable[code] = bc
+	store.currencyRateTable[code] = []*CurrencyRate{{Code: code, Exchange: exchange, ValidFrom: now, CreateBy: author}}
+	return bc.toCurrency(), nil
 }
 
 // UpdateCurrency updates the currency data
 // Error should be returned if the specified Currency is not exist.
 func (em *InMemoryExchangeManager) UpdateCurrency(context context.Context, code string, currency Currency, author string) error {
-	curr, exist := InMemoryCurrencyTable[code]
+	if err := validateCurrencyPrecision(currency); err != nil {
+		return err
+	}
+	store := em.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	curr, exist := store.currencyTable[code]
 	if !exist {
 		return ErrCurrencyNotFound
 	}
-	curr.exchange = currency.GetExchange()
+	now := time.Now()
+	if !curr.exchange.Equal(currency.GetExchange()) {
+		// INSERT INTO CURRENCY_RATE, after closing the rate in effect
+		rates := store.currencyRateTable[code]
+		if len(rates) > 0 {
+			rates[len(rates)-1].ValidTo = now
+		}
+		store.currencyRateTable[code] = append(rates, &CurrencyRate{Code: code, Exchange: currency.GetExchange(), ValidFrom: now, CreateBy: author})
+	}
 	curr.name = currency.GetName()
 	curr.exchange = currency.GetExchange()
+	curr.scale = currency.GetScale()
+	curr.roundingMode = currency.GetRoundingMode()
 	curr.updateBy = author
-	curr.updateTime = time.Now()
+	curr.updateTime = now
 
 	currency.SetCode(code)
 	return nil
 
 }
 
+// UpdateCurrencyRates changes the exchange values of the currencies all at once, each new exchange value
+// taking effect at the validFrom time in the Currency rate history.
+func (em *InMemoryExchangeManager) UpdateCurrencyRates(context context.Context, rates map[string]decimal.Decimal, validFrom time.Time, author string) error {
+	store := em.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// all the currencies are checked before any of them is updated.
+	changed := make([]string, 0, len(rates))
+	for code, exchange := range rates {
+		curr, exist := store.currencyTable[code]
+		if !exist {
+			return ErrCurrencyNotFound
+		}
+		if curr.exchange.Equal(exchange) {
+			continue
+		}
+		if history := store.currencyRateTable[code]; len(history) > 0 && !validFrom.After(history[len(history)-1].ValidFrom) {
+			logrus.Errorf("error updating exchange rate of %s. rate in effect since %s, new rate from %s", code, history[len(history)-1].ValidFrom, validFrom)
+			return ErrCurrencyRateOutOfOrder
+		}
+		changed = append(changed, code)
+	}
+
+	now := time.Now()
+	for _, code := range changed {
+		// INSERT INTO CURRENCY_RATE, after closing the rate in effect
+		history := store.currencyRateTable[code]
+		if len(history) > 0 {
+			history[len(history)-1].ValidTo = validFrom
+		}
+		store.currencyRateTable[code] = append(history, &CurrencyRate{Code: code, Exchange: rates[code], ValidFrom: validFrom, CreateBy: author})
+		curr := store.currencyTable[code]
+		curr.exchange = rates[code]
+		curr.updateBy = author
+		curr.updateTime = now
+	}
+	return nil
+}
+
 // CalculateExchangeRate gets the Currency exchange rate for exchanging between the two Currency.
 // if any of the Currency is not exist, an error should be returned.
 // if from and to Currency is equal, this must return 1.0
 func (em *InMemoryExchangeManager) CalculateExchangeRate(context context.Context, fromCurrency, toCurrency string) (decimal.Decimal, error) {
-	from, err := em.GetCurrency(context, fromCurrency)
-	if err != nil {
-		return decimal.Zero, err
+	store := em.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	from, exist := store.currencyTable[fromCurrency]
+	if !exist {
+		return decimal.Zero, ErrCurrencyNotFound
 	}
-	to, err := em.GetCurrency(context, toCurrency)
-	if err != nil {
-		return decimal.Zero, err
+	to, exist := store.currencyTable[toCurrency]
+	if !exist {
+		return decimal.Zero, ErrCurrencyNotFound
 	}
-	m1 := em.GetDenom(context).Div(from.GetExchange())
-	m2 := m1.Mul(to.GetExchange())
-	m3 := m2.Div(em.GetDenom(context))
-	//m1 := new(big.Float).Quo(em.GetDenom(context), big.NewFloat(from.GetExchange()))
-	//m2 := new(big.Float).Mul(m1, big.NewFloat(to.GetExchange()))
-	//m3 := new(big.Float).Quo(m2, em.GetDenom(context))
-	return m3, nil
+	return exchangeRate(store.commonDenominator, from.exchange, to.exchange), nil
 }
 
 // CalculateExchange gets the Currency exchange value for the Amount of fromCurrency into toCurrency.
 // If any of the Currency is not exist, an error should be returned.
 // if from and to Currency is equal, the returned Amount must be equal to the Amount in the argument.
 func (em *InMemoryExchangeManager) CalculateExchange(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	exchanged, _, err := em.CalculateExchangeWithRemainder(context, fromCurrency, toCurrency, amount)
+	return exchanged, err
+}
+
+// CalculateExchangeWithRemainder gets the exchange value just like CalculateExchange, together with the remainder
+// left by rounding it to the scale of toCurrency.
+func (em *InMemoryExchangeManager) CalculateExchangeWithRemainder(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
 	exchange, err := em.CalculateExchangeRate(context, fromCurrency, toCurrency)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	to, err := em.GetCurrency(context, toCurrency)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	exchanged, remainder := RoundCurrencyAmount(to, exchange.Mul(amount))
+	return exchanged, remainder, nil
+}
+
+// ListCurrencyRates lists the history of the Currency exchange values, the oldest first.
+func (em *InMemoryExchangeManager) ListCurrencyRates(context context.Context, code string) ([]*CurrencyRate, error) {
+	store := em.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if _, exist := store.currencyTable[code]; !exist {
+		return nil, ErrCurrencyNotFound
+	}
+	ret := make([]*CurrencyRate, 0, len(store.currencyRateTable[code]))
+	for _, rate := range store.currencyRateTable[code] {
+		copied := *rate
+		ret = append(ret, &copied)
+	}
+	return ret, nil
+}
+
+// CalculateExchangeRateAt gets the exchange rate between the two Currency, using the exchange values in effect at the specified time.
+func (em *InMemoryExchangeManager) CalculateExchangeRateAt(context context.Context, fromCurrency, toCurrency string, at time.Time) (decimal.Decimal, error) {
+	store := em.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	exchanges := make([]decimal.Decimal, 0, 2)
+	for _, code := range []string{fromCurrency, toCurrency} {
+		if _, exist := store.currencyTable[code]; !exist {
+			return decimal.Zero, ErrCurrencyNotFound
+		}
+		// SELECT EXCHANGE FROM CURRENCY_RATE WHERE CODE = {code} AND VALID_FROM <= {at} AND (VALID_TO IS NULL OR VALID_TO > {at})
+		rate := effectiveRate(store.currencyRateTable[code], at)
+		if rate == nil {
+			return decimal.Zero, ErrCurrencyRateNotFound
+		}
+		exchanges = append(exchanges, rate.Exchange)
+	}
+	return exchangeRate(store.commonDenominator, exchanges[0], exchanges[1]), nil
+}
+
+// CalculateExchangeAt gets the exchange value for the Amount of fromCurrency into toCurrency,
+// using the exchange values in effect at the specified time.
+func (em *InMemoryExchangeManager) CalculateExchangeAt(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal, at time.Time) (decimal.Decimal, error) {
+	exchange, err := em.CalculateExchangeRateAt(context, fromCurrency, toCurrency, at)
 	if err != nil {
 		return decimal.Zero, err
 	}
-	m1 := exchange.Mul(amount)
-	return m1, nil
+	to, err := em.GetCurrency(context, toCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	exchanged, _ := RoundCurrencyAmount(to, exchange.Mul(amount))
+	return exchanged, nil
 }
 
 // ListCurrencies will list all currencies.
 func (em *InMemoryExchangeManager) ListCurrencies(context context.Context) ([]Currency, error) {
+	store := em.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
 	ret := make([]Currency, 0)
-	for _, cur := range InMemoryCurrencyTable {
-		rec := &BaseCurrency{
-			Code:       cur.code,
-			Name:       cur.name,
-			Exchange:   cur.exchange,
-			CreateTime: cur.createTime,
-			CreateBy:   cur.createBy,
-			UpdateTime: cur.updateTime,
-			UpdateBy:   cur.updateBy,
-		}
-		ret = append(ret, rec)
+	for _, cur := range store.currencyTable {
+		ret = append(ret, cur.toCurrency())
 	}
 	return ret, nil
 }
+
+// toCurrency creates a new BaseCurrency out of this record.
+func (cur *InMemoryCurrencyRecords) toCurrency() Currency {
+	return &BaseCurrency{
+		Code:         cur.code,
+		Name:         cur.name,
+		Exchange:     cur.exchange,
+		Scale:        cur.scale,
+		RoundingMode: cur.roundingMode,
+		CreateTime:   cur.createTime,
+		CreateBy:     cur.createBy,
+		UpdateTime:   cur.updateTime,
+		UpdateBy:     cur.updateBy,
+	}
+}
+
+// InMemoryHoldManager implementation of HoldManager using inmemory Hold table map
+type InMemoryHoldManager struct {
+	store *InMemoryStore
+}
+
+// getStore returns the store this manager is bound to, or the default store if not bound to any.
+func (hm *InMemoryHoldManager) getStore() *InMemoryStore {
+	if hm.store == nil {
+		return defaultInMemoryStore
+	}
+	return hm.store
+}
+
+// NewHold will create a new blank un-persisted hold.
+func (hm *InMemoryHoldManager) NewHold(context context.Context) Hold {
+	return &BaseHold{}
+}
+
+// PlaceHold will save the active hold into database, reserving its amount from the account available Balance.
+// It fails with an InsufficientBalanceError if the available Balance would go below what the account balance limit allows,
+// and with ErrAccountFrozen or ErrAccountClosed if the account state rejects transactions reducing its Balance.
+func (hm *InMemoryHoldManager) PlaceHold(context context.Context, holdToPlace Hold) error {
+	if err := validateHold(holdToPlace); err != nil {
+		return err
+	}
+
+	store := hm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// SELECT COUNT(*) FROM HOLD WHERE HOLD_ID = {holdID}
+	if _, exist := store.holdTable[holdToPlace.GetHoldID()]; exist {
+		return ErrHoldAlreadyPersisted
+	}
+	// SELECT * FROM ACCOUNT WHERE ACCOUNT_NUMBER = {accountNumber} FOR UPDATE
+	accountRecord, exist := store.accountTable[holdToPlace.GetAccountNumber()]
+	if !exist {
+		return ErrAccountIDNotFound
+	}
+	now := time.Now()
+	held := store.heldAmount(accountRecord.id, now)
+	err := checkHoldPlacement(accountRecord.toAccount(), held, holdToPlace.GetAmount())
+	if err != nil {
+		logrus.Errorf("error placing hold %s on account %s. got %s", holdToPlace.GetHoldID(), accountRecord.id, err.Error())
+		return err
+	}
+
+	// INSERT INTO HOLD ...
+	store.holdTable[holdToPlace.GetHoldID()] = &InMemoryHoldRecord{
+		holdID:         holdToPlace.GetHoldID(),
+		accountNumber:  holdToPlace.GetAccountNumber(),
+		description:    holdToPlace.GetDescription(),
+		amount:         holdToPlace.GetAmount(),
+		capturedAmount: decimal.Zero,
+		state:          HoldActive,
+		expireTime:     holdToPlace.GetExpireTime(),
+		createTime:     now,
+		createBy:       holdToPlace.GetCreateBy(),
+		updateTime:     now,
+		updateBy:       holdToPlace.GetCreateBy(),
+	}
+	holdToPlace.SetCapturedAmount(decimal.Zero).SetState(HoldActive).SetCreateTime(now).SetUpdateTime(now).SetUpdateBy(holdToPlace.GetCreateBy())
+	return nil
+}
+
+// GetHoldByID retrieve a hold by specifying its ID
+func (hm *InMemoryHoldManager) GetHoldByID(context context.Context, holdID string) (Hold, error) {
+	store := hm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	holdRecord, exist := store.holdTable[holdID]
+	if !exist {
+		return nil, ErrHoldNotFound
+	}
+	return holdRecord.toHold(), nil
+}
+
+// ListActiveHolds returns the active holds of the account that have not expired at the specified time, ordered by their creation time.
+func (hm *InMemoryHoldManager) ListActiveHolds(context context.Context, accountNumber string, at time.Time) ([]Hold, error) {
+	store := hm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	records := make([]*InMemoryHoldRecord, 0)
+	for _, holdRecord := range store.holdTable {
+		if holdRecord.accountNumber == accountNumber && isHoldReserving(holdRecord.state, holdRecord.expireTime, at) {
+			records = append(records, holdRecord)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].createTime.Equal(records[j].createTime) {
+			return records[i].holdID < records[j].holdID
+		}
+		return records[i].createTime.Before(records[j].createTime)
+	})
+	holds := make([]Hold, len(records))
+	for i, holdRecord := range records {
+		holds[i] = holdRecord.toHold()
+	}
+	return holds, nil
+}
+
+// GetHeldAmount returns the sum of the remaining amount of the active holds of the account that have not expired at the specified time.
+func (hm *InMemoryHoldManager) GetHeldAmount(context context.Context, accountNumber string, at time.Time) (decimal.Decimal, error) {
+	store := hm.getStore()
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if _, exist := store.accountTable[accountNumber]; !exist {
+		return decimal.Zero, ErrAccountIDNotFound
+	}
+	return store.heldAmount(accountNumber, at), nil
+}
+
+// CaptureHold records the amount as captured from the hold. The hold must be active, not expired,
+// and its remaining amount must cover the amount. Once its whole amount is captured, the hold becomes HoldCaptured.
+func (hm *InMemoryHoldManager) CaptureHold(context context.Context, holdID string, amount decimal.Decimal, capturedBy string) error {
+	store := hm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	holdRecord, err := store.checkHoldCapture(holdID, amount)
+	if err != nil {
+		return err
+	}
+	store.captureHold(holdRecord, amount, capturedBy)
+	return nil
+}
+
+// CaptureHoldWithJournal commits the persisted journal moving the captured amount out of the held account,
+// and records the amount as captured from the hold, all at once. If the hold can not be captured or the journal
+// can not be committed, neither of them is changed.
+func (hm *InMemoryHoldManager) CaptureHoldWithJournal(context context.Context, holdID string, amount decimal.Decimal, capturedBy string, journalToCommit Journal) error {
+	if journalToCommit == nil {
+		return ErrJournalNil
+	}
+
+	store := hm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	holdRecord, err := store.checkHoldCapture(holdID, amount)
+	if err != nil {
+		return err
+	}
+	if err := store.commitJournal(journalToCommit, &holdCapture{holdID: holdID, amount: amount, capturedBy: capturedBy}); err != nil {
+		return err
+	}
+	store.captureHold(holdRecord, amount, capturedBy)
+	return nil
+}
+
+// checkHoldCapture loads the hold and checks the amount can be captured from it now.
+// The store lock must be held by the caller.
+func (store *InMemoryStore) checkHoldCapture(holdID string, amount decimal.Decimal) (*InMemoryHoldRecord, error) {
+	// SELECT * FROM HOLD WHERE HOLD_ID = {holdID} FOR UPDATE
+	holdRecord, exist := store.holdTable[holdID]
+	if !exist {
+		return nil, ErrHoldNotFound
+	}
+	if err := checkHoldCapture(holdRecord.toHold(), amount, time.Now()); err != nil {
+		logrus.Errorf("error capturing hold %s. got %s", holdID, err.Error())
+		return nil, err
+	}
+	return holdRecord, nil
+}
+
+// captureHold records the amount as captured from the hold.
+// The store write lock must be held by the caller.
+func (store *InMemoryStore) captureHold(holdRecord *InMemoryHoldRecord, amount decimal.Decimal, capturedBy string) {
+	now := time.Now()
+	// UPDATE HOLD SET CAPTURED_AMOUNT = CAPTURED_AMOUNT + {amount}, STATE = {state}, UPDATE_TIME = {now}, UPDATE_BY = {capturedBy} WHERE HOLD_ID = {holdID}
+	holdRecord.capturedAmount = holdRecord.capturedAmount.Add(amount)
+	if holdRecord.capturedAmount.Equal(holdRecord.amount) {
+		holdRecord.state = HoldCaptured
+	}
+	holdRecord.updateTime = now
+	holdRecord.updateBy = capturedBy
+}
+
+// ReleaseHold releases the remaining amount of an active hold, which becomes HoldReleased.
+func (hm *InMemoryHoldManager) ReleaseHold(context context.Context, holdID, releasedBy string) error {
+	store := hm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// SELECT * FROM HOLD WHERE HOLD_ID = {holdID} FOR UPDATE
+	holdRecord, exist := store.holdTable[holdID]
+	if !exist {
+		return ErrHoldNotFound
+	}
+	if holdRecord.state != HoldActive {
+		logrus.Errorf("error releasing hold %s. hold is %s", holdID, holdRecord.state)
+		return ErrHoldNotActive
+	}
+
+	// UPDATE HOLD SET STATE = {HoldReleased}, UPDATE_TIME = {now}, UPDATE_BY = {releasedBy} WHERE HOLD_ID = {holdID}
+	holdRecord.state = HoldReleased
+	holdRecord.updateTime = time.Now()
+	holdRecord.updateBy = releasedBy
+	return nil
+}
+
+// ExpireHolds marks the active holds that have expired at the specified time as HoldExpired, and returns how many there were.
+func (hm *InMemoryHoldManager) ExpireHolds(context context.Context, at time.Time) (int, error) {
+	store := hm.getStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// UPDATE HOLD SET STATE = {HoldExpired}, UPDATE_TIME = {now} WHERE STATE = {HoldActive} AND EXPIRE_TIME <= {at}
+	expired := 0
+	now := time.Now()
+	for _, holdRecord := range store.holdTable {
+		if holdRecord.state == HoldActive && !holdRecord.expireTime.After(at) {
+			holdRecord.state = HoldExpired
+			holdRecord.updateTime = now
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// heldAmount sums the remaining amount of the active holds of the account that have not expired at the specified time.
+// The store lock must be held by the caller.
+func (store *InMemoryStore) heldAmount(accountNumber string, at time.Time) decimal.Decimal {
+	// SELECT AMOUNT, CAPTURED_AMOUNT FROM HOLD WHERE ACCOUNT_NUMBER = {accountNumber} AND STATE = {HoldActive} AND EXPIRE_TIME > {at}
+	held := decimal.Zero
+	for _, holdRecord := range store.holdTable {
+		if holdRecord.accountNumber == accountNumber && isHoldReserving(holdRecord.state, holdRecord.expireTime, at) {
+			held = held.Add(holdRecord.amount.Sub(holdRecord.capturedAmount))
+		}
+	}
+	return held
+}
+
+func (r *InMemoryHoldRecord) toHold() Hold {
+	return &BaseHold{
+		HoldID:         r.holdID,
+		AccountNumber:  r.accountNumber,
+		Description:    r.description,
+		Amount:         r.amount,
+		CapturedAmount: r.capturedAmount,
+		State:          r.state,
+		ExpireTime:     r.expireTime,
+		CreateTime:     r.createTime,
+		CreateBy:       r.createBy,
+		UpdateTime:     r.updateTime,
+		UpdateBy:       r.updateBy,
+	}
+}
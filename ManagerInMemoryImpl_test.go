@@ -2,9 +2,12 @@ package acccore
 
 import (
 	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
 )
 
 type ExchangeTest struct {
@@ -66,3 +69,154 @@ func TestInMemoryExchangeManager_CalculateExchange(t *testing.T) {
 		}
 	}
 }
+
+func newTestAccounting(store *InMemoryStore) *Accounting {
+	return NewAccounting(store.GetAccountManager(), store.GetTransactionManager(), store.GetJournalManager(), &RandomGenUniqueIDGenerator{
+		Length:     16,
+		UpperAlpha: true,
+		Numeric:    true,
+	})
+}
+
+func TestInMemoryStore_Isolation(t *testing.T) {
+	ctx := context.Background()
+	storeA := NewInMemoryStore()
+	storeB := NewInMemoryStore()
+
+	accA := newTestAccounting(storeA)
+	account, err := accA.CreateNewAccount(ctx, "ACC-1", "Alpha", "Alpha account", "1.1", "GOLD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+
+	exist, err := storeA.GetAccountManager().IsAccountIDExist(ctx, account.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	exist, err = storeB.GetAccountManager().IsAccountIDExist(ctx, account.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	// the same account number can be used in other store
+	accB := newTestAccounting(storeB)
+	_, err = accB.CreateNewAccount(ctx, "ACC-1", "Alpha", "Alpha account", "1.1", "GOLD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+
+	_, err = storeA.GetExchangeManager().CreateCurrency(ctx, "GOLD", "Gold", decimal.NewFromInt(1), "aCreator")
+	assert.NoError(t, err)
+	exist, err = storeB.GetExchangeManager().IsCurrencyExist(ctx, "GOLD")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	storeA.Clear()
+	exist, err = storeA.GetAccountManager().IsAccountIDExist(ctx, "ACC-1")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+	exist, err = storeB.GetAccountManager().IsAccountIDExist(ctx, "ACC-1")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestInMemoryStore_DeprecatedTables(t *testing.T) {
+	ClearInMemoryTables()
+	ctx := context.Background()
+
+	// the deprecated tables are those of the default store, used by managers not bound to any store.
+	account := (&InMemoryAccountManager{}).NewAccount(ctx).SetAccountNumber("ACC-1").SetName("Alpha").
+		SetDescription("Alpha account").SetCOA("1.1").SetCurrency("GOLD").SetAlignment(DEBIT).SetCreateBy("aCreator")
+	assert.NoError(t, (&InMemoryAccountManager{}).PersistAccount(ctx, account))
+	assert.Contains(t, InMemoryAccountTable, "ACC-1")
+
+	ClearInMemoryTables()
+	assert.Empty(t, InMemoryAccountTable)
+	assert.Empty(t, InMemoryJournalTable)
+	assert.Empty(t, InMemoryTransactionTable)
+	assert.Empty(t, InMemoryCurrencyTable)
+}
+
+func TestInMemoryStore_ConcurrentPersistJournal(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	acc := newTestAccounting(store)
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	workers := 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := acc.CreateNewJournal(ctx, "Topup", []TransactionInfo{
+				{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.NewFromInt(10)},
+				{AccountNumber: wallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.NewFromInt(10)},
+			}, "aCreator")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	reserve, err = store.GetAccountManager().GetAccountByID(ctx, reserve.GetAccountNumber())
+	assert.NoError(t, err)
+	wallet, err = store.GetAccountManager().GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, reserve.GetBalance().Equal(decimal.NewFromInt(int64(10*workers))))
+	assert.True(t, wallet.GetBalance().Equal(decimal.NewFromInt(int64(10*workers))))
+}
+
+func TestInMemoryJournalManager_TwoPhaseCommit(t *testing.T) {
+	testTwoPhaseCommit(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryAccountManager_OptimisticConcurrency(t *testing.T) {
+	testAccountOptimisticConcurrency(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryJournalManager_IdempotencyKey(t *testing.T) {
+	testIdempotentJournal(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryCOAManager_ChartOfAccounts(t *testing.T) {
+	store := NewInMemoryStore()
+	testChartOfAccounts(t, newTestAccounting(store), store.GetCOAManager())
+}
+
+func TestInMemoryTransactionManager_BalanceAt(t *testing.T) {
+	testBalanceAt(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryTransactionManager_BalanceAtEqualTime(t *testing.T) {
+	store := NewInMemoryStore()
+	testBalanceAtEqualTime(t, newTestAccounting(store), func(at time.Time) {
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+		for _, trx := range store.transactionTable {
+			trx.transactionTime = at
+		}
+	})
+}
+
+func TestInMemoryStore_ListingSorts(t *testing.T) {
+	testListingSorts(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryStore_CursorPagination(t *testing.T) {
+	testCursorPagination(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryStore_CursorPaginationConcurrent(t *testing.T) {
+	testCursorPaginationConcurrent(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryStore_Streaming(t *testing.T) {
+	testStreaming(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryAccountManager_BalanceLimit(t *testing.T) {
+	testBalanceLimit(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestInMemoryAccountManager_AccountStates(t *testing.T) {
+	testAccountStates(t, newTestAccounting(NewInMemoryStore()))
+}
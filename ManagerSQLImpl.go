@@ -0,0 +1,2079 @@
+package acccore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// SQLDialect define the flavour of SQL spoken by the database behind a SQLStore.
+type SQLDialect int
+
+const (
+	// SQLDialectSQLite is the dialect for SQLite databases
+	SQLDialectSQLite SQLDialect = iota
+	// SQLDialectPostgres is the dialect for PostgreSQL databases
+	SQLDialectPostgres
+	// SQLDialectMySQL is the dialect for MySQL and MariaDB databases
+	SQLDialectMySQL
+)
+
+// rebind converts the `?` placeholders in the query into the placeholder style of the dialect.
+func (dialect SQLDialect) rebind(query string) string {
+	if dialect != SQLDialectPostgres {
+		return query
+	}
+	var buff strings.Builder
+	count := 0
+	for _, r := range query {
+		if r == '?' {
+			count++
+			buff.WriteString(fmt.Sprintf("$%d", count))
+		} else {
+			buff.WriteRune(r)
+		}
+	}
+	return buff.String()
+}
+
+// forUpdate returns the row locking clause of the dialect.
+// SQLite locks the whole database on write, so it have no such clause.
+func (dialect SQLDialect) forUpdate() string {
+	if dialect == SQLDialectSQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// isUniqueViolation tells whether the error is the database rejecting a row whose key is already taken.
+// The drivers are not imported, so the error is recognized by its SQLSTATE when the driver exposes it, or by its message.
+func (dialect SQLDialect) isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch dialect {
+	case SQLDialectPostgres:
+		var state interface{ SQLState() string }
+		if errors.As(err, &state) {
+			return state.SQLState() == "23505"
+		}
+		return strings.Contains(err.Error(), "duplicate key value violates unique constraint")
+	case SQLDialectMySQL:
+		return strings.Contains(err.Error(), "Error 1062")
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// sqlDecimalScale is the number of decimals kept by the DECIMAL(38, 12) columns holding the amounts.
+const sqlDecimalScale = 12
+
+// checkSQLAmountPrecision returns ErrSQLAmountPrecision if any of the amounts have more decimals than the database keeps.
+func checkSQLAmountPrecision(amounts ...decimal.Decimal) error {
+	for _, amount := range amounts {
+		if !amount.Equal(amount.Truncate(sqlDecimalScale)) {
+			logrus.Errorf("error storing amount %s. it have more than %d decimals", amount, sqlDecimalScale)
+			return ErrSQLAmountPrecision
+		}
+	}
+	return nil
+}
+
+// sqlQuerier is the common functions of *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqlScanner is the common function of *sql.Row and *sql.Rows
+type sqlScanner interface {
+	Scan(dest ...any) error
+}
+
+const (
+	sqlJournalColumns      = "journal_id, journaling_time, description, reversal, reversed_journal_id, amount, exchange_rate, create_time, create_by"
+	sqlAccountColumns      = "account_number, currency, name, description, alignment, balance, balance_limit, overdraft_limit, state, coa, create_time, create_by, update_time, update_by, version"
+	sqlTransactionColumns  = "transaction_id, transaction_time, account_number, journal_id, description, alignment, amount, account_balance, create_time, create_by"
+	sqlCurrencyColumns     = "code, name, exchange, scale, rounding_mode, create_time, create_by, update_time, update_by"
+	sqlCurrencyRateColumns = "code, exchange, valid_from, valid_to, create_by"
+	sqlCOAColumns          = "code, parent_code, name, description, category, alignment, create_time, create_by"
+	sqlHoldColumns         = "hold_id, account_number, description, amount, captured_amount, state, expire_time, create_time, create_by, update_time, update_by"
+)
+
+// SQLStore is a set of managers backed by a database/sql database.
+// The database schema must be brought up to date using Migrate before use.
+// The amounts are stored with 12 decimals, amounts having more decimals are rejected with ErrSQLAmountPrecision.
+type SQLStore struct {
+	db      *sql.DB
+	dialect SQLDialect
+
+	denomMutex sync.RWMutex
+	// commonDenominator is the common denominator used by the exchange manager
+	commonDenominator decimal.Decimal
+
+	cursorMutex sync.RWMutex
+	// cursorCodec signs and verifies the pagination cursors
+	cursorCodec *cursorCodec
+
+	journalManager     *SQLJournalManager
+	accountManager     *SQLAccountManager
+	transactionManager *SQLTransactionManager
+	exchangeManager    *SQLExchangeManager
+	coaManager         *SQLCOAManager
+	holdManager        *SQLHoldManager
+}
+
+// NewSQLStore creates a new SQLStore on top of the specified database, using the specified dialect.
+func NewSQLStore(db *sql.DB, dialect SQLDialect) *SQLStore {
+	store := &SQLStore{
+		db:                db,
+		dialect:           dialect,
+		commonDenominator: decimal.NewFromInt(1),
+		cursorCodec:       newCursorCodec(),
+	}
+	store.journalManager = &SQLJournalManager{store: store}
+	store.accountManager = &SQLAccountManager{store: store}
+	store.transactionManager = &SQLTransactionManager{store: store}
+	store.exchangeManager = &SQLExchangeManager{store: store}
+	store.coaManager = &SQLCOAManager{store: store}
+	store.holdManager = &SQLHoldManager{store: store}
+	return store
+}
+
+// GetDB returns the database used by this store
+func (store *SQLStore) GetDB() *sql.DB {
+	return store.db
+}
+
+// SetCursorSecret sets the secret used to sign the pagination cursors. By default a random secret is used,
+// so a secret must be set for the cursors to stay valid across restarts or between application instances.
+func (store *SQLStore) SetCursorSecret(secret []byte) *SQLStore {
+	store.cursorMutex.Lock()
+	defer store.cursorMutex.Unlock()
+	store.cursorCodec = &cursorCodec{secret: secret}
+	return store
+}
+
+// getCursorCodec returns the codec signing and verifying the pagination cursors
+func (store *SQLStore) getCursorCodec() *cursorCodec {
+	store.cursorMutex.RLock()
+	defer store.cursorMutex.RUnlock()
+	return store.cursorCodec
+}
+
+// GetJournalManager returns the journal manager bound to this store
+func (store *SQLStore) GetJournalManager() JournalManager {
+	return store.journalManager
+}
+
+// GetAccountManager returns the account manager bound to this store
+func (store *SQLStore) GetAccountManager() AccountManager {
+	return store.accountManager
+}
+
+// GetTransactionManager returns the transaction manager bound to this store
+func (store *SQLStore) GetTransactionManager() TransactionManager {
+	return store.transactionManager
+}
+
+// GetExchangeManager returns the exchange manager bound to this store
+func (store *SQLStore) GetExchangeManager() ExchangeManager {
+	return store.exchangeManager
+}
+
+// GetCOAManager returns the chart of accounts manager bound to this store
+func (store *SQLStore) GetCOAManager() COAManager {
+	return store.coaManager
+}
+
+// GetHoldManager returns the fund hold manager bound to this store
+func (store *SQLStore) GetHoldManager() HoldManager {
+	return store.holdManager
+}
+
+// count runs a SELECT COUNT(*) query and returns the count.
+func (store *SQLStore) count(context context.Context, q sqlQuerier, query string, args ...any) (int, error) {
+	var count int
+	if err := q.QueryRowContext(context, store.dialect.rebind(query), args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SQLJournalManager implementation of JournalManager using database/sql
+type SQLJournalManager struct {
+	store *SQLStore
+}
+
+// NewJournal will create new blank un-persisted journal
+func (jm *SQLJournalManager) NewJournal(context context.Context) Journal {
+	return &BaseJournal{}
+}
+
+// PersistJournal will record a journal entry into database.
+// It requires list of Transactions for which each of the transaction MUST BE :
+//
+//	1.NOT BE PERSISTED. (the journal AccountNumber is not exist in DB yet)
+//	2.Pointing or owned by a PERSISTED Account
+//	3.Balanced per Currency. The Transactions on the accounts of each Currency balance on their own, so a journal
+//	  may only mix currencies through legs offsetting each other, such as the FX position legs.
+//	4.Balanced. The total sum of DEBIT and total sum of CREDIT is equal.
+//	5.No duplicate transaction that belongs to the same Account.
+//	6.Its idempotency key, if any, is not used by other journal.
+//
+// A persisted journal is staged, it is not yet visible and do not change any account Balance
+// until it is committed using CommitJournal, or discarded using CancelJournal.
+func (jm *SQLJournalManager) PersistJournal(context context.Context, journalToPersist Journal) (err error) {
+	// 1. Checking the mandatories are not missing, the Transactions are IDed, balanced,
+	//    and do not appear twice for the same account.
+	if err := ValidateJournal(journalToPersist); err != nil {
+		return err
+	}
+
+	store := jm.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// 2. Checking if the journal ID must not in the Database (already persisted)
+	count, err := store.count(context, tx, "SELECT COUNT(*) FROM acccore_journal WHERE journal_id = ?", journalToPersist.GetJournalID())
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		logrus.Errorf("error persisting journal %s. journal already exist.", journalToPersist.GetJournalID())
+		return ErrJournalAlreadyPersisted
+	}
+	//    The idempotency key must not be used by other journal, not even one that is still waiting to be committed.
+	if len(journalToPersist.GetIdempotencyKey()) > 0 {
+		count, err = store.count(context, tx, "SELECT COUNT(*) FROM acccore_journal_idempotency_key WHERE idempotency_key = ?", journalToPersist.GetIdempotencyKey())
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			logrus.Errorf("error persisting journal %s. idempotency key %s is already used.", journalToPersist.GetJournalID(), journalToPersist.GetIdempotencyKey())
+			return ErrJournalIdempotencyKeyAlreadyUsed
+		}
+	}
+
+	// 3. Make sure all journal Transactions are not persisted.
+	for idx, trx := range journalToPersist.GetTransactions() {
+		count, err = store.count(context, tx, "SELECT COUNT(*) FROM acccore_transaction WHERE transaction_id = ?", trx.GetTransactionID())
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			logrus.Errorf("error persisting journal %s. transaction %d is already exist.", journalToPersist.GetJournalID(), idx)
+			return ErrJournalTransactionAlreadyPersisted
+		}
+	}
+
+	// 4. Make sure all the accounts involved exist, accept their transaction with an amount fitting the scale of their Currency,
+	//    and that the Transactions of each Currency balance
+	alignments := make(map[string]Alignment, len(journalToPersist.GetTransactions()))
+	amounts := make(map[string]decimal.Decimal, len(journalToPersist.GetTransactions()))
+	accountNumbers := make([]string, 0, len(journalToPersist.GetTransactions()))
+	for _, trx := range journalToPersist.GetTransactions() {
+		alignments[trx.GetAccountNumber()] = trx.GetAlignment()
+		amounts[trx.GetAccountNumber()] = trx.GetAmount()
+		accountNumbers = append(accountNumbers, trx.GetAccountNumber())
+	}
+	sort.Strings(accountNumbers)
+	currencies := make(map[string]string, len(accountNumbers))
+	for _, accountNumber := range accountNumbers {
+		var (
+			accountCurrency     string
+			accountState        AccountState
+			scale, roundingMode sql.NullInt32
+		)
+		err = tx.QueryRowContext(context, store.dialect.rebind("SELECT a.currency, a.state, c.scale, c.rounding_mode FROM acccore_account a LEFT JOIN acccore_currency c ON c.code = a.currency WHERE a.account_number = ?"), accountNumber).
+			Scan(&accountCurrency, &accountState, &scale, &roundingMode)
+		if errors.Is(err, sql.ErrNoRows) {
+			logrus.Errorf("error persisting journal %s. theres a transaction belong to non existent account (%s)", journalToPersist.GetJournalID(), accountNumber)
+			return ErrJournalTransactionAccountNotPersist
+		}
+		if err != nil {
+			return err
+		}
+		if err = checkAccountState(accountState, alignments[accountNumber]); err != nil {
+			logrus.Errorf("error persisting journal %s. account %s is %s", journalToPersist.GetJournalID(), accountNumber, accountState)
+			return err
+		}
+		if err = checkAmountPrecision(amounts[accountNumber], scale.Int32, RoundingMode(roundingMode.Int32)); err != nil {
+			logrus.Errorf("error persisting journal %s. amount %s of account %s have more than %d decimals", journalToPersist.GetJournalID(), amounts[accountNumber], accountNumber, scale.Int32)
+			return err
+		}
+		if err = checkSQLAmountPrecision(amounts[accountNumber]); err != nil {
+			return err
+		}
+		currencies[accountNumber] = accountCurrency
+	}
+	trxCurrencies := make([]string, 0, len(journalToPersist.GetTransactions()))
+	for _, trx := range journalToPersist.GetTransactions() {
+		trxCurrencies = append(trxCurrencies, currencies[trx.GetAccountNumber()])
+	}
+	amount, err := currencyBalancedAmount(journalToPersist.GetTransactions(), trxCurrencies)
+	if err != nil {
+		logrus.Errorf("error persisting journal %s. Transactions here uses account with different currencies that do not balance per Currency", journalToPersist.GetJournalID())
+		return err
+	}
+
+	// 5. If this is a Reversal journal, make sure the journal being reversed have not been reversed before,
+	//    not even by a reversal journal that is still waiting to be committed.
+	reversedJournalID := ""
+	if journalToPersist.GetReversedJournal() != nil {
+		reversedJournalID = journalToPersist.GetReversedJournal().GetJournalID()
+		count, err = store.count(context, tx, "SELECT COUNT(*) FROM acccore_journal WHERE journal_id = ? AND committed = ?", reversedJournalID, true)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			logrus.Errorf("error persisting journal %s. the journal to reverse %s is not found", journalToPersist.GetJournalID(), reversedJournalID)
+			return ErrJournalIDNotFound
+		}
+		count, err = store.count(context, tx, "SELECT COUNT(*) FROM acccore_journal WHERE reversed_journal_id = ?", reversedJournalID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			logrus.Errorf("error persisting journal %s. this journal try to make reverse transaction on journals thats already reversed %s", journalToPersist.GetJournalID(), reversedJournalID)
+			return ErrJournalCanNotDoubleReverse
+		}
+	}
+
+	// ALL is OK. So lets start staging.
+	now := time.Now().UTC()
+
+	// 1. Save the Journal, not yet committed
+	_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_journal ("+sqlJournalColumns+", committed) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		journalToPersist.GetJournalID(), now, journalToPersist.GetDescription(), len(reversedJournalID) > 0, reversedJournalID,
+		amount, journalToPersist.GetExchangeRate(), now, journalToPersist.GetCreateBy(), false)
+	if err != nil {
+		return err
+	}
+	if len(journalToPersist.GetIdempotencyKey()) > 0 {
+		_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_journal_idempotency_key (idempotency_key, journal_id) VALUES (?, ?)"),
+			journalToPersist.GetIdempotencyKey(), journalToPersist.GetJournalID())
+		if err != nil {
+			return err
+		}
+	}
+
+	// 2 Save the Transactions, not yet committed. The account balance is only known when the journal get committed.
+	for _, trx := range journalToPersist.GetTransactions() {
+		_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_transaction ("+sqlTransactionColumns+", committed) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			trx.GetTransactionID(), now, trx.GetAccountNumber(), journalToPersist.GetJournalID(), trx.GetDescription(),
+			trx.GetAlignment(), trx.GetAmount(), decimal.Zero, now, trx.GetCreateBy(), false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// sqlAccountBalance holds the columns of an account needed to update its Balance.
+type sqlAccountBalance struct {
+	alignment      Alignment
+	balance        decimal.Decimal
+	balanceLimit   BalanceLimit
+	overdraftLimit decimal.Decimal
+	state          AccountState
+	version        int64
+}
+
+// toAccount creates an Account of the account number out of the locked Balance and constraints.
+func (account *sqlAccountBalance) toAccount(accountNumber string) Account {
+	return &BaseAccount{
+		AccountNumber: accountNumber,
+		Alignment:     account.alignment,
+		Balance:       account.balance,
+		BalanceLimit:  account.balanceLimit,
+		Overdraft:     account.overdraftLimit,
+		State:         account.state,
+		Version:       account.version,
+	}
+}
+
+// CommitJournal will commit the journal into the system
+// Only non committed journal can be committed.
+// Committing the journal applies its Transactions into the account Balances and makes it visible,
+// all within a single database transaction.
+// Every commit draws its commit sequence from the single acccore_commit_sequence row, which stays locked until the
+// committing database transaction ends. Commits are therefore serialized on that row, even when they touch different
+// accounts, and the commit throughput is bound by how long a single commit takes to reach the database commit.
+func (jm *SQLJournalManager) CommitJournal(context context.Context, journalToCommit Journal) error {
+	if journalToCommit == nil {
+		return ErrJournalNil
+	}
+	return jm.store.commitJournal(context, journalToCommit, nil)
+}
+
+// commitJournal applies the journal Transactions into the account Balances and makes it visible, in a single database transaction.
+// The Balance an account may go down to is checked against its available Balance, so the amount held on the account
+// can not be spent. If the journal captures a hold, the hold is updated within the same database transaction
+// and the captured amount is no longer held once the journal is committed.
+func (store *SQLStore) commitJournal(context context.Context, journalToCommit Journal, capture *holdCapture) (err error) {
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		committed bool
+		amount    decimal.Decimal
+	)
+	err = tx.QueryRowContext(context, store.dialect.rebind("SELECT committed, amount FROM acccore_journal WHERE journal_id = ?"+store.dialect.forUpdate()), journalToCommit.GetJournalID()).
+		Scan(&committed, &amount)
+	if errors.Is(err, sql.ErrNoRows) {
+		logrus.Errorf("error committing journal %s. journal not found.", journalToCommit.GetJournalID())
+		return ErrJournalIDNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if committed {
+		logrus.Errorf("error committing journal %s. journal already committed.", journalToCommit.GetJournalID())
+		return ErrJournalAlreadyCommitted
+	}
+
+	rows, err := tx.QueryContext(context, store.dialect.rebind("SELECT "+sqlTransactionColumns+" FROM acccore_transaction WHERE journal_id = ? ORDER BY transaction_id"), journalToCommit.GetJournalID())
+	if err != nil {
+		return err
+	}
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return err
+	}
+
+	// Lock all the accounts involved, in a consistent order to avoid dead locks
+	accountNumbers := make([]string, 0, len(transactions))
+	for _, trx := range transactions {
+		accountNumbers = append(accountNumbers, trx.GetAccountNumber())
+	}
+	sort.Strings(accountNumbers)
+	accounts := make(map[string]*sqlAccountBalance, len(accountNumbers))
+	for _, accountNumber := range accountNumbers {
+		account := &sqlAccountBalance{}
+		err = tx.QueryRowContext(context, store.dialect.rebind("SELECT alignment, balance, balance_limit, overdraft_limit, state, version FROM acccore_account WHERE account_number = ?"+store.dialect.forUpdate()), accountNumber).
+			Scan(&account.alignment, &account.balance, &account.balanceLimit, &account.overdraftLimit, &account.state, &account.version)
+		if err != nil {
+			return err
+		}
+		accounts[accountNumber] = account
+	}
+
+	now := time.Now().UTC()
+	var captured Hold
+	if capture != nil {
+		captured, err = store.getHold(context, tx, capture.holdID, true)
+		if err != nil {
+			return err
+		}
+		if err = checkHoldCapture(captured, capture.amount, now); err != nil {
+			logrus.Errorf("error capturing hold %s. got %s", capture.holdID, err.Error())
+			return err
+		}
+	}
+
+	balances := make(map[string]decimal.Decimal, len(transactions))
+	for _, trx := range transactions {
+		account := accounts[trx.GetAccountNumber()]
+		// the account state might have changed since the journal was persisted.
+		if err = checkAccountState(account.state, trx.GetAlignment()); err != nil {
+			logrus.Errorf("error committing journal %s. account %s is %s", journalToCommit.GetJournalID(), trx.GetAccountNumber(), account.state)
+			return err
+		}
+		balance := account.balance
+		if trx.GetAlignment() == account.alignment {
+			account.balance = account.balance.Add(trx.GetAmount())
+		} else {
+			account.balance = account.balance.Sub(trx.GetAmount())
+
+			// the held amount is not available, except for the amount captured by this journal.
+			var held decimal.Decimal
+			held, err = store.heldAmount(context, tx, trx.GetAccountNumber(), now)
+			if err != nil {
+				return err
+			}
+			heldAfter := held
+			if captured != nil && captured.GetAccountNumber() == trx.GetAccountNumber() {
+				heldAfter = held.Sub(capture.amount)
+			}
+			if err = checkBalanceLimit(trx.GetAccountNumber(), account.balanceLimit, account.overdraftLimit, balance.Sub(held), account.balance.Sub(heldAfter)); err != nil {
+				logrus.Errorf("error committing journal %s. got %s", journalToCommit.GetJournalID(), err.Error())
+				return err
+			}
+		}
+		balances[trx.GetTransactionID()] = account.balance
+
+		_, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_transaction SET transaction_time = ?, account_balance = ?, committed = ? WHERE transaction_id = ?"),
+			now, account.balance, true, trx.GetTransactionID())
+		if err != nil {
+			return err
+		}
+
+		var result sql.Result
+		result, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_account SET balance = ?, update_time = ?, update_by = ?, version = ? WHERE account_number = ? AND version = ?"),
+			account.balance, now, trx.GetCreateBy(), account.version+1, trx.GetAccountNumber(), account.version)
+		if err != nil {
+			return err
+		}
+		var affected int64
+		affected, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			logrus.Errorf("error committing journal %s. account %s have been modified concurrently", journalToCommit.GetJournalID(), trx.GetAccountNumber())
+			return ErrAccountConcurrentModification
+		}
+		account.version++
+	}
+
+	if captured != nil {
+		if err = store.captureHold(context, tx, captured, capture.amount, capture.capturedBy, now); err != nil {
+			return err
+		}
+	}
+
+	// the commit sequence row stays locked until this transaction ends, so the next commit only draws its sequence
+	// once this one is visible, and cursors never skip a journal committed concurrently.
+	// A database sequence or identity column would not wait for the commit to become visible, so this is the price of it:
+	// concurrent commits queue up here, which is why the sequence is drawn last, after every account is updated.
+	_, err = tx.ExecContext(context, "UPDATE acccore_commit_sequence SET last_sequence = last_sequence + 1")
+	if err != nil {
+		return err
+	}
+	var sequence int64
+	if err = tx.QueryRowContext(context, "SELECT last_sequence FROM acccore_commit_sequence").Scan(&sequence); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_transaction SET commit_sequence = ? WHERE journal_id = ?"),
+		sequence, journalToCommit.GetJournalID())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_journal SET journaling_time = ?, committed = ?, commit_sequence = ? WHERE journal_id = ?"),
+		now, true, sequence, journalToCommit.GetJournalID())
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	journalToCommit.SetJournalingTime(now).SetAmount(amount)
+	for _, trx := range journalToCommit.GetTransactions() {
+		if balance, ok := balances[trx.GetTransactionID()]; ok {
+			trx.SetJournalID(journalToCommit.GetJournalID()).SetTransactionTime(now).SetAccountBalance(balance)
+		}
+	}
+
+	return nil
+}
+
+// CancelJournal Cancel a journal
+// Only non committed journal can be cancelled.
+// Cancelling the journal deletes the journal and all of its Transactions, as if they were never persisted.
+func (jm *SQLJournalManager) CancelJournal(context context.Context, journalToCancel Journal) (err error) {
+	if journalToCancel == nil {
+		return ErrJournalNil
+	}
+
+	store := jm.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var committed bool
+	err = tx.QueryRowContext(context, store.dialect.rebind("SELECT committed FROM acccore_journal WHERE journal_id = ?"+store.dialect.forUpdate()), journalToCancel.GetJournalID()).
+		Scan(&committed)
+	if errors.Is(err, sql.ErrNoRows) {
+		logrus.Errorf("error cancelling journal %s. journal not found.", journalToCancel.GetJournalID())
+		return ErrJournalIDNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if committed {
+		logrus.Errorf("error cancelling journal %s. journal already committed.", journalToCancel.GetJournalID())
+		return ErrJournalAlreadyCommitted
+	}
+
+	_, err = tx.ExecContext(context, store.dialect.rebind("DELETE FROM acccore_transaction WHERE journal_id = ?"), journalToCancel.GetJournalID())
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(context, store.dialect.rebind("DELETE FROM acccore_journal_idempotency_key WHERE journal_id = ?"), journalToCancel.GetJournalID())
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(context, store.dialect.rebind("DELETE FROM acccore_journal WHERE journal_id = ?"), journalToCancel.GetJournalID())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsJournalIDReversed check if the journal with specified ID has been reversed
+func (jm *SQLJournalManager) IsJournalIDReversed(context context.Context, journalID string) (bool, error) {
+	store := jm.store
+	count, err := store.count(context, store.db, "SELECT COUNT(*) FROM acccore_journal WHERE journal_id = ? AND committed = ?", journalID, true)
+	if err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, ErrJournalIDNotFound
+	}
+	count, err = store.count(context, store.db, "SELECT COUNT(*) FROM acccore_journal WHERE reversed_journal_id = ? AND committed = ?", journalID, true)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsJournalIDExist will check if an Journal ID/number is exist in the database.
+// Journals that are not yet committed are also taken into account, as their ID is already taken.
+func (jm *SQLJournalManager) IsJournalIDExist(context context.Context, journalID string) (bool, error) {
+	count, err := jm.store.count(context, jm.store.db, "SELECT COUNT(*) FROM acccore_journal WHERE journal_id = ?", journalID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetJournalByID retrieved a Journal information identified by its ID.
+// the provided ID must be exactly the same, not uses the LIKE select expression.
+// Journals that are not yet committed are not found.
+func (jm *SQLJournalManager) GetJournalByID(context context.Context, journalID string) (Journal, error) {
+	store := jm.store
+	var (
+		journalingTime, createTime     time.Time
+		description, reversedJournalID string
+		createBy, id                   string
+		reversal                       bool
+		amount, exchangeRate           decimal.Decimal
+	)
+	err := store.db.QueryRowContext(context, store.dialect.rebind("SELECT "+sqlJournalColumns+" FROM acccore_journal WHERE journal_id = ? AND committed = ?"), journalID, true).
+		Scan(&id, &journalingTime, &description, &reversal, &reversedJournalID, &amount, &exchangeRate, &createTime, &createBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJournalIDNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	journal := jm.NewJournal(context).SetDescription(description).SetCreateTime(createTime).
+		SetCreateBy(createBy).SetReversal(reversal).
+		SetJournalingTime(journalingTime).SetJournalID(id).SetAmount(amount).SetExchangeRate(exchangeRate)
+
+	var idempotencyKey string
+	err = store.db.QueryRowContext(context, store.dialect.rebind("SELECT idempotency_key FROM acccore_journal_idempotency_key WHERE journal_id = ?"), journalID).
+		Scan(&idempotencyKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	journal.SetIdempotencyKey(idempotencyKey)
+
+	if reversal {
+		reversed, err := jm.GetJournalByID(context, reversedJournalID)
+		if err != nil {
+			return nil, ErrJournalLoadReversalInconsistent
+		}
+		journal.SetReversedJournal(reversed)
+	}
+
+	rows, err := store.db.QueryContext(context, store.dialect.rebind("SELECT "+sqlTransactionColumns+" FROM acccore_transaction WHERE journal_id = ? ORDER BY transaction_id"), journalID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+	journal.SetTransactions(transactions)
+
+	return journal, nil
+}
+
+// GetJournalByIdempotencyKey retrieved the Journal created with the specified idempotency key.
+// Journals that are not yet committed are not found.
+func (jm *SQLJournalManager) GetJournalByIdempotencyKey(context context.Context, idempotencyKey string) (Journal, error) {
+	if len(idempotencyKey) == 0 {
+		return nil, ErrJournalIDNotFound
+	}
+	var journalID string
+	err := jm.store.db.QueryRowContext(context, jm.store.dialect.rebind("SELECT journal_id FROM acccore_journal_idempotency_key WHERE idempotency_key = ?"), idempotencyKey).
+		Scan(&journalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJournalIDNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jm.GetJournalByID(context, journalID)
+}
+
+// ListJournals retrieve list of journals with transaction date between the `from` and `until` time range inclusive.
+// Journals that are not yet committed are not listed.
+// This function uses pagination.
+func (jm *SQLJournalManager) ListJournals(context context.Context, from time.Time, until time.Time, request PageRequest) (PageResult, []Journal, error) {
+	store := jm.store
+	orderBy, err := journalSortRegistry.orderBy(request)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	count, err := store.count(context, store.db, "SELECT COUNT(*) FROM acccore_journal WHERE committed = ? AND journaling_time >= ? AND journaling_time <= ?", true, from.UTC(), until.UTC())
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	pageResult := PageResultFor(request, count)
+
+	rows, err := store.db.QueryContext(context, store.dialect.rebind("SELECT journal_id FROM acccore_journal WHERE committed = ? AND journaling_time >= ? AND journaling_time <= ? ORDER BY "+orderBy+" LIMIT ? OFFSET ?"),
+		true, from.UTC(), until.UTC(), pageResult.PageSize, pageResult.Offset)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	journalIDs := make([]string, 0, pageResult.PageSize)
+	for rows.Next() {
+		var journalID string
+		if err := rows.Scan(&journalID); err != nil {
+			_ = rows.Close()
+			return PageResult{}, nil, err
+		}
+		journalIDs = append(journalIDs, journalID)
+	}
+	if err := rows.Close(); err != nil {
+		return PageResult{}, nil, err
+	}
+
+	journals := make([]Journal, len(journalIDs))
+	for i, journalID := range journalIDs {
+		journal, err := jm.GetJournalByID(context, journalID)
+		if err != nil {
+			return PageResult{}, nil, err
+		}
+		journals[i] = journal
+	}
+	return pageResult, journals, nil
+}
+
+// ListJournalsByCursor retrieve list of journals with transaction date between the `from` and `until` time range inclusive,
+// ordered by the sequence they were committed in, then by their journaling time and ID.
+// Journals that are not yet committed are not listed.
+// This function uses cursor pagination.
+func (jm *SQLJournalManager) ListJournalsByCursor(context context.Context, from time.Time, until time.Time, request CursorRequest) (CursorResult, []Journal, error) {
+	store := jm.store
+	request, position, err := decodeCursorRequest(store.getCursorCodec(), journalCursorListing, request)
+	if err != nil {
+		return CursorResult{}, nil, err
+	}
+	keyset, keysetArgs, orderBy := position.sqlKeyset("commit_sequence", "journaling_time", "journal_id")
+
+	args := append([]any{true, from.UTC(), until.UTC()}, keysetArgs...)
+	rows, err := store.db.QueryContext(context, store.dialect.rebind("SELECT commit_sequence, journaling_time, journal_id FROM acccore_journal WHERE committed = ? AND journaling_time >= ? AND journaling_time <= ?"+keyset+" ORDER BY "+orderBy+" LIMIT ?"),
+		append(args, request.ItemSize+1)...)
+	if err != nil {
+		return CursorResult{}, nil, err
+	}
+	keys := make([]cursorKey, 0, request.ItemSize+1)
+	for rows.Next() {
+		key := cursorKey{}
+		if err := rows.Scan(&key.Sequence, &key.Time, &key.ID); err != nil {
+			_ = rows.Close()
+			return CursorResult{}, nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Close(); err != nil {
+		return CursorResult{}, nil, err
+	}
+
+	result, keys := cursorPage(store.getCursorCodec(), journalCursorListing, request, position, keys,
+		func(key cursorKey) cursorKey { return key })
+	journals := make([]Journal, len(keys))
+	for i, key := range keys {
+		journal, err := jm.GetJournalByID(context, key.ID)
+		if err != nil {
+			return CursorResult{}, nil, err
+		}
+		journals[i] = journal
+	}
+	return result, journals, nil
+}
+
+// StreamJournals streams all the journals with transaction date between the `from` and `until` time range inclusive,
+// ordered by the sequence they were committed in, then by their journaling time and ID, without loading them all at once.
+// Journals that are not yet committed are not streamed.
+func (jm *SQLJournalManager) StreamJournals(context context.Context, from time.Time, until time.Time) iter.Seq2[Journal, error] {
+	return streamCursor(context, func(request CursorRequest) (CursorResult, []Journal, error) {
+		return jm.ListJournalsByCursor(context, from, until, request)
+	})
+}
+
+// RenderJournal Render this journal into string for easy inspection
+func (jm *SQLJournalManager) RenderJournal(context context.Context, journal Journal) string {
+	return renderJournal(journal)
+}
+
+// SQLAccountManager implementation of AccountManager using database/sql
+type SQLAccountManager struct {
+	store *SQLStore
+}
+
+// NewAccount will create a new blank un-persisted account.
+func (am *SQLAccountManager) NewAccount(context context.Context) Account {
+	return &BaseAccount{}
+}
+
+// PersistAccount will save the account into database.
+// will throw error if the account already persisted
+func (am *SQLAccountManager) PersistAccount(context context.Context, AccountToPersist Account) error {
+	if len(AccountToPersist.GetAccountNumber()) == 0 {
+		return ErrAccountMissingID
+	}
+	if len(AccountToPersist.GetName()) == 0 {
+		return ErrAccountMissingName
+	}
+	if len(AccountToPersist.GetDescription()) == 0 {
+		return ErrAccountMissingDescription
+	}
+	if len(AccountToPersist.GetCreateBy()) == 0 {
+		return ErrAccountMissingCreator
+	}
+	if err := validateOverdraftLimit(AccountToPersist); err != nil {
+		return err
+	}
+	if err := checkSQLAmountPrecision(AccountToPersist.GetBalance(), AccountToPersist.GetOverdraftLimit()); err != nil {
+		return err
+	}
+
+	exist, err := am.IsAccountIDExist(context, AccountToPersist.GetAccountNumber())
+	if err != nil {
+		return err
+	}
+	if exist {
+		return ErrAccountAlreadyPersisted
+	}
+
+	now := time.Now().UTC()
+	_, err = am.store.db.ExecContext(context, am.store.dialect.rebind("INSERT INTO acccore_account ("+sqlAccountColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		AccountToPersist.GetAccountNumber(), AccountToPersist.GetCurrency(), AccountToPersist.GetName(), AccountToPersist.GetDescription(),
+		AccountToPersist.GetAlignment(), AccountToPersist.GetBalance(), AccountToPersist.GetBalanceLimit(), AccountToPersist.GetOverdraftLimit(), AccountActive, AccountToPersist.GetCOA(),
+		now, AccountToPersist.GetCreateBy(), now, AccountToPersist.GetUpdateBy(), 1)
+	if am.store.dialect.isUniqueViolation(err) {
+		// the account was persisted concurrently, after it was checked above.
+		logrus.Errorf("error persisting account %s. account is already persisted", AccountToPersist.GetAccountNumber())
+		return ErrAccountAlreadyPersisted
+	}
+	if err != nil {
+		return err
+	}
+	AccountToPersist.SetVersion(1).SetState(AccountActive)
+	return nil
+}
+
+// UpdateAccount will update the account database to reflect to the provided account information.
+// This update account function will fail if the account ID/number is not existing in the database.
+// It will fail with ErrAccountConcurrentModification if the account have been modified since it was read.
+// The Balance, Currency and Alignment of the account are not written, the Balance only moves by committing journals.
+func (am *SQLAccountManager) UpdateAccount(context context.Context, AccountToUpdate Account) error {
+	if len(AccountToUpdate.GetAccountNumber()) == 0 {
+		return ErrAccountMissingID
+	}
+	if len(AccountToUpdate.GetName()) == 0 {
+		return ErrAccountMissingName
+	}
+	if len(AccountToUpdate.GetDescription()) == 0 {
+		return ErrAccountMissingDescription
+	}
+	if len(AccountToUpdate.GetCreateBy()) == 0 {
+		return ErrAccountMissingCreator
+	}
+	if err := validateOverdraftLimit(AccountToUpdate); err != nil {
+		return err
+	}
+	if err := checkSQLAmountPrecision(AccountToUpdate.GetOverdraftLimit()); err != nil {
+		return err
+	}
+
+	result, err := am.store.db.ExecContext(context, am.store.dialect.rebind("UPDATE acccore_account SET name = ?, description = ?, balance_limit = ?, overdraft_limit = ?, coa = ?, update_time = ?, update_by = ?, version = ? WHERE account_number = ? AND version = ?"),
+		AccountToUpdate.GetName(), AccountToUpdate.GetDescription(), AccountToUpdate.GetBalanceLimit(), AccountToUpdate.GetOverdraftLimit(), AccountToUpdate.GetCOA(), time.Now().UTC(), AccountToUpdate.GetUpdateBy(),
+		AccountToUpdate.GetVersion()+1, AccountToUpdate.GetAccountNumber(), AccountToUpdate.GetVersion())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// either the account is not there, or its version have moved.
+		exist, err := am.IsAccountIDExist(context, AccountToUpdate.GetAccountNumber())
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return ErrAccountIsNotPersisted
+		}
+		logrus.Errorf("error updating account %s. account version %d is not the current version", AccountToUpdate.GetAccountNumber(), AccountToUpdate.GetVersion())
+		return ErrAccountConcurrentModification
+	}
+	AccountToUpdate.SetVersion(AccountToUpdate.GetVersion() + 1)
+	return nil
+}
+
+// IsAccountIDExist will check if an account ID/number is exist in the database.
+func (am *SQLAccountManager) IsAccountIDExist(context context.Context, id string) (bool, error) {
+	count, err := am.store.count(context, am.store.db, "SELECT COUNT(*) FROM acccore_account WHERE account_number = ?", id)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetAccountByID retrieve an account information by specifying the ID/number
+func (am *SQLAccountManager) GetAccountByID(context context.Context, id string) (Account, error) {
+	row := am.store.db.QueryRowContext(context, am.store.dialect.rebind("SELECT "+sqlAccountColumns+" FROM acccore_account WHERE account_number = ?"), id)
+	account, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountIDNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// ListAccounts list all account in the database.
+// This function uses pagination
+func (am *SQLAccountManager) ListAccounts(context context.Context, request PageRequest) (PageResult, []Account, error) {
+	return am.listAccounts(context, "", request)
+}
+
+// StreamAccounts streams all account in the database ordered by their account number, without loading them all at once.
+func (am *SQLAccountManager) StreamAccounts(context context.Context) iter.Seq2[Account, error] {
+	return streamAccounts(context, func(afterAccountNumber string, limit int) ([]Account, error) {
+		store := am.store
+		rows, err := store.db.QueryContext(context, store.dialect.rebind("SELECT "+sqlAccountColumns+" FROM acccore_account WHERE account_number > ? ORDER BY account_number LIMIT ?"),
+			afterAccountNumber, limit)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		accounts := make([]Account, 0, limit)
+		for rows.Next() {
+			account, err := scanAccount(rows)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, account)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return accounts, nil
+	})
+}
+
+// ListAccountByCOA returns list of accounts that have the same COA number.
+// This function uses pagination
+func (am *SQLAccountManager) ListAccountByCOA(context context.Context, coa string, request PageRequest) (PageResult, []Account, error) {
+	return am.listAccounts(context, "coa = ?", request, coa)
+}
+
+// FindAccounts returns list of accounts that have their Name contains a substring of specified parameter.
+// this search should  be case insensitive.
+func (am *SQLAccountManager) FindAccounts(context context.Context, nameLike string, request PageRequest) (PageResult, []Account, error) {
+	lookup := "%" + strings.ToUpper(strings.ReplaceAll(nameLike, "%", "")) + "%"
+	return am.listAccounts(context, "UPPER(name) LIKE ?", request, lookup)
+}
+
+// ChangeAccountState moves the account into the new state, and records the change with its reason and author for audit.
+// A closed account can not change its state anymore, and an account can only be closed once its Balance is zero.
+// Changing the state increments the account version. Changing into the current state does nothing.
+func (am *SQLAccountManager) ChangeAccountState(context context.Context, accountNumber string, state AccountState, reason, changedBy string) (err error) {
+	if len(changedBy) == 0 {
+		return ErrAccountMissingUpdater
+	}
+
+	store := am.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		current AccountState
+		balance decimal.Decimal
+		version int64
+	)
+	err = tx.QueryRowContext(context, store.dialect.rebind("SELECT state, balance, version FROM acccore_account WHERE account_number = ?"+store.dialect.forUpdate()), accountNumber).
+		Scan(&current, &balance, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAccountIDNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current == state {
+		return tx.Commit()
+	}
+	if err = validateStateChange(current, state, balance); err != nil {
+		logrus.Errorf("error changing account %s state from %s into %s. got %s", accountNumber, current, state, err.Error())
+		return err
+	}
+
+	now := time.Now().UTC()
+	var result sql.Result
+	result, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_account SET state = ?, update_time = ?, update_by = ?, version = ? WHERE account_number = ? AND version = ?"),
+		state, now, changedBy, version+1, accountNumber, version)
+	if err != nil {
+		return err
+	}
+	var affected int64
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		logrus.Errorf("error changing account %s state. account have been modified concurrently", accountNumber)
+		return ErrAccountConcurrentModification
+	}
+	_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_account_state_change (account_number, version, from_state, to_state, reason, change_time, change_by) VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		accountNumber, version+1, current, state, reason, now, changedBy)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListAccountStateChanges returns all the state changes of the account, oldest first.
+func (am *SQLAccountManager) ListAccountStateChanges(context context.Context, accountNumber string) ([]*AccountStateChange, error) {
+	exist, err := am.IsAccountIDExist(context, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, ErrAccountIDNotFound
+	}
+	rows, err := am.store.db.QueryContext(context, am.store.dialect.rebind("SELECT account_number, version, from_state, to_state, reason, change_time, change_by FROM acccore_account_state_change WHERE account_number = ? ORDER BY version"), accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	changes := make([]*AccountStateChange, 0)
+	for rows.Next() {
+		change := &AccountStateChange{}
+		if err := rows.Scan(&change.AccountNumber, &change.Version, &change.From, &change.To, &change.Reason, &change.ChangeTime, &change.ChangeBy); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// listAccounts list the accounts matching the where clause, ordered following the request sorts.
+func (am *SQLAccountManager) listAccounts(context context.Context, where string, request PageRequest, args ...any) (PageResult, []Account, error) {
+	store := am.store
+	orderBy, err := accountSortRegistry.orderBy(request)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	if len(where) > 0 {
+		where = " WHERE " + where
+	}
+	count, err := store.count(context, store.db, "SELECT COUNT(*) FROM acccore_account"+where, args...)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	pageResult := PageResultFor(request, count)
+
+	rows, err := store.db.QueryContext(context, store.dialect.rebind("SELECT "+sqlAccountColumns+" FROM acccore_account"+where+" ORDER BY "+orderBy+" LIMIT ? OFFSET ?"),
+		append(args, pageResult.PageSize, pageResult.Offset)...)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	defer rows.Close()
+	accounts := make([]Account, 0, pageResult.PageSize)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return PageResult{}, nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return PageResult{}, nil, err
+	}
+	return pageResult, accounts, nil
+}
+
+// scanAccount scan a row of sqlAccountColumns into a BaseAccount
+func scanAccount(row sqlScanner) (Account, error) {
+	account := &BaseAccount{}
+	err := row.Scan(&account.AccountNumber, &account.Currency, &account.Name, &account.Description, &account.Alignment,
+		&account.Balance, &account.BalanceLimit, &account.Overdraft, &account.State, &account.COA, &account.CreateTime, &account.CreateBy, &account.UpdateTime, &account.UpdateBy, &account.Version)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// SQLCOAManager implementation of COAManager using database/sql
+type SQLCOAManager struct {
+	store *SQLStore
+}
+
+// NewCOA will create a new blank un-persisted COA.
+func (cm *SQLCOAManager) NewCOA(context context.Context) COA {
+	return &BaseCOA{}
+}
+
+// PersistCOA will save the COA into database.
+// will throw error if the COA already persisted, if its parent is not persisted,
+// or if its category is not the same as its parent category.
+func (cm *SQLCOAManager) PersistCOA(context context.Context, coaToPersist COA) error {
+	if len(coaToPersist.GetCode()) == 0 {
+		return ErrCOAMissingCode
+	}
+	if len(coaToPersist.GetName()) == 0 {
+		return ErrCOAMissingName
+	}
+	if len(coaToPersist.GetCreateBy()) == 0 {
+		return ErrCOAMissingCreator
+	}
+
+	exist, err := cm.IsCOAExist(context, coaToPersist.GetCode())
+	if err != nil {
+		return err
+	}
+	if exist {
+		return ErrCOAAlreadyPersisted
+	}
+	if len(coaToPersist.GetParentCode()) > 0 {
+		parent, err := cm.GetCOAByCode(context, coaToPersist.GetParentCode())
+		if errors.Is(err, ErrCOANotFound) {
+			logrus.Errorf("error persisting COA %s. parent %s not found", coaToPersist.GetCode(), coaToPersist.GetParentCode())
+			return ErrCOAParentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if parent.GetCategory() != coaToPersist.GetCategory() {
+			logrus.Errorf("error persisting COA %s. category %d is not the parent %s category %d", coaToPersist.GetCode(), coaToPersist.GetCategory(), parent.GetCode(), parent.GetCategory())
+			return ErrCOACategoryMismatch
+		}
+	}
+
+	_, err = cm.store.db.ExecContext(context, cm.store.dialect.rebind("INSERT INTO acccore_coa ("+sqlCOAColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+		coaToPersist.GetCode(), coaToPersist.GetParentCode(), coaToPersist.GetName(), coaToPersist.GetDescription(),
+		coaToPersist.GetCategory(), coaToPersist.GetAlignment(), time.Now().UTC(), coaToPersist.GetCreateBy())
+	return err
+}
+
+// IsCOAExist will check if a COA code is exist in the database.
+func (cm *SQLCOAManager) IsCOAExist(context context.Context, code string) (bool, error) {
+	count, err := cm.store.count(context, cm.store.db, "SELECT COUNT(*) FROM acccore_coa WHERE code = ?", code)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetCOAByCode retrieve a COA information by specifying its code
+func (cm *SQLCOAManager) GetCOAByCode(context context.Context, code string) (COA, error) {
+	row := cm.store.db.QueryRowContext(context, cm.store.dialect.rebind("SELECT "+sqlCOAColumns+" FROM acccore_coa WHERE code = ?"), code)
+	coa, err := scanCOA(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCOANotFound
+	}
+	return coa, err
+}
+
+// ListCOAChildren returns the direct children of the COA with the specified code, ordered by their code.
+// An empty parent code lists the roots of the chart.
+func (cm *SQLCOAManager) ListCOAChildren(context context.Context, parentCode string) ([]COA, error) {
+	if len(parentCode) > 0 {
+		exist, err := cm.IsCOAExist(context, parentCode)
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			return nil, ErrCOANotFound
+		}
+	}
+	rows, err := cm.store.db.QueryContext(context, cm.store.dialect.rebind("SELECT "+sqlCOAColumns+" FROM acccore_coa WHERE parent_code = ? ORDER BY code"), parentCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	children := make([]COA, 0)
+	for rows.Next() {
+		coa, err := scanCOA(rows)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, coa)
+	}
+	return children, rows.Err()
+}
+
+// scanCOA scan a row of sqlCOAColumns into a BaseCOA
+func scanCOA(row sqlScanner) (COA, error) {
+	coa := &BaseCOA{}
+	err := row.Scan(&coa.Code, &coa.ParentCode, &coa.Name, &coa.Description, &coa.Category, &coa.Alignment, &coa.CreateTime, &coa.CreateBy)
+	if err != nil {
+		return nil, err
+	}
+	return coa, nil
+}
+
+// SQLTransactionManager implementation of TransactionManager using database/sql
+type SQLTransactionManager struct {
+	store *SQLStore
+}
+
+// NewTransaction will create new blank un-persisted Transaction
+func (tm *SQLTransactionManager) NewTransaction(context context.Context) Transaction {
+	return &BaseTransaction{}
+}
+
+// IsTransactionIDExist will check if an Transaction ID/number is exist in the database.
+// Transactions that are not yet committed are also taken into account, as their ID is already taken.
+func (tm *SQLTransactionManager) IsTransactionIDExist(context context.Context, id string) (bool, error) {
+	count, err := tm.store.count(context, tm.store.db, "SELECT COUNT(*) FROM acccore_transaction WHERE transaction_id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetTransactionByID will retrieve one single transaction that identified by some ID
+// Transactions that are not yet committed are not found.
+func (tm *SQLTransactionManager) GetTransactionByID(context context.Context, id string) (Transaction, error) {
+	row := tm.store.db.QueryRowContext(context, tm.store.dialect.rebind("SELECT "+sqlTransactionColumns+" FROM acccore_transaction WHERE transaction_id = ? AND committed = ?"), id, true)
+	transaction, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// ListTransactionsOnAccount retrieves list of Transactions that belongs to this account
+// that transaction happens between the `from` and `until` time range inclusive.
+// Transactions that are not yet committed are not listed.
+// This function uses pagination
+func (tm *SQLTransactionManager) ListTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account, request PageRequest) (PageResult, []Transaction, error) {
+	store := tm.store
+	orderBy, err := transactionSortRegistry.orderBy(request)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	count, err := store.count(context, store.db, "SELECT COUNT(*) FROM acccore_transaction WHERE account_number = ? AND committed = ? AND transaction_time >= ? AND transaction_time <= ?",
+		account.GetAccountNumber(), true, from.UTC(), until.UTC())
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	pageResult := PageResultFor(request, count)
+
+	rows, err := store.db.QueryContext(context, store.dialect.rebind("SELECT "+sqlTransactionColumns+" FROM acccore_transaction WHERE account_number = ? AND committed = ? AND transaction_time >= ? AND transaction_time <= ? ORDER BY "+orderBy+" LIMIT ? OFFSET ?"),
+		account.GetAccountNumber(), true, from.UTC(), until.UTC(), pageResult.PageSize, pageResult.Offset)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return PageResult{}, nil, err
+	}
+	return pageResult, transactions, nil
+}
+
+// ListTransactionsOnAccountByCursor retrieves list of Transactions that belongs to this account
+// that transaction happens between the `from` and `until` time range inclusive, ordered by the sequence they were committed in,
+// then by their transaction time and ID.
+// Transactions that are not yet committed are not listed.
+// This function uses cursor pagination.
+func (tm *SQLTransactionManager) ListTransactionsOnAccountByCursor(context context.Context, from time.Time, until time.Time, account Account, request CursorRequest) (CursorResult, []Transaction, error) {
+	store := tm.store
+	listing := transactionCursorListing(account)
+	request, position, err := decodeCursorRequest(store.getCursorCodec(), listing, request)
+	if err != nil {
+		return CursorResult{}, nil, err
+	}
+	keyset, keysetArgs, orderBy := position.sqlKeyset("commit_sequence", "transaction_time", "transaction_id")
+
+	args := append([]any{account.GetAccountNumber(), true, from.UTC(), until.UTC()}, keysetArgs...)
+	rows, err := store.db.QueryContext(context, store.dialect.rebind("SELECT "+sqlTransactionColumns+", commit_sequence FROM acccore_transaction WHERE account_number = ? AND committed = ? AND transaction_time >= ? AND transaction_time <= ?"+keyset+" ORDER BY "+orderBy+" LIMIT ?"),
+		append(args, request.ItemSize+1)...)
+	if err != nil {
+		return CursorResult{}, nil, err
+	}
+	defer rows.Close()
+	type sequencedTransaction struct {
+		transaction Transaction
+		sequence    int64
+	}
+	sequenced := make([]sequencedTransaction, 0, request.ItemSize+1)
+	for rows.Next() {
+		item := sequencedTransaction{}
+		if item.transaction, err = scanTransaction(rows, &item.sequence); err != nil {
+			return CursorResult{}, nil, err
+		}
+		sequenced = append(sequenced, item)
+	}
+	if err := rows.Err(); err != nil {
+		return CursorResult{}, nil, err
+	}
+
+	result, sequenced := cursorPage(store.getCursorCodec(), listing, request, position, sequenced,
+		func(item sequencedTransaction) cursorKey {
+			return cursorKey{Sequence: item.sequence, Time: item.transaction.GetTransactionTime(), ID: item.transaction.GetTransactionID()}
+		})
+	transactions := make([]Transaction, len(sequenced))
+	for i, item := range sequenced {
+		transactions[i] = item.transaction
+	}
+	return result, transactions, nil
+}
+
+// StreamTransactionsOnAccount streams all the Transactions that belongs to this account
+// that transaction happens between the `from` and `until` time range inclusive, ordered by the sequence they were committed in,
+// then by their transaction time and ID, without loading them all at once.
+// Transactions that are not yet committed are not streamed.
+func (tm *SQLTransactionManager) StreamTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account) iter.Seq2[Transaction, error] {
+	return streamCursor(context, func(request CursorRequest) (CursorResult, []Transaction, error) {
+		return tm.ListTransactionsOnAccountByCursor(context, from, until, account, request)
+	})
+}
+
+// GetBalanceAt retrieves the Balance of the account at the specified time, which is the account Balance
+// recorded on its last committed transaction at or before that time.
+// The Balance is zero if the account have no transaction up to that time.
+func (tm *SQLTransactionManager) GetBalanceAt(context context.Context, accountNumber string, at time.Time) (decimal.Decimal, error) {
+	return tm.balanceAt(context, tm.store.db, accountNumber, at)
+}
+
+// GetBalancesAt retrieves the Balance of many accounts at the specified time, keyed by their account number.
+// All the balances are read within a single database transaction, so they are consistent with each other.
+func (tm *SQLTransactionManager) GetBalancesAt(context context.Context, accountNumbers []string, at time.Time) (map[string]decimal.Decimal, error) {
+	tx, err := tm.store.db.BeginTx(context, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	balances := make(map[string]decimal.Decimal, len(accountNumbers))
+	for _, accountNumber := range accountNumbers {
+		balance, err := tm.balanceAt(context, tx, accountNumber, at)
+		if err != nil {
+			return nil, err
+		}
+		balances[accountNumber] = balance
+	}
+	return balances, nil
+}
+
+// balanceAt reads the account Balance recorded on the last committed transaction of the account at or before the specified time.
+func (tm *SQLTransactionManager) balanceAt(context context.Context, q sqlQuerier, accountNumber string, at time.Time) (decimal.Decimal, error) {
+	store := tm.store
+	count, err := store.count(context, q, "SELECT COUNT(*) FROM acccore_account WHERE account_number = ?", accountNumber)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if count == 0 {
+		logrus.Errorf("error getting balance of account %s. account not found.", accountNumber)
+		return decimal.Zero, ErrAccountIDNotFound
+	}
+
+	var balance decimal.Decimal
+	err = q.QueryRowContext(context, store.dialect.rebind("SELECT account_balance FROM acccore_transaction WHERE account_number = ? AND committed = ? AND transaction_time <= ? ORDER BY transaction_time DESC, commit_sequence DESC, transaction_id DESC LIMIT 1"),
+		accountNumber, true, at.UTC()).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Zero, nil
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return balance, nil
+}
+
+// RenderTransactionsOnAccount Render list of transaction been down on an account in a time span
+func (tm *SQLTransactionManager) RenderTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account, request PageRequest) (string, error) {
+	result, transactions, err := tm.ListTransactionsOnAccount(context, from, until, account, request)
+	if err != nil {
+		return "Error rendering", err
+	}
+
+	return renderTransactionsOnAccount(from, until, account, result, transactions), nil
+}
+
+// scanTransaction scan a row of sqlTransactionColumns into a BaseTransaction, followed by the extra columns if any
+func scanTransaction(row sqlScanner, extra ...any) (Transaction, error) {
+	trx := &BaseTransaction{}
+	err := row.Scan(append([]any{&trx.TransactionID, &trx.TransactionTime, &trx.AccountNumber, &trx.JournalID, &trx.Description,
+		&trx.TransactionType, &trx.Amount, &trx.AccountBalance, &trx.CreateTime, &trx.CreateBy}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	return trx, nil
+}
+
+// scanTransactions scan all rows of sqlTransactionColumns into BaseTransactions and close the rows.
+func scanTransactions(rows *sql.Rows) ([]Transaction, error) {
+	defer rows.Close()
+	transactions := make([]Transaction, 0)
+	for rows.Next() {
+		trx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, trx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// SQLExchangeManager implementation of ExchangeManager using database/sql
+type SQLExchangeManager struct {
+	store *SQLStore
+}
+
+// IsCurrencyExist will check in the exchange system for a Currency existance
+// non-existent Currency means that the Currency is not supported.
+// error should be thrown if only there's an underlying error such as db error.
+func (em *SQLExchangeManager) IsCurrencyExist(context context.Context, currency string) (bool, error) {
+	count, err := em.store.count(context, em.store.db, "SELECT COUNT(*) FROM acccore_currency WHERE code = ?", currency)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetDenom get the current common denominator used in the exchange
+func (em *SQLExchangeManager) GetDenom(context context.Context) decimal.Decimal {
+	em.store.denomMutex.RLock()
+	defer em.store.denomMutex.RUnlock()
+	return em.store.commonDenominator
+}
+
+// SetDenom set the current common denominator value into the specified value
+func (em *SQLExchangeManager) SetDenom(context context.Context, denom decimal.Decimal) {
+	em.store.denomMutex.Lock()
+	defer em.store.denomMutex.Unlock()
+	em.store.commonDenominator = denom
+}
+
+// ListCurrencies will list all currencies.
+func (em *SQLExchangeManager) ListCurrencies(context context.Context) ([]Currency, error) {
+	rows, err := em.store.db.QueryContext(context, "SELECT "+sqlCurrencyColumns+" FROM acccore_currency ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ret := make([]Currency, 0)
+	for rows.Next() {
+		cur, err := scanCurrency(rows)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, cur)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GetCurrency retrieve currency data indicated by the code argument
+func (em *SQLExchangeManager) GetCurrency(context context.Context, code string) (Currency, error) {
+	row := em.store.db.QueryRowContext(context, em.store.dialect.rebind("SELECT "+sqlCurrencyColumns+" FROM acccore_currency WHERE code = ?"), code)
+	cur, err := scanCurrency(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCurrencyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// CreateCurrency set the specified value as denominator value for that speciffic Currency.
+// This function should return error if the Currency specified is not exist.
+// The exchange value starts the Currency rate history.
+func (em *SQLExchangeManager) CreateCurrency(context context.Context, code, name string, exchange decimal.Decimal, author string) (cur Currency, err error) {
+	store := em.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	count, err := store.count(context, tx, "SELECT COUNT(*) FROM acccore_currency WHERE code = ?", code)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrCurrencyAlreadyPersisted
+	}
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_currency ("+sqlCurrencyColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		code, name, exchange, 0, RoundingNone, now, author, now, author)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_currency_rate ("+sqlCurrencyRateColumns+") VALUES (?, ?, ?, ?, ?)"),
+		code, exchange, now, nil, author)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &BaseCurrency{
+		Code:       code,
+		Name:       name,
+		Exchange:   exchange,
+		CreateTime: now,
+		CreateBy:   author,
+		UpdateTime: now,
+		UpdateBy:   author,
+	}, nil
+}
+
+// UpdateCurrency updates the currency data
+// Error should be returned if the specified Currency is not exist.
+// Changing the exchange value ends the rate in effect and starts a new one in the Currency rate history.
+func (em *SQLExchangeManager) UpdateCurrency(context context.Context, code string, currency Currency, author string) (err error) {
+	if err = validateCurrencyPrecision(currency); err != nil {
+		return err
+	}
+	store := em.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exchange decimal.Decimal
+	err = tx.QueryRowContext(context, store.dialect.rebind("SELECT exchange FROM acccore_currency WHERE code = ?"+store.dialect.forUpdate()), code).
+		Scan(&exchange)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCurrencyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_currency SET name = ?, exchange = ?, scale = ?, rounding_mode = ?, update_time = ?, update_by = ? WHERE code = ?"),
+		currency.GetName(), currency.GetExchange(), currency.GetScale(), currency.GetRoundingMode(), now, author, code)
+	if err != nil {
+		return err
+	}
+	if !exchange.Equal(currency.GetExchange()) {
+		_, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_currency_rate SET valid_to = ? WHERE code = ? AND valid_to IS NULL"),
+			now, code)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_currency_rate ("+sqlCurrencyRateColumns+") VALUES (?, ?, ?, ?, ?)"),
+			code, currency.GetExchange(), now, nil, author)
+		if err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	currency.SetCode(code)
+	return nil
+}
+
+// UpdateCurrencyRates changes the exchange values of the currencies all at once, each new exchange value
+// taking effect at the validFrom time in the Currency rate history. The currencies are updated within a single database transaction.
+func (em *SQLExchangeManager) UpdateCurrencyRates(context context.Context, rates map[string]decimal.Decimal, validFrom time.Time, author string) (err error) {
+	store := em.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// the currencies are locked in the order of their code, so concurrent updates do not deadlock.
+	codes := make([]string, 0, len(rates))
+	for code := range rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	now := time.Now().UTC()
+	for _, code := range codes {
+		var exchange decimal.Decimal
+		err = tx.QueryRowContext(context, store.dialect.rebind("SELECT exchange FROM acccore_currency WHERE code = ?"+store.dialect.forUpdate()), code).
+			Scan(&exchange)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCurrencyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if exchange.Equal(rates[code]) {
+			continue
+		}
+		var inEffectFrom time.Time
+		err = tx.QueryRowContext(context, store.dialect.rebind("SELECT valid_from FROM acccore_currency_rate WHERE code = ? AND valid_to IS NULL"), code).
+			Scan(&inEffectFrom)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && !validFrom.After(inEffectFrom) {
+			logrus.Errorf("error updating exchange rate of %s. rate in effect since %s, new rate from %s", code, inEffectFrom, validFrom)
+			return ErrCurrencyRateOutOfOrder
+		}
+		_, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_currency SET exchange = ?, update_time = ?, update_by = ? WHERE code = ?"),
+			rates[code], now, author, code)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_currency_rate SET valid_to = ? WHERE code = ? AND valid_to IS NULL"),
+			validFrom.UTC(), code)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_currency_rate ("+sqlCurrencyRateColumns+") VALUES (?, ?, ?, ?, ?)"),
+			code, rates[code], validFrom.UTC(), nil, author)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListCurrencyRates lists the history of the Currency exchange values, the oldest first.
+func (em *SQLExchangeManager) ListCurrencyRates(context context.Context, code string) ([]*CurrencyRate, error) {
+	exist, err := em.IsCurrencyExist(context, code)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, ErrCurrencyNotFound
+	}
+	rows, err := em.store.db.QueryContext(context, em.store.dialect.rebind("SELECT "+sqlCurrencyRateColumns+" FROM acccore_currency_rate WHERE code = ? ORDER BY valid_from"), code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ret := make([]*CurrencyRate, 0)
+	for rows.Next() {
+		rate, err := scanCurrencyRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// CalculateExchangeRate gets the Currency exchange rate for exchanging between the two Currency.
+// if any of the Currency is not exist, an error should be returned.
+// if from and to Currency is equal, this must return 1.0
+func (em *SQLExchangeManager) CalculateExchangeRate(context context.Context, fromCurrency, toCurrency string) (decimal.Decimal, error) {
+	from, err := em.GetCurrency(context, fromCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	to, err := em.GetCurrency(context, toCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return exchangeRate(em.GetDenom(context), from.GetExchange(), to.GetExchange()), nil
+}
+
+// CalculateExchange gets the Currency exchange value for the Amount of fromCurrency into toCurrency.
+// If any of the Currency is not exist, an error should be returned.
+// if from and to Currency is equal, the returned Amount must be equal to the Amount in the argument.
+func (em *SQLExchangeManager) CalculateExchange(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	exchanged, _, err := em.CalculateExchangeWithRemainder(context, fromCurrency, toCurrency, amount)
+	return exchanged, err
+}
+
+// CalculateExchangeWithRemainder gets the exchange value just like CalculateExchange, together with the remainder
+// left by rounding it to the scale of toCurrency.
+func (em *SQLExchangeManager) CalculateExchangeWithRemainder(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	exchange, err := em.CalculateExchangeRate(context, fromCurrency, toCurrency)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	to, err := em.GetCurrency(context, toCurrency)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	exchanged, remainder := RoundCurrencyAmount(to, exchange.Mul(amount))
+	return exchanged, remainder, nil
+}
+
+// CalculateExchangeRateAt gets the exchange rate between the two Currency, using the exchange values in effect at the specified time.
+func (em *SQLExchangeManager) CalculateExchangeRateAt(context context.Context, fromCurrency, toCurrency string, at time.Time) (decimal.Decimal, error) {
+	exchanges := make([]decimal.Decimal, 0, 2)
+	for _, code := range []string{fromCurrency, toCurrency} {
+		exist, err := em.IsCurrencyExist(context, code)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if !exist {
+			return decimal.Zero, ErrCurrencyNotFound
+		}
+		var exchange decimal.Decimal
+		err = em.store.db.QueryRowContext(context, em.store.dialect.rebind("SELECT exchange FROM acccore_currency_rate WHERE code = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)"),
+			code, at.UTC(), at.UTC()).Scan(&exchange)
+		if errors.Is(err, sql.ErrNoRows) {
+			return decimal.Zero, ErrCurrencyRateNotFound
+		}
+		if err != nil {
+			return decimal.Zero, err
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchangeRate(em.GetDenom(context), exchanges[0], exchanges[1]), nil
+}
+
+// CalculateExchangeAt gets the exchange value for the Amount of fromCurrency into toCurrency,
+// using the exchange values in effect at the specified time.
+func (em *SQLExchangeManager) CalculateExchangeAt(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal, at time.Time) (decimal.Decimal, error) {
+	exchange, err := em.CalculateExchangeRateAt(context, fromCurrency, toCurrency, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	to, err := em.GetCurrency(context, toCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	exchanged, _ := RoundCurrencyAmount(to, exchange.Mul(amount))
+	return exchanged, nil
+}
+
+// scanCurrencyRate scan a row of sqlCurrencyRateColumns into a CurrencyRate
+func scanCurrencyRate(row sqlScanner) (*CurrencyRate, error) {
+	rate := &CurrencyRate{}
+	var validTo sql.NullTime
+	err := row.Scan(&rate.Code, &rate.Exchange, &rate.ValidFrom, &validTo, &rate.CreateBy)
+	if err != nil {
+		return nil, err
+	}
+	if validTo.Valid {
+		rate.ValidTo = validTo.Time
+	}
+	return rate, nil
+}
+
+// scanCurrency scan a row of sqlCurrencyColumns into a BaseCurrency
+func scanCurrency(row sqlScanner) (Currency, error) {
+	cur := &BaseCurrency{}
+	err := row.Scan(&cur.Code, &cur.Name, &cur.Exchange, &cur.Scale, &cur.RoundingMode, &cur.CreateTime, &cur.CreateBy, &cur.UpdateTime, &cur.UpdateBy)
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// SQLHoldManager implementation of HoldManager using database/sql
+type SQLHoldManager struct {
+	store *SQLStore
+}
+
+// NewHold will create a new blank un-persisted hold.
+func (hm *SQLHoldManager) NewHold(context context.Context) Hold {
+	return &BaseHold{}
+}
+
+// PlaceHold will save the active hold into database, reserving its amount from the account available Balance.
+// It fails with an InsufficientBalanceError if the available Balance would go below what the account balance limit allows,
+// and with ErrAccountFrozen or ErrAccountClosed if the account state rejects transactions reducing its Balance.
+// The account is locked while the hold is placed, so concurrent holds can not reserve the same Balance.
+func (hm *SQLHoldManager) PlaceHold(context context.Context, holdToPlace Hold) (err error) {
+	if err := validateHold(holdToPlace); err != nil {
+		return err
+	}
+	if err := checkSQLAmountPrecision(holdToPlace.GetAmount()); err != nil {
+		return err
+	}
+
+	store := hm.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	count, err := store.count(context, tx, "SELECT COUNT(*) FROM acccore_hold WHERE hold_id = ?", holdToPlace.GetHoldID())
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrHoldAlreadyPersisted
+	}
+
+	account := &sqlAccountBalance{}
+	err = tx.QueryRowContext(context, store.dialect.rebind("SELECT alignment, balance, balance_limit, overdraft_limit, state, version FROM acccore_account WHERE account_number = ?"+store.dialect.forUpdate()), holdToPlace.GetAccountNumber()).
+		Scan(&account.alignment, &account.balance, &account.balanceLimit, &account.overdraftLimit, &account.state, &account.version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAccountIDNotFound
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	held, err := store.heldAmount(context, tx, holdToPlace.GetAccountNumber(), now)
+	if err != nil {
+		return err
+	}
+	err = checkHoldPlacement(account.toAccount(holdToPlace.GetAccountNumber()), held, holdToPlace.GetAmount())
+	if err != nil {
+		logrus.Errorf("error placing hold %s on account %s. got %s", holdToPlace.GetHoldID(), holdToPlace.GetAccountNumber(), err.Error())
+		return err
+	}
+
+	_, err = tx.ExecContext(context, store.dialect.rebind("INSERT INTO acccore_hold ("+sqlHoldColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		holdToPlace.GetHoldID(), holdToPlace.GetAccountNumber(), holdToPlace.GetDescription(), holdToPlace.GetAmount(), decimal.Zero,
+		HoldActive, holdToPlace.GetExpireTime().UTC(), now, holdToPlace.GetCreateBy(), now, holdToPlace.GetCreateBy())
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	holdToPlace.SetCapturedAmount(decimal.Zero).SetState(HoldActive).SetCreateTime(now).SetUpdateTime(now).SetUpdateBy(holdToPlace.GetCreateBy())
+	return nil
+}
+
+// GetHoldByID retrieve a hold by specifying its ID
+func (hm *SQLHoldManager) GetHoldByID(context context.Context, holdID string) (Hold, error) {
+	return hm.store.getHold(context, hm.store.db, holdID, false)
+}
+
+// ListActiveHolds returns the active holds of the account that have not expired at the specified time, ordered by their creation time.
+func (hm *SQLHoldManager) ListActiveHolds(context context.Context, accountNumber string, at time.Time) ([]Hold, error) {
+	rows, err := hm.store.db.QueryContext(context, hm.store.dialect.rebind("SELECT "+sqlHoldColumns+" FROM acccore_hold WHERE account_number = ? AND state = ? AND expire_time > ? ORDER BY create_time, hold_id"),
+		accountNumber, HoldActive, at.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	holds := make([]Hold, 0)
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
+
+// GetHeldAmount returns the sum of the remaining amount of the active holds of the account that have not expired at the specified time.
+func (hm *SQLHoldManager) GetHeldAmount(context context.Context, accountNumber string, at time.Time) (decimal.Decimal, error) {
+	count, err := hm.store.count(context, hm.store.db, "SELECT COUNT(*) FROM acccore_account WHERE account_number = ?", accountNumber)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if count == 0 {
+		return decimal.Zero, ErrAccountIDNotFound
+	}
+	return hm.store.heldAmount(context, hm.store.db, accountNumber, at)
+}
+
+// CaptureHold records the amount as captured from the hold. The hold must be active, not expired,
+// and its remaining amount must cover the amount. Once its whole amount is captured, the hold becomes HoldCaptured.
+func (hm *SQLHoldManager) CaptureHold(context context.Context, holdID string, amount decimal.Decimal, capturedBy string) (err error) {
+	store := hm.store
+	tx, err := store.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	hold, err := store.getHold(context, tx, holdID, true)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if err = checkHoldCapture(hold, amount, now); err != nil {
+		logrus.Errorf("error capturing hold %s. got %s", holdID, err.Error())
+		return err
+	}
+	if err = store.captureHold(context, tx, hold, amount, capturedBy, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CaptureHoldWithJournal commits the persisted journal moving the captured amount out of the held account,
+// and records the amount as captured from the hold, within a single database transaction.
+// If the hold can not be captured or the journal can not be committed, neither of them is changed.
+func (hm *SQLHoldManager) CaptureHoldWithJournal(context context.Context, holdID string, amount decimal.Decimal, capturedBy string, journalToCommit Journal) error {
+	if journalToCommit == nil {
+		return ErrJournalNil
+	}
+	return hm.store.commitJournal(context, journalToCommit, &holdCapture{holdID: holdID, amount: amount, capturedBy: capturedBy})
+}
+
+// ReleaseHold releases the remaining amount of an active hold, which becomes HoldReleased.
+func (hm *SQLHoldManager) ReleaseHold(context context.Context, holdID, releasedBy string) error {
+	store := hm.store
+	result, err := store.db.ExecContext(context, store.dialect.rebind("UPDATE acccore_hold SET state = ?, update_time = ?, update_by = ? WHERE hold_id = ? AND state = ?"),
+		HoldReleased, time.Now().UTC(), releasedBy, holdID, HoldActive)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// either the hold is not there, or it is no longer active.
+		hold, err := store.getHold(context, store.db, holdID, false)
+		if err != nil {
+			return err
+		}
+		logrus.Errorf("error releasing hold %s. hold is %s", holdID, hold.GetState())
+		return ErrHoldNotActive
+	}
+	return nil
+}
+
+// ExpireHolds marks the active holds that have expired at the specified time as HoldExpired, and returns how many there were.
+func (hm *SQLHoldManager) ExpireHolds(context context.Context, at time.Time) (int, error) {
+	store := hm.store
+	result, err := store.db.ExecContext(context, store.dialect.rebind("UPDATE acccore_hold SET state = ?, update_time = ? WHERE state = ? AND expire_time <= ?"),
+		HoldExpired, time.Now().UTC(), HoldActive, at.UTC())
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(affected), nil
+}
+
+// getHold loads the hold, locking it for update if asked.
+func (store *SQLStore) getHold(context context.Context, q sqlQuerier, holdID string, forUpdate bool) (Hold, error) {
+	query := "SELECT " + sqlHoldColumns + " FROM acccore_hold WHERE hold_id = ?"
+	if forUpdate {
+		query += store.dialect.forUpdate()
+	}
+	hold, err := scanHold(q.QueryRowContext(context, store.dialect.rebind(query), holdID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// captureHold records the amount as captured from the hold locked by the database transaction.
+func (store *SQLStore) captureHold(context context.Context, tx *sql.Tx, hold Hold, amount decimal.Decimal, capturedBy string, now time.Time) error {
+	captured := hold.GetCapturedAmount().Add(amount)
+	state := HoldActive
+	if captured.Equal(hold.GetAmount()) {
+		state = HoldCaptured
+	}
+	_, err := tx.ExecContext(context, store.dialect.rebind("UPDATE acccore_hold SET captured_amount = ?, state = ?, update_time = ?, update_by = ? WHERE hold_id = ?"),
+		captured, state, now, capturedBy, hold.GetHoldID())
+	return err
+}
+
+// heldAmount sums the remaining amount of the active holds of the account that have not expired at the specified time.
+// The amounts are summed here rather than in SQL, as SQLite sums DECIMAL columns as floating points.
+func (store *SQLStore) heldAmount(context context.Context, q sqlQuerier, accountNumber string, at time.Time) (decimal.Decimal, error) {
+	rows, err := q.QueryContext(context, store.dialect.rebind("SELECT amount, captured_amount FROM acccore_hold WHERE account_number = ? AND state = ? AND expire_time > ?"),
+		accountNumber, HoldActive, at.UTC())
+	if err != nil {
+		return decimal.Zero, err
+	}
+	defer rows.Close()
+	held := decimal.Zero
+	for rows.Next() {
+		var amount, captured decimal.Decimal
+		if err := rows.Scan(&amount, &captured); err != nil {
+			return decimal.Zero, err
+		}
+		held = held.Add(amount.Sub(captured))
+	}
+	return held, rows.Err()
+}
+
+// scanHold scan a row of sqlHoldColumns into a BaseHold
+func scanHold(row sqlScanner) (Hold, error) {
+	hold := &BaseHold{}
+	err := row.Scan(&hold.HoldID, &hold.AccountNumber, &hold.Description, &hold.Amount, &hold.CapturedAmount, &hold.State,
+		&hold.ExpireTime, &hold.CreateTime, &hold.CreateBy, &hold.UpdateTime, &hold.UpdateBy)
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
@@ -0,0 +1,290 @@
+package acccore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSQLStore(t *testing.T) *SQLStore {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// every connection to ":memory:" opens its own database, so keep to a single connection.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err.Error())
+	}
+	return NewSQLStore(db, SQLDialectSQLite)
+}
+
+func newTestSQLAccounting(store *SQLStore) *Accounting {
+	return NewAccounting(store.GetAccountManager(), store.GetTransactionManager(), store.GetJournalManager(), &RandomGenUniqueIDGenerator{
+		Length:     16,
+		UpperAlpha: true,
+		Numeric:    true,
+	})
+}
+
+func TestSQLDialect_rebind(t *testing.T) {
+	query := "SELECT * FROM acccore_account WHERE account_number = ? AND coa = ?"
+	assert.Equal(t, query, SQLDialectSQLite.rebind(query))
+	assert.Equal(t, query, SQLDialectMySQL.rebind(query))
+	assert.Equal(t, "SELECT * FROM acccore_account WHERE account_number = $1 AND coa = $2", SQLDialectPostgres.rebind(query))
+}
+
+// sqlStateError is a driver error exposing its SQLSTATE
+type sqlStateError string
+
+func (state sqlStateError) Error() string {
+	return "driver error " + string(state)
+}
+
+func (state sqlStateError) SQLState() string {
+	return string(state)
+}
+
+func TestSQLDialect_isUniqueViolation(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	account, err := newTestSQLAccounting(store).CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	_, err = store.db.ExecContext(ctx, "INSERT INTO acccore_account ("+sqlAccountColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		account.GetAccountNumber(), "POINT", "Wallet", "Point wallet", CREDIT, 0, NonNegativeBalance, 0, AccountActive, "2.1", time.Now(), "aCreator", time.Now(), "", 1)
+	assert.True(t, SQLDialectSQLite.isUniqueViolation(err), "error %v", err)
+	assert.False(t, SQLDialectSQLite.isUniqueViolation(sql.ErrNoRows))
+	assert.False(t, SQLDialectSQLite.isUniqueViolation(nil))
+
+	assert.True(t, SQLDialectPostgres.isUniqueViolation(sqlStateError("23505")))
+	assert.False(t, SQLDialectPostgres.isUniqueViolation(sqlStateError("23503")))
+	assert.True(t, SQLDialectPostgres.isUniqueViolation(fmt.Errorf(`pq: duplicate key value violates unique constraint "acccore_account_pkey"`)))
+	assert.True(t, SQLDialectMySQL.isUniqueViolation(fmt.Errorf("Error 1062 (23000): Duplicate entry 'ACC-1' for key 'PRIMARY'")))
+	assert.False(t, SQLDialectMySQL.isUniqueViolation(fmt.Errorf("Error 1452 (23000): Cannot add or update a child row")))
+}
+
+func TestSQLStore_AmountPrecision(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	acc := newTestSQLAccounting(store).SetHoldManager(store.GetHoldManager())
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	// 12 decimals are kept, more would be truncated by the database.
+	_, err = acc.CreateNewJournal(ctx, "Topup", []TransactionInfo{
+		{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.RequireFromString("0.0000000000001")},
+		{AccountNumber: wallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.RequireFromString("0.0000000000001")},
+	}, "aCreator")
+	assert.ErrorIs(t, err, ErrSQLAmountPrecision)
+	_, err = acc.CreateNewJournal(ctx, "Topup", []TransactionInfo{
+		{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.RequireFromString("10.000000000001")},
+		{AccountNumber: wallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.RequireFromString("10.000000000001")},
+	}, "aCreator")
+	assert.NoError(t, err)
+	loaded, err := store.GetAccountManager().GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, loaded.GetBalance().Equal(decimal.RequireFromString("10.000000000001")), "balance %s", loaded.GetBalance())
+
+	_, err = acc.PlaceHold(ctx, wallet.GetAccountNumber(), decimal.RequireFromString("1.0000000000001"), time.Now().Add(time.Hour), "Checkout", "checkout")
+	assert.ErrorIs(t, err, ErrSQLAmountPrecision)
+	assert.ErrorIs(t, store.GetAccountManager().UpdateAccount(ctx, loaded.SetBalanceLimit(OverdraftBalance).
+		SetOverdraftLimit(decimal.RequireFromString("0.0000000000001")).SetUpdateBy("anEditor")), ErrSQLAmountPrecision)
+}
+
+func TestSQLStore_Journal(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	acc := newTestSQLAccounting(store)
+
+	goldLoan, err := acc.CreateNewAccount(ctx, "", "Gold Loan", "Gold base loan reserve", "1.1", "GOLD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	alphaCreditor, err := acc.CreateNewAccount(ctx, "", "Gold Creditor Alpha", "Gold base debitor alpha", "2.1", "GOLD", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	pointWallet, err := acc.CreateNewAccount(ctx, "", "Point Wallet", "Point wallet", "2.2", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	_, err = acc.CreateNewAccount(ctx, goldLoan.GetAccountNumber(), "Gold Loan", "Gold base loan reserve", "1.1", "GOLD", DEBIT, "aCreator")
+	assert.ErrorIs(t, err, ErrAccountAlreadyPersisted)
+
+	journal, err := acc.CreateNewJournal(ctx, "Creditor Topup Gold", []TransactionInfo{
+		{AccountNumber: goldLoan.GetAccountNumber(), Description: "Added Gold Reserve", TxType: DEBIT, Amount: decimal.NewFromInt(1000000)},
+		{AccountNumber: alphaCreditor.GetAccountNumber(), Description: "Added Gold Equity", TxType: CREDIT, Amount: decimal.NewFromInt(1000000)},
+	}, "aCreator")
+	assert.NoError(t, err)
+
+	loaded, err := store.GetJournalManager().GetJournalByID(ctx, journal.GetJournalID())
+	assert.NoError(t, err)
+	assert.Equal(t, "Creditor Topup Gold", loaded.GetDescription())
+	assert.True(t, loaded.GetAmount().Equal(decimal.NewFromInt(1000000)))
+	assert.Len(t, loaded.GetTransactions(), 2)
+	t.Log(store.GetJournalManager().RenderJournal(ctx, loaded))
+
+	goldLoan, err = store.GetAccountManager().GetAccountByID(ctx, goldLoan.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, goldLoan.GetBalance().Equal(decimal.NewFromInt(1000000)))
+
+	// mixed currencies must be rejected, and leave no trace.
+	_, err = acc.CreateNewJournal(ctx, "Mixed", []TransactionInfo{
+		{AccountNumber: goldLoan.GetAccountNumber(), Description: "Gold", TxType: CREDIT, Amount: decimal.NewFromInt(10)},
+		{AccountNumber: pointWallet.GetAccountNumber(), Description: "Point", TxType: DEBIT, Amount: decimal.NewFromInt(10)},
+	}, "aCreator")
+	assert.Error(t, err)
+	goldLoan, err = store.GetAccountManager().GetAccountByID(ctx, goldLoan.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, goldLoan.GetBalance().Equal(decimal.NewFromInt(1000000)))
+
+	result, transactions, err := store.GetTransactionManager().ListTransactionsOnAccount(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), goldLoan, PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.TotalEntries)
+	assert.Len(t, transactions, 1)
+
+	result, journals, err := store.GetJournalManager().ListJournals(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.TotalEntries)
+	assert.Len(t, journals, 1)
+
+	result, accounts, err := store.GetAccountManager().FindAccounts(ctx, "gold", PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.TotalEntries)
+	assert.Len(t, accounts, 2)
+
+	result, accounts, err = store.GetAccountManager().ListAccountByCOA(ctx, "2.2", PageRequest{PageNo: 1, ItemSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.TotalEntries)
+	assert.Equal(t, pointWallet.GetAccountNumber(), accounts[0].GetAccountNumber())
+}
+
+func TestSQLStore_ConcurrentPersistJournal(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLStore(t)
+	acc := newTestSQLAccounting(store)
+
+	reserve, err := acc.CreateNewAccount(ctx, "", "Reserve", "Point reserve", "1.1", "POINT", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	wallet, err := acc.CreateNewAccount(ctx, "", "Wallet", "Point wallet", "2.1", "POINT", CREDIT, "aCreator")
+	assert.NoError(t, err)
+
+	workers := 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := acc.CreateNewJournal(ctx, "Topup", []TransactionInfo{
+				{AccountNumber: reserve.GetAccountNumber(), Description: "Reserve", TxType: DEBIT, Amount: decimal.NewFromInt(10)},
+				{AccountNumber: wallet.GetAccountNumber(), Description: "Topup", TxType: CREDIT, Amount: decimal.NewFromInt(10)},
+			}, "aCreator")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	wallet, err = store.GetAccountManager().GetAccountByID(ctx, wallet.GetAccountNumber())
+	assert.NoError(t, err)
+	assert.True(t, wallet.GetBalance().Equal(decimal.NewFromInt(int64(10*workers))))
+}
+
+func TestSQLExchangeManager_CalculateExchange(t *testing.T) {
+	ctx := context.Background()
+	exchangeManager := newTestSQLStore(t).GetExchangeManager()
+	_, err := exchangeManager.CreateCurrency(ctx, "GOLD", "Gold", decimal.NewFromFloat(0.01), "superman")
+	assert.NoError(t, err)
+	_, err = exchangeManager.CreateCurrency(ctx, "SILVER", "Silver", decimal.NewFromFloat(0.1), "superman")
+	assert.NoError(t, err)
+	_, err = exchangeManager.CreateCurrency(ctx, "GOLD", "Gold", decimal.NewFromFloat(0.01), "superman")
+	assert.ErrorIs(t, err, ErrCurrencyAlreadyPersisted)
+
+	result, err := exchangeManager.CalculateExchange(ctx, "GOLD", "SILVER", decimal.NewFromInt(1000))
+	assert.NoError(t, err)
+	assert.True(t, result.Equal(decimal.NewFromInt(10000)), result.String())
+
+	silver, err := exchangeManager.GetCurrency(ctx, "SILVER")
+	assert.NoError(t, err)
+	silver.SetExchange(decimal.NewFromFloat(0.05))
+	assert.NoError(t, exchangeManager.UpdateCurrency(ctx, "SILVER", silver, "superman"))
+	result, err = exchangeManager.CalculateExchange(ctx, "GOLD", "SILVER", decimal.NewFromInt(1000))
+	assert.NoError(t, err)
+	assert.True(t, result.Equal(decimal.NewFromInt(5000)), result.String())
+
+	currencies, err := exchangeManager.ListCurrencies(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, currencies, 2)
+}
+
+func TestSQLJournalManager_TwoPhaseCommit(t *testing.T) {
+	testTwoPhaseCommit(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLAccountManager_OptimisticConcurrency(t *testing.T) {
+	testAccountOptimisticConcurrency(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLJournalManager_IdempotencyKey(t *testing.T) {
+	testIdempotentJournal(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLCOAManager_ChartOfAccounts(t *testing.T) {
+	store := newTestSQLStore(t)
+	testChartOfAccounts(t, newTestSQLAccounting(store), store.GetCOAManager())
+}
+
+func TestSQLTransactionManager_BalanceAt(t *testing.T) {
+	testBalanceAt(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLTransactionManager_BalanceAtEqualTime(t *testing.T) {
+	store := newTestSQLStore(t)
+	testBalanceAtEqualTime(t, newTestSQLAccounting(store), func(at time.Time) {
+		_, err := store.db.Exec("UPDATE acccore_transaction SET transaction_time = ?", at)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSQLStore_ListingSorts(t *testing.T) {
+	testListingSorts(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLStore_CursorPagination(t *testing.T) {
+	testCursorPagination(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLStore_CursorPaginationConcurrent(t *testing.T) {
+	// a file database, so the cursors are read on their own connections while journals are being committed.
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "ledger.db")+"?_journal_mode=WAL&_busy_timeout=10000&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatal(err.Error())
+	}
+	testCursorPaginationConcurrent(t, newTestSQLAccounting(NewSQLStore(db, SQLDialectSQLite)))
+}
+
+func TestSQLStore_Streaming(t *testing.T) {
+	testStreaming(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLAccountManager_BalanceLimit(t *testing.T) {
+	testBalanceLimit(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
+
+func TestSQLAccountManager_AccountStates(t *testing.T) {
+	testAccountStates(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
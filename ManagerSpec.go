@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/shopspring/decimal"
+	"iter"
 	"time"
 )
 
@@ -16,25 +17,87 @@ var (
 	ErrJournalTransactionAlreadyPersisted  = fmt.Errorf("journal transaction is already persisted")
 	ErrJournalTransactionMissingID         = fmt.Errorf("journal Transactions missing AccountNumber")
 	ErrJournalNotBalance                   = fmt.Errorf("journal's sum of debit and sum of credit do not Balance")
-	ErrJournalTransactionMixCurrency       = fmt.Errorf("journal Transactions contains mixed currencies, all transaction in a journal must belong to the same Currency")
+	ErrJournalTransactionAmountPrecision   = fmt.Errorf("journal transaction amount have more decimals than the scale of its Currency")
+	ErrJournalTransactionUnknownAlignment  = fmt.Errorf("journal transaction alignment must be either DEBIT or CREDIT")
+	ErrJournalTransactionMixCurrency       = fmt.Errorf("journal Transactions contains mixed currencies that do not balance, the Transactions of each Currency must balance on their own")
 	ErrJournalTransactionAccountNotPersist = fmt.Errorf("journal Transactions revering to non-existent account")
 	ErrJournalTransactionAccountDuplicate  = fmt.Errorf("multiple journal Transactions belongs to the same account")
 	ErrJournalIDNotFound                   = fmt.Errorf("journal with specified ID not in database")
 	ErrJournalLoadReversalInconsistent     = fmt.Errorf("reversed journal reverence to unexistent journal")
 	ErrJournalCanNotDoubleReverse          = fmt.Errorf("journal can only reversed once")
+	ErrJournalAlreadyCommitted             = fmt.Errorf("journal is already committed")
+	ErrJournalIdempotencyKeyAlreadyUsed    = fmt.Errorf("journal idempotency key is already used by other journal")
+	ErrJournalIdempotencyKeyConflict       = fmt.Errorf("journal idempotency key is already used by a journal with different transactions")
+	ErrJournalVetoed                       = fmt.Errorf("journal is vetoed by a journal hook")
 
-	ErrAccountAlreadyPersisted   = fmt.Errorf("account is already persisted")
-	ErrAccountIsNotPersisted     = fmt.Errorf("account is not persisted")
-	ErrAccountIDNotFound         = fmt.Errorf("account AccountNumber not in database")
-	ErrAccountMissingID          = fmt.Errorf("account AccountNumber or number is not provided")
-	ErrAccountMissingName        = fmt.Errorf("account Name is not provided")
-	ErrAccountMissingDescription = fmt.Errorf("account Description is not provided")
-	ErrAccountMissingCreator     = fmt.Errorf("account creator is not provided")
+	ErrAccountAlreadyPersisted       = fmt.Errorf("account is already persisted")
+	ErrAccountIsNotPersisted         = fmt.Errorf("account is not persisted")
+	ErrAccountIDNotFound             = fmt.Errorf("account AccountNumber not in database")
+	ErrAccountMissingID              = fmt.Errorf("account AccountNumber or number is not provided")
+	ErrAccountMissingName            = fmt.Errorf("account Name is not provided")
+	ErrAccountMissingDescription     = fmt.Errorf("account Description is not provided")
+	ErrAccountMissingCreator         = fmt.Errorf("account creator is not provided")
+	ErrAccountConcurrentModification = fmt.Errorf("account have been modified by someone else, reload the account and retry")
+	ErrAccountCOANotFound            = fmt.Errorf("account COA is not in the chart of accounts")
+	ErrAccountCOAAlignmentMismatch   = fmt.Errorf("account alignment is not the one expected from the accounts under its COA")
+	ErrAccountInvalidOverdraftLimit  = fmt.Errorf("account overdraft limit must not be negative")
+	ErrInsufficientBalance           = fmt.Errorf("account balance is insufficient")
+	ErrAccountMissingUpdater         = fmt.Errorf("account updater is not provided")
+	ErrAccountStateUnknown           = fmt.Errorf("account state is not known")
+	ErrAccountFrozen                 = fmt.Errorf("account is frozen for this transaction")
+	ErrAccountClosed                 = fmt.Errorf("account is closed")
+	ErrAccountBalanceNotZero         = fmt.Errorf("account balance must be zero to close the account")
+
+	ErrCOANotFound         = fmt.Errorf("COA code not in the chart of accounts")
+	ErrCOAAlreadyPersisted = fmt.Errorf("COA is already persisted")
+	ErrCOAMissingCode      = fmt.Errorf("COA code is not provided")
+	ErrCOAMissingName      = fmt.Errorf("COA Name is not provided")
+	ErrCOAMissingCreator   = fmt.Errorf("COA creator is not provided")
+	ErrCOAParentNotFound   = fmt.Errorf("COA parent is not in the chart of accounts")
+	ErrCOACategoryMismatch = fmt.Errorf("COA category must be the same as its parent category")
+	ErrCOAManagerNotSet    = fmt.Errorf("chart of accounts manager is not set")
 
 	ErrTransactionNotFound = fmt.Errorf("transaction AccountNumber not in database")
 
-	ErrCurrencyNotFound         = fmt.Errorf("currency not found")
-	ErrCurrencyAlreadyPersisted = fmt.Errorf("currency already persisted")
+	ErrHoldMissingID        = fmt.Errorf("hold ID is not provided")
+	ErrHoldMissingAccount   = fmt.Errorf("hold AccountNumber is not provided")
+	ErrHoldMissingAuthor    = fmt.Errorf("hold author is not known")
+	ErrHoldInvalidAmount    = fmt.Errorf("hold amount must be positive")
+	ErrHoldAlreadyPersisted = fmt.Errorf("hold is already persisted")
+	ErrHoldNotFound         = fmt.Errorf("hold ID not in database")
+	ErrHoldNotActive        = fmt.Errorf("hold is already captured, released or expired")
+	ErrHoldExpired          = fmt.Errorf("hold have expired")
+	ErrHoldAmountExceeded   = fmt.Errorf("amount exceeds the remaining amount of the hold")
+	ErrHoldManagerNotSet    = fmt.Errorf("hold manager is not set")
+
+	ErrUnknownSortColumn = fmt.Errorf("sort column is not known")
+	ErrCursorInvalid     = fmt.Errorf("pagination cursor is invalid, tampered or belongs to other listing")
+
+	ErrReportMissingCurrency = fmt.Errorf("report currency is not provided")
+
+	ErrJSONFormatUnsupported = fmt.Errorf("JSON format version is not supported")
+	ErrJSONUnknownType       = fmt.Errorf("JSON type is not registered in the journal codec")
+
+	ErrCurrencyNotFound               = fmt.Errorf("currency not found")
+	ErrCurrencyAlreadyPersisted       = fmt.Errorf("currency already persisted")
+	ErrCurrencyInvalidPrecision       = fmt.Errorf("currency scale must not be negative, and its rounding mode must be known")
+	ErrCurrencyRateNotFound           = fmt.Errorf("currency have no exchange rate in effect at the time")
+	ErrCurrencyRateOutOfOrder         = fmt.Errorf("currency exchange rate must take effect after the exchange rate in effect")
+	ErrExchangeManagerNotSet          = fmt.Errorf("exchange manager is not set")
+	ErrExchangeRateProviderFailed     = fmt.Errorf("exchange rate provider failed to provide the exchange rates")
+	ErrExchangeRateInvalid            = fmt.Errorf("exchange rate provided is invalid")
+	ErrExchangeRatesStale             = fmt.Errorf("exchange rates provided are stale")
+	ErrExchangeRateInvalidInterval    = fmt.Errorf("exchange rate refresh interval must be positive")
+	ErrFXRevaluationAccountsNotSet    = fmt.Errorf("FX revaluation accounts of the reporting currency are not set")
+	ErrFXRevaluationLocationNotSet    = fmt.Errorf("location of the FX revaluation periods is not set")
+	ErrFXPositionAccountNotSet        = fmt.Errorf("FX position account of the currency is not set")
+	ErrCrossCurrencyInvalidAmount     = fmt.Errorf("cross currency transfer amount must be positive")
+	ErrCrossCurrencyRemainder         = fmt.Errorf("cross currency transfer amount leaves a rounding remainder in the currency it is exchanged into")
+	ErrCrossCurrencyAlignmentMismatch = fmt.Errorf("cross currency transfer accounts must have the same alignment")
+	ErrMigrationInvalidFile           = fmt.Errorf("migration file name is not valid")
+	ErrMigrationMissingScript         = fmt.Errorf("migration is missing its up or down script")
+	ErrMigrationUnknownVersion        = fmt.Errorf("migration version is unknown")
+	ErrSQLAmountPrecision             = fmt.Errorf("amount have more decimals than the 12 decimals stored in the database")
 )
 
 // JournalManager is interface used of managing journals
@@ -46,42 +109,61 @@ type JournalManager interface {
 	// It requires list of Transactions for which each of the transaction MUST BE :
 	//    1.NOT BE PERSISTED. (the journal AccountNumber is not exist in DB yet)
 	//    2.Pointing or owned by a PERSISTED Account
-	//    3.Each of this account must belong to the same Currency
+	//    3.Balanced per Currency. The Transactions on the accounts of each Currency balance on their own, so a journal
+	//      may only mix currencies through legs offsetting each other, such as the FX position legs.
 	//    4.Balanced. The total sum of DEBIT and total sum of CREDIT is equal.
 	//    5.No duplicate transaction that belongs to the same Account.
-	// If your database support 2 phased commit, you can make all Balance changes in
-	// accounts and Transactions. If your db do not support this, you can implement your own 2 phase commits mechanism
-	// on the CommitJournal and CancelJournal
+	//    6.Its idempotency key, if any, is not used by other journal.
+	// A persisted journal is staged, it is not yet visible and do not change any account Balance
+	// until it is committed using CommitJournal, or discarded using CancelJournal.
 	PersistJournal(context context.Context, journalToPersist Journal) error
 
 	// CommitJournal will commit the journal into the system
 	// Only non committed journal can be committed.
-	// use this if the implementation database do not support 2 phased commit.
-	// if your database support 2 phased commit, you should do all commit in the PersistJournal function
-	// and this function should simply return nil.
+	// Committing applies all Balance changes of the journal Transactions into their accounts, and makes
+	// the journal and its Transactions visible. A transaction may not take the available Balance of its account,
+	// the Balance minus the amount held on it, below what the account balance limit allows.
 	CommitJournal(context context.Context, journalToCommit Journal) error
 
 	// CancelJournal Cancel a journal
-	// Only non committed journal can be committed.
-	// use this if the implementation database do not support 2 phased commit.
-	// if your database do not support 2 phased commit, you should do all roll back in the PersistJournal function
-	// and this function should simply return nil.
+	// Only non committed journal can be cancelled.
+	// Cancelling discards the journal and its Transactions, leaving no trace of them.
 	CancelJournal(context context.Context, journalToCancel Journal) error
 
 	// IsJournalIDReversed check if the journal with specified ID has been reversed
 	IsJournalIDReversed(context context.Context, journalID string) (bool, error)
 
 	// IsJournalIDExist will check if an Journal ID/number is exist in the database.
+	// Journals that are not yet committed are also taken into account, as their ID is already taken.
 	IsJournalIDExist(context context.Context, journalID string) (bool, error)
 
 	// GetJournalByID retrieved a Journal information identified by its ID.
 	// the provided ID must be exactly the same, not uses the LIKE select expression.
+	// Journals that are not yet committed are not found.
 	GetJournalByID(context context.Context, journalID string) (Journal, error)
 
+	// GetJournalByIdempotencyKey retrieved the Journal created with the specified idempotency key.
+	// Journals that are not yet committed are not found.
+	GetJournalByIdempotencyKey(context context.Context, idempotencyKey string) (Journal, error)
+
 	// ListJournals retrieve list of journals with transaction date between the `from` and `until` time range inclusive.
+	// Journals that are not yet committed are not listed.
 	// This function uses pagination.
 	ListJournals(context context.Context, from time.Time, until time.Time, request PageRequest) (PageResult, []Journal, error)
 
+	// ListJournalsByCursor retrieve list of journals with transaction date between the `from` and `until` time range inclusive,
+	// ordered by the sequence they were committed in, then by their journaling time and ID.
+	// Journals that are not yet committed are not listed.
+	// This function uses cursor pagination, so journals committed between page fetches never cause duplicates or skipped journals.
+	// It returns ErrCursorInvalid if the cursor was not created by this listing.
+	ListJournalsByCursor(context context.Context, from time.Time, until time.Time, request CursorRequest) (CursorResult, []Journal, error)
+
+	// StreamJournals streams all the journals with transaction date between the `from` and `until` time range inclusive,
+	// ordered by the sequence they were committed in, then by their journaling time and ID, without loading them all at once.
+	// Journals that are not yet committed are not streamed.
+	// The stream ends with the error of the context once it is cancelled, or with any error while reading the journals.
+	StreamJournals(context context.Context, from time.Time, until time.Time) iter.Seq2[Journal, error]
+
 	// RenderJournal Render this journal into string for easy inspection
 	RenderJournal(context context.Context, journal Journal) string
 }
@@ -92,16 +174,46 @@ type TransactionManager interface {
 	NewTransaction(context context.Context) Transaction
 
 	// IsTransactionIDExist will check if an Transaction ID/number is exist in the database.
+	// Transactions that are not yet committed are also taken into account, as their ID is already taken.
 	IsTransactionIDExist(context context.Context, id string) (bool, error)
 
 	// GetTransactionByID will retrieve one single transaction that identified by some ID
+	// Transactions that are not yet committed are not found.
 	GetTransactionByID(context context.Context, id string) (Transaction, error)
 
 	// ListTransactionsWithAccount retrieves list of Transactions that belongs to this account
-	// that transaction happens between the `from` and `until` time range.
+	// that transaction happens between the `from` and `until` time range inclusive.
+	// Transactions that are not yet committed are not listed.
 	// This function uses pagination
 	ListTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account, request PageRequest) (PageResult, []Transaction, error)
 
+	// ListTransactionsOnAccountByCursor retrieves list of Transactions that belongs to this account
+	// that transaction happens between the `from` and `until` time range inclusive, ordered by the sequence they were committed in,
+	// then by their transaction time and ID.
+	// Transactions that are not yet committed are not listed.
+	// This function uses cursor pagination, so transactions committed between page fetches never cause duplicates or skipped transactions.
+	// It returns ErrCursorInvalid if the cursor was not created by this listing of the same account.
+	ListTransactionsOnAccountByCursor(context context.Context, from time.Time, until time.Time, account Account, request CursorRequest) (CursorResult, []Transaction, error)
+
+	// StreamTransactionsOnAccount streams all the Transactions that belongs to this account
+	// that transaction happens between the `from` and `until` time range inclusive, ordered by the sequence they were committed in,
+	// then by their transaction time and ID, without loading them all at once.
+	// Transactions that are not yet committed are not streamed.
+	// The stream ends with the error of the context once it is cancelled, or with any error while reading the transactions.
+	StreamTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account) iter.Seq2[Transaction, error]
+
+	// GetBalanceAt retrieves the Balance of the account at the specified time, which is the account Balance
+	// recorded on its last committed transaction at or before that time.
+	// Among transactions sharing the same transaction time, the last committed one is taken, and the one with the greatest ID
+	// if they were committed together.
+	// The Balance is zero if the account have no transaction up to that time.
+	// It returns ErrAccountIDNotFound if the account is not in the database.
+	GetBalanceAt(context context.Context, accountNumber string, at time.Time) (decimal.Decimal, error)
+
+	// GetBalancesAt retrieves the Balance of many accounts at the specified time, keyed by their account number.
+	// See GetBalanceAt for how each Balance is determined.
+	GetBalancesAt(context context.Context, accountNumbers []string, at time.Time) (map[string]decimal.Decimal, error)
+
 	// RenderTransactionsOnAccount Render list of transaction been down on an account in a time span
 	RenderTransactionsOnAccount(context context.Context, from time.Time, until time.Time, account Account, request PageRequest) (string, error)
 }
@@ -113,10 +225,15 @@ type AccountManager interface {
 
 	// PersistAccount will save the account into database.
 	// will throw error if the account already persisted
+	// The persisted account starts at version 1, which is set into the provided account.
 	PersistAccount(context context.Context, AccountToPersist Account) error
 
 	// UpdateAccount will update the account database to reflect to the provided account information.
 	// This update account function will fail if the account ID/number is not existing in the database.
+	// It will fail with ErrAccountConcurrentModification if the account version is no longer the one in the database,
+	// meaning the account have been modified since it was read. On success the version is incremented
+	// into the provided account.
+	// The Balance, Currency and Alignment of the account are not written, the Balance only moves by committing journals.
 	UpdateAccount(context context.Context, AccountToUpdate Account) error
 
 	// IsAccountIDExist will check if an account ID/number is exist in the database.
@@ -129,6 +246,10 @@ type AccountManager interface {
 	// This function uses pagination
 	ListAccounts(context context.Context, request PageRequest) (PageResult, []Account, error)
 
+	// StreamAccounts streams all account in the database ordered by their account number, without loading them all at once.
+	// The stream ends with the error of the context once it is cancelled, or with any error while reading the accounts.
+	StreamAccounts(context context.Context) iter.Seq2[Account, error]
+
 	// ListAccountByCOA returns list of accounts that have the same COA number.
 	// This function uses pagination
 	ListAccountByCOA(context context.Context, coa string, request PageRequest) (PageResult, []Account, error)
@@ -136,6 +257,74 @@ type AccountManager interface {
 	// FindAccounts returns list of accounts that have their Name contains a substring of specified parameter.
 	// this search should  be case insensitive.
 	FindAccounts(context context.Context, nameLike string, request PageRequest) (PageResult, []Account, error)
+
+	// ChangeAccountState moves the account into the new state, and records the change with its reason and author for audit.
+	// A closed account can not change its state anymore, and an account can only be closed once its Balance is zero.
+	// Changing the state increments the account version. Changing into the current state does nothing.
+	ChangeAccountState(context context.Context, accountNumber string, state AccountState, reason, changedBy string) error
+
+	// ListAccountStateChanges returns all the state changes of the account, oldest first.
+	ListAccountStateChanges(context context.Context, accountNumber string) ([]*AccountStateChange, error)
+}
+
+// COAManager is interface used for managing the chart of accounts
+type COAManager interface {
+	// NewCOA will create a new blank un-persisted COA.
+	NewCOA(context context.Context) COA
+
+	// PersistCOA will save the COA into database.
+	// will throw error if the COA already persisted, if its parent is not persisted,
+	// or if its category is not the same as its parent category.
+	PersistCOA(context context.Context, coaToPersist COA) error
+
+	// IsCOAExist will check if a COA code is exist in the database.
+	IsCOAExist(context context.Context, code string) (bool, error)
+
+	// GetCOAByCode retrieve a COA information by specifying its code
+	GetCOAByCode(context context.Context, code string) (COA, error)
+
+	// ListCOAChildren returns the direct children of the COA with the specified code, ordered by their code.
+	// An empty parent code lists the roots of the chart.
+	ListCOAChildren(context context.Context, parentCode string) ([]COA, error)
+}
+
+// HoldManager is interface used for managing fund holds on accounts.
+// The available Balance of an account is its Balance minus the remaining amount of its active holds that have not expired.
+// Expired holds stop reserving their amount as soon as they expire, ExpireHolds only records their state.
+type HoldManager interface {
+	// NewHold will create a new blank un-persisted hold.
+	NewHold(context context.Context) Hold
+
+	// PlaceHold will save the active hold into database, reserving its amount from the account available Balance.
+	// It fails with an InsufficientBalanceError if the available Balance would go below what the account balance limit allows,
+	// and with ErrAccountFrozen or ErrAccountClosed if the account state rejects transactions reducing its Balance.
+	PlaceHold(context context.Context, holdToPlace Hold) error
+
+	// GetHoldByID retrieve a hold by specifying its ID
+	GetHoldByID(context context.Context, holdID string) (Hold, error)
+
+	// ListActiveHolds returns the active holds of the account that have not expired at the specified time, ordered by their creation time.
+	ListActiveHolds(context context.Context, accountNumber string, at time.Time) ([]Hold, error)
+
+	// GetHeldAmount returns the sum of the remaining amount of the active holds of the account that have not expired at the specified time.
+	GetHeldAmount(context context.Context, accountNumber string, at time.Time) (decimal.Decimal, error)
+
+	// CaptureHold records the amount as captured from the hold. The hold must be active, not expired,
+	// and its remaining amount must cover the amount. Once its whole amount is captured, the hold becomes HoldCaptured.
+	// It moves no fund, use CaptureHoldWithJournal to also commit the journal moving the captured amount.
+	CaptureHold(context context.Context, holdID string, amount decimal.Decimal, capturedBy string) error
+
+	// CaptureHoldWithJournal commits the persisted journal moving the captured amount out of the held account,
+	// and records the amount as captured from the hold, all at once. The captured amount is no longer held
+	// when the journal is checked against the held account balance limit.
+	// If the hold can not be captured or the journal can not be committed, neither of them is changed.
+	CaptureHoldWithJournal(context context.Context, holdID string, amount decimal.Decimal, capturedBy string, journalToCommit Journal) error
+
+	// ReleaseHold releases the remaining amount of an active hold, which becomes HoldReleased.
+	ReleaseHold(context context.Context, holdID, releasedBy string) error
+
+	// ExpireHolds marks the active holds that have expired at the specified time as HoldExpired, and returns how many there were.
+	ExpireHolds(context context.Context, at time.Time) (int, error)
 }
 
 // ExchangeManager will define functions to be implemented for Currency exchanges.
@@ -158,16 +347,34 @@ type ExchangeManager interface {
 	// CreateCurrency set the specified value as denominator value for that speciffic Currency.
 	// This function should return error if the Currency specified is not exist.
 	CreateCurrency(context context.Context, code, name string, exchange decimal.Decimal, author string) (Currency, error)
-	// UpdateCurrency updates the currency data
+	// UpdateCurrency updates the currency data, including its scale and rounding mode.
 	// Error should be returned if the specified Currency is not exist.
+	// Changing the exchange value ends the rate in effect and starts a new one in the Currency rate history.
 	UpdateCurrency(context context.Context, code string, currency Currency, author string) error
+	// UpdateCurrencyRates changes the exchange values of the currencies keyed by their code, all at once, each new exchange value
+	// taking effect at the validFrom time in the Currency rate history. Exchange values that do not change are left untouched.
+	// Either all the currencies are updated, or none of them.
+	// ErrCurrencyNotFound should be returned if any of the Currency is not exist, and ErrCurrencyRateOutOfOrder if the validFrom time
+	// is not after the time the exchange value in effect of any of the changed Currency took effect.
+	UpdateCurrencyRates(context context.Context, rates map[string]decimal.Decimal, validFrom time.Time, author string) error
+	// ListCurrencyRates lists the history of the Currency exchange values, the oldest first.
+	ListCurrencyRates(context context.Context, code string) ([]*CurrencyRate, error)
 
 	// Get the Currency exchange rate for exchanging between the two Currency.
 	// if any of the Currency is not exist, an error should be returned.
 	// if from and to Currency is equal, this must return 1.0
 	CalculateExchangeRate(context context.Context, fromCurrency, toCurrency string) (decimal.Decimal, error)
-	// Get the Currency exchange value for the Amount of fromCurrency into toCurrency.
+	// Get the Currency exchange value for the Amount of fromCurrency into toCurrency, rounded to the scale of toCurrency.
 	// If any of the Currency is not exist, an error should be returned.
 	// if from and to Currency is equal, the returned Amount must be equal to the Amount in the argument.
 	CalculateExchange(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, error)
+	// CalculateExchangeWithRemainder gets the exchange value just like CalculateExchange, together with the remainder
+	// left by rounding it to the scale of toCurrency, so the remainder can be booked.
+	CalculateExchangeWithRemainder(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error)
+	// CalculateExchangeRateAt gets the exchange rate between the two Currency, using the exchange values in effect at the specified time.
+	// ErrCurrencyRateNotFound should be returned if any of the Currency have no exchange value in effect at that time.
+	CalculateExchangeRateAt(context context.Context, fromCurrency, toCurrency string, at time.Time) (decimal.Decimal, error)
+	// CalculateExchangeAt gets the exchange value for the Amount of fromCurrency into toCurrency, rounded to the scale of toCurrency,
+	// using the exchange values in effect at the specified time.
+	CalculateExchangeAt(context context.Context, fromCurrency, toCurrency string, amount decimal.Decimal, at time.Time) (decimal.Decimal, error)
 }
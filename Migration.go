@@ -0,0 +1,243 @@
+package acccore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// migrationFiles contains all the SQL migration scripts.
+// Each version have an up script named `<version>_<name>.up.sql` and a down script named `<version>_<name>.down.sql`.
+// The scripts are kept portable among SQLite, PostgreSQL and MySQL.
+// The amounts are stored in DECIMAL(38, 12) columns, so SQLStore rejects amounts having more than 12 decimals
+// with ErrSQLAmountPrecision, while the exchange values and rates are rounded by the database to 12 decimals.
+// Note that SQLite stores DECIMAL columns using numeric affinity, non integer values beyond 15 significant digits
+// might lose their precision there.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	migrationFileRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+)
+
+const (
+	// MigrationTable is the name of the table recording the applied migration versions.
+	MigrationTable = "acccore_schema_migration"
+)
+
+// Migration is a single versioned change of the SQL schema
+type Migration struct {
+	// Version is the sequence number of this migration, starting from 1.
+	Version int
+	// Name is a short description of this migration
+	Name string
+	// Up is the script applying this migration
+	Up string
+	// Down is the script reverting this migration
+	Down string
+}
+
+// Migrations returns all the embedded migrations, ordered by their version.
+func Migrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			logrus.Errorf("error loading migrations. invalid migration file name %s", entry.Name())
+			return nil, ErrMigrationInvalidFile
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		script, err := fs.ReadFile(migrationFiles, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, exist := migrations[version]
+		if !exist {
+			migration = &Migration{Version: version, Name: match[2]}
+			migrations[version] = migration
+		}
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	ret := make([]*Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if len(migration.Up) == 0 || len(migration.Down) == 0 {
+			logrus.Errorf("error loading migrations. migration %d is missing its up or down script", migration.Version)
+			return nil, ErrMigrationMissingScript
+		}
+		ret = append(ret, migration)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Version < ret[j].Version
+	})
+	return ret, nil
+}
+
+// Migrate brings the database schema up to date by applying all pending migrations.
+func Migrate(context context.Context, db *sql.DB) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	return MigrateTo(context, db, migrations[len(migrations)-1].Version)
+}
+
+// MigrateTo applies or reverts migrations until the database schema is at the specified version.
+// Version 0 reverts all migrations.
+func MigrateTo(context context.Context, db *sql.DB, version int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if version != 0 {
+		known := false
+		for _, migration := range migrations {
+			if migration.Version == version {
+				known = true
+			}
+		}
+		if !known {
+			return ErrMigrationUnknownVersion
+		}
+	}
+
+	if _, err := db.ExecContext(context, "CREATE TABLE IF NOT EXISTS "+MigrationTable+" (version INTEGER NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)"); err != nil {
+		return err
+	}
+	applied, err := appliedMigrationVersions(context, db)
+	if err != nil {
+		return err
+	}
+
+	// apply the pending migrations up to the version, in ascending order
+	for _, migration := range migrations {
+		if migration.Version > version || applied[migration.Version] {
+			continue
+		}
+		logrus.Infof("applying migration %d %s", migration.Version, migration.Name)
+		err := runMigrationScript(context, db, migration.Up,
+			fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (%d, '%s', CURRENT_TIMESTAMP)", MigrationTable, migration.Version, migration.Name))
+		if err != nil {
+			logrus.Errorf("error applying migration %d %s. got %s", migration.Version, migration.Name, err.Error())
+			return err
+		}
+	}
+
+	// revert the applied migrations above the version, in descending order
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version <= version || !applied[migration.Version] {
+			continue
+		}
+		logrus.Infof("reverting migration %d %s", migration.Version, migration.Name)
+		err := runMigrationScript(context, db, migration.Down,
+			fmt.Sprintf("DELETE FROM %s WHERE version = %d", MigrationTable, migration.Version))
+		if err != nil {
+			logrus.Errorf("error reverting migration %d %s. got %s", migration.Version, migration.Name, err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrationVersion returns the highest migration version applied into the database. 0 if none is applied.
+func MigrationVersion(context context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(context, "SELECT MAX(version) FROM "+MigrationTable).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// appliedMigrationVersions returns the set of migration versions recorded in the migration table.
+func appliedMigrationVersions(context context.Context, db *sql.DB) (map[int]bool, error) {
+	rows, err := db.QueryContext(context, "SELECT version FROM "+MigrationTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// runMigrationScript executes all statements in the script followed by the bookkeeping statement,
+// within a single database transaction.
+// Note that MySQL implicitly commits on DDL statements, so a failing migration may be partially applied there.
+func runMigrationScript(context context.Context, db *sql.DB, script, bookkeeping string) error {
+	tx, err := db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	for _, statement := range append(splitSQLStatements(script), bookkeeping) {
+		if _, err := tx.ExecContext(context, statement); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// splitSQLStatements splits a script into its statements, separated by semicolons outside of quoted strings.
+// Lines starting with `--` are comments and are ignored.
+func splitSQLStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	script = strings.Join(lines, "\n")
+
+	statements := make([]string, 0)
+	var buff strings.Builder
+	quoted := false
+	for _, r := range script {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			buff.WriteRune(r)
+		case r == ';' && !quoted:
+			if statement := strings.TrimSpace(buff.String()); len(statement) > 0 {
+				statements = append(statements, statement)
+			}
+			buff.Reset()
+		default:
+			buff.WriteRune(r)
+		}
+	}
+	if statement := strings.TrimSpace(buff.String()); len(statement) > 0 {
+		statements = append(statements, statement)
+	}
+	return statements
+}
@@ -0,0 +1,69 @@
+package acccore
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "migration versions must be sequential")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrations, err := Migrations()
+	assert.NoError(t, err)
+	latest := migrations[len(migrations)-1].Version
+
+	assert.NoError(t, Migrate(ctx, db))
+	version, err := MigrationVersion(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, latest, version)
+
+	// migrating an up to date database does nothing
+	assert.NoError(t, Migrate(ctx, db))
+
+	store := NewSQLStore(db, SQLDialectSQLite)
+	exist, err := store.GetAccountManager().IsAccountIDExist(ctx, "1234")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	// revert everything, the tables should be gone
+	assert.NoError(t, MigrateTo(ctx, db, 0))
+	version, err = MigrationVersion(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+	_, err = store.GetAccountManager().IsAccountIDExist(ctx, "1234")
+	assert.Error(t, err)
+
+	assert.ErrorIs(t, MigrateTo(ctx, db, latest+1), ErrMigrationUnknownVersion)
+
+	assert.NoError(t, Migrate(ctx, db))
+	version, err = MigrationVersion(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, latest, version)
+}
+
+func TestSplitSQLStatements(t *testing.T) {
+	statements := splitSQLStatements(`-- a comment; with semicolon
+CREATE TABLE a (b VARCHAR(10) DEFAULT 'x;y');
+
+INSERT INTO a (b) VALUES ('z');
+`)
+	assert.Equal(t, []string{"CREATE TABLE a (b VARCHAR(10) DEFAULT 'x;y')", "INSERT INTO a (b) VALUES ('z')"}, statements)
+}
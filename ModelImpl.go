@@ -6,6 +6,97 @@ import (
 	"time"
 )
 
+// JSONFormat is the version of the JSON format of the models
+type JSONFormat int
+
+const (
+	// JSONFormatV1 is the legacy format, the decimals are written as JSON numbers and it have no format version field.
+	// Readers decoding the numbers into float64 may lose precision.
+	JSONFormatV1 JSONFormat = 1
+	// JSONFormatV2 writes the decimals as JSON strings, so they are read back exactly.
+	JSONFormatV2 JSONFormat = 2
+)
+
+// formatVersion returns the version written into the format version field, zero to leave the field out.
+func (format JSONFormat) formatVersion() JSONFormat {
+	if format == JSONFormatV1 {
+		return 0
+	}
+	return format
+}
+
+// decimal returns the decimal to be written in this format, a JSON number in JSONFormatV1 or a JSON string otherwise.
+func (format JSONFormat) decimal(value decimal.Decimal) json.Marshaler {
+	if format == JSONFormatV1 {
+		return jsonNumberDecimal(value)
+	}
+	return jsonDecimal(value)
+}
+
+// isKnown returns true if the format is one of the known JSON formats.
+func (format JSONFormat) isKnown() bool {
+	return format == JSONFormatV1 || format == JSONFormatV2
+}
+
+// checkJSONFormat returns ErrJSONFormatUnsupported if the format version read is not a known format.
+// A missing format version is the legacy JSONFormatV1.
+func checkJSONFormat(version *JSONFormat) error {
+	if version != nil && !version.isKnown() {
+		return ErrJSONFormatUnsupported
+	}
+	return nil
+}
+
+// MarshalJSONFormat writes the model into JSON in the format, where json.Marshal always writes JSONFormatV2.
+// It returns ErrJSONFormatUnsupported if the format is not known.
+// The transactions and reversed journal of a BaseJournal are written by DefaultJournalCodec in the same format.
+// Other values, including the types embedding the models of this package, are written using json.Marshal.
+func MarshalJSONFormat(value any, format JSONFormat) ([]byte, error) {
+	if !format.isKnown() {
+		return nil, ErrJSONFormatUnsupported
+	}
+	switch model := value.(type) {
+	case *BaseJournal:
+		return model.marshalJSONWith(DefaultJournalCodec.withJSONFormat(format))
+	case *BaseTransaction:
+		return model.marshalJSONFormat(format)
+	case *BaseAccount:
+		return model.marshalJSONFormat(format)
+	case *BaseCurrency:
+		return model.marshalJSONFormat(format)
+	case *BaseHold:
+		return model.marshalJSONFormat(format)
+	}
+	return json.Marshal(value)
+}
+
+// jsonDecimal is a decimal written as a JSON string,
+// and read from either a JSON string or a JSON number without losing precision.
+type jsonDecimal decimal.Decimal
+
+// MarshalJSON writes the decimal as a JSON string.
+func (d jsonDecimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(decimal.Decimal(d).String())
+}
+
+// UnmarshalJSON reads the decimal from a JSON string or a JSON number.
+func (d *jsonDecimal) UnmarshalJSON(data []byte) error {
+	var value decimal.Decimal
+	if err := value.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*d = jsonDecimal(value)
+	return nil
+}
+
+// jsonNumberDecimal is a decimal written as a JSON number, as JSONFormatV1 does.
+type jsonNumberDecimal decimal.Decimal
+
+// MarshalJSON writes the decimal as a JSON number.
+func (d jsonNumberDecimal) MarshalJSON() ([]byte, error) {
+	return []byte(decimal.Decimal(d).String()), nil
+}
+
 // BaseJournal is the base implementation of Journal
 type BaseJournal struct {
 	JournalID       string          `json:"journal_id"`
@@ -17,29 +108,52 @@ type BaseJournal struct {
 	Transactions    []Transaction   `json:"transactions"`
 	CreateTime      time.Time       `json:"create_time"`
 	CreatedBy       string          `json:"created_by"`
+	IdempotencyKey  string          `json:"idempotency_key"`
+	ExchangeRate    decimal.Decimal `json:"exchange_rate"`
 }
 
+// MarshalJSON writes the journal in JSONFormatV2, with its transactions and reversed journal written by DefaultJournalCodec.
+// Use MarshalJSONFormat or a JournalCodec to write it in another format.
 func (journal *BaseJournal) MarshalJSON() ([]byte, error) {
+	return journal.marshalJSONWith(DefaultJournalCodec.withJSONFormat(JSONFormatV2))
+}
+
+// marshalJSONWith writes the journal in the JSON format of the codec, with its transactions and reversed journal written by the codec.
+func (journal *BaseJournal) marshalJSONWith(codec *JournalCodec) ([]byte, error) {
+	transactions, err := codec.encodeTransactions(journal.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	reversed, err := codec.encodeReversedJournal(journal.ReversedJournal)
+	if err != nil {
+		return nil, err
+	}
 	toMarshal := struct {
-		JournalID       string        `json:"journal_id"`
-		JournalingTime  time.Time     `json:"journaling_time"`
-		Description     string        `json:"description"`
-		Reversal        bool          `json:"reversal"`
-		ReversedJournal Journal       `json:"reversed_journal"`
-		Amount          float64       `json:"amount"`
-		Transactions    []Transaction `json:"transactions"`
-		CreateTime      time.Time     `json:"create_time"`
-		CreatedBy       string        `json:"created_by"`
+		FormatVersion   JSONFormat        `json:"format_version,omitempty"`
+		JournalID       string            `json:"journal_id"`
+		JournalingTime  time.Time         `json:"journaling_time"`
+		Description     string            `json:"description"`
+		Reversal        bool              `json:"reversal"`
+		ReversedJournal json.RawMessage   `json:"reversed_journal"`
+		Amount          json.Marshaler    `json:"amount"`
+		Transactions    []json.RawMessage `json:"transactions"`
+		CreateTime      time.Time         `json:"create_time"`
+		CreatedBy       string            `json:"created_by"`
+		IdempotencyKey  string            `json:"idempotency_key"`
+		ExchangeRate    json.Marshaler    `json:"exchange_rate"`
 	}{
+		FormatVersion:   codec.format.formatVersion(),
 		JournalID:       journal.JournalID,
 		JournalingTime:  journal.JournalingTime,
 		Description:     journal.Description,
 		Reversal:        journal.Reversal,
-		ReversedJournal: journal.ReversedJournal,
-		Amount:          journal.Amount.InexactFloat64(),
-		Transactions:    journal.Transactions,
+		ReversedJournal: reversed,
+		Amount:          codec.format.decimal(journal.Amount),
+		Transactions:    transactions,
 		CreateTime:      journal.CreateTime,
 		CreatedBy:       journal.CreatedBy,
+		IdempotencyKey:  journal.IdempotencyKey,
+		ExchangeRate:    codec.format.decimal(journal.ExchangeRate),
 	}
 	return json.Marshal(toMarshal)
 }
@@ -51,34 +165,40 @@ func (journal *BaseJournal) UnmarshalJSON(data []byte) error {
 	}
 
 	toMarshal := struct {
-		JournalID       string        `json:"journal_id"`
-		JournalingTime  time.Time     `json:"journaling_time"`
-		Description     string        `json:"description"`
-		Reversal        bool          `json:"reversal"`
-		ReversedJournal Journal       `json:"reversed_journal"`
-		Amount          float64       `json:"amount"`
-		Transactions    []Transaction `json:"transactions"`
-		CreateTime      time.Time     `json:"create_time"`
-		CreatedBy       string        `json:"created_by"`
+		FormatVersion   *JSONFormat       `json:"format_version,omitempty"`
+		JournalID       string            `json:"journal_id"`
+		JournalingTime  time.Time         `json:"journaling_time"`
+		Description     string            `json:"description"`
+		Reversal        bool              `json:"reversal"`
+		ReversedJournal json.RawMessage   `json:"reversed_journal"`
+		Amount          jsonDecimal       `json:"amount"`
+		Transactions    []json.RawMessage `json:"transactions"`
+		CreateTime      time.Time         `json:"create_time"`
+		CreatedBy       string            `json:"created_by"`
+		IdempotencyKey  string            `json:"idempotency_key"`
+		ExchangeRate    jsonDecimal       `json:"exchange_rate"`
 	}{}
 
 	err := json.Unmarshal(data, &toMarshal)
 	if err != nil {
 		return err
 	}
+	if err := checkJSONFormat(toMarshal.FormatVersion); err != nil {
+		return err
+	}
 
 	journal.JournalID = toMarshal.JournalID
 
 	journal.JournalingTime = toMarshal.JournalingTime
 	journal.Description = toMarshal.Description
 	journal.Reversal = toMarshal.Reversal
-	journal.ReversedJournal = toMarshal.ReversedJournal
-	journal.Amount = decimal.NewFromFloat(toMarshal.Amount)
-	journal.Transactions = toMarshal.Transactions
+	journal.Amount = decimal.Decimal(toMarshal.Amount)
 	journal.CreateTime = toMarshal.CreateTime
 	journal.CreatedBy = toMarshal.CreatedBy
+	journal.IdempotencyKey = toMarshal.IdempotencyKey
+	journal.ExchangeRate = decimal.Decimal(toMarshal.ExchangeRate)
 
-	return nil
+	return DefaultJournalCodec.decodeJournalFields(journal, toMarshal.Transactions, toMarshal.ReversedJournal)
 }
 
 // GetJournalID would return the journal unique ID
@@ -182,6 +302,28 @@ func (journal *BaseJournal) SetCreateBy(creator string) Journal {
 	return journal
 }
 
+// GetIdempotencyKey returns the client supplied key that identify the request creating this journal.
+func (journal *BaseJournal) GetIdempotencyKey() string {
+	return journal.IdempotencyKey
+}
+
+// SetIdempotencyKey will set the idempotency key
+func (journal *BaseJournal) SetIdempotencyKey(key string) Journal {
+	journal.IdempotencyKey = key
+	return journal
+}
+
+// GetExchangeRate returns the exchange rate applied between the currencies of a cross-currency journal.
+func (journal *BaseJournal) GetExchangeRate() decimal.Decimal {
+	return journal.ExchangeRate
+}
+
+// SetExchangeRate will set the applied exchange rate
+func (journal *BaseJournal) SetExchangeRate(rate decimal.Decimal) Journal {
+	journal.ExchangeRate = rate
+	return journal
+}
+
 // BaseTransaction is the base implementation of Transaction
 type BaseTransaction struct {
 	TransactionID   string          `json:"transaction_id"`
@@ -196,27 +338,35 @@ type BaseTransaction struct {
 	CreateBy        string          `json:"create_by"`
 }
 
+// MarshalJSON writes the transaction in JSONFormatV2. Use MarshalJSONFormat to write it in another format.
 func (trx *BaseTransaction) MarshalJSON() ([]byte, error) {
+	return trx.marshalJSONFormat(JSONFormatV2)
+}
+
+// marshalJSONFormat writes the transaction in the JSON format.
+func (trx *BaseTransaction) marshalJSONFormat(format JSONFormat) ([]byte, error) {
 	toMarshal := struct {
-		TransactionID   string    `json:"transaction_id"`
-		TransactionTime time.Time `json:"transaction_time"`
-		AccountNumber   string    `json:"account_number"`
-		JournalID       string    `json:"journal_id"`
-		Description     string    `json:"description"`
-		TransactionType Alignment `json:"transaction_type"`
-		Amount          float64   `json:"amount"`
-		AccountBalance  float64   `json:"account_balance"`
-		CreateTime      time.Time `json:"create_time"`
-		CreateBy        string    `json:"create_by"`
+		FormatVersion   JSONFormat     `json:"format_version,omitempty"`
+		TransactionID   string         `json:"transaction_id"`
+		TransactionTime time.Time      `json:"transaction_time"`
+		AccountNumber   string         `json:"account_number"`
+		JournalID       string         `json:"journal_id"`
+		Description     string         `json:"description"`
+		TransactionType Alignment      `json:"transaction_type"`
+		Amount          json.Marshaler `json:"amount"`
+		AccountBalance  json.Marshaler `json:"account_balance"`
+		CreateTime      time.Time      `json:"create_time"`
+		CreateBy        string         `json:"create_by"`
 	}{
+		FormatVersion:   format.formatVersion(),
 		TransactionID:   trx.TransactionID,
 		TransactionTime: trx.TransactionTime,
 		AccountNumber:   trx.AccountNumber,
 		JournalID:       trx.JournalID,
 		Description:     trx.Description,
 		TransactionType: trx.TransactionType,
-		Amount:          trx.Amount.InexactFloat64(),
-		AccountBalance:  trx.AccountBalance.InexactFloat64(),
+		Amount:          format.decimal(trx.Amount),
+		AccountBalance:  format.decimal(trx.AccountBalance),
 		CreateTime:      trx.CreateTime,
 		CreateBy:        trx.CreateBy,
 	}
@@ -230,22 +380,26 @@ func (trx *BaseTransaction) UnmarshalJSON(data []byte) error {
 	}
 
 	toMarshal := struct {
-		TransactionID   string    `json:"transaction_id"`
-		TransactionTime time.Time `json:"transaction_time"`
-		AccountNumber   string    `json:"account_number"`
-		JournalID       string    `json:"journal_id"`
-		Description     string    `json:"description"`
-		TransactionType Alignment `json:"transaction_type"`
-		Amount          float64   `json:"amount"`
-		AccountBalance  float64   `json:"account_balance"`
-		CreateTime      time.Time `json:"create_time"`
-		CreateBy        string    `json:"create_by"`
+		FormatVersion   *JSONFormat `json:"format_version,omitempty"`
+		TransactionID   string      `json:"transaction_id"`
+		TransactionTime time.Time   `json:"transaction_time"`
+		AccountNumber   string      `json:"account_number"`
+		JournalID       string      `json:"journal_id"`
+		Description     string      `json:"description"`
+		TransactionType Alignment   `json:"transaction_type"`
+		Amount          jsonDecimal `json:"amount"`
+		AccountBalance  jsonDecimal `json:"account_balance"`
+		CreateTime      time.Time   `json:"create_time"`
+		CreateBy        string      `json:"create_by"`
 	}{}
 
 	err := json.Unmarshal(data, &toMarshal)
 	if err != nil {
 		return err
 	}
+	if err := checkJSONFormat(toMarshal.FormatVersion); err != nil {
+		return err
+	}
 
 	trx.TransactionID = toMarshal.TransactionID
 	trx.TransactionTime = toMarshal.TransactionTime
@@ -253,8 +407,8 @@ func (trx *BaseTransaction) UnmarshalJSON(data []byte) error {
 	trx.JournalID = toMarshal.JournalID
 	trx.Description = toMarshal.Description
 	trx.TransactionType = toMarshal.TransactionType
-	trx.Amount = decimal.NewFromFloat(toMarshal.Amount)
-	trx.AccountBalance = decimal.NewFromFloat(toMarshal.AccountBalance)
+	trx.Amount = decimal.Decimal(toMarshal.Amount)
+	trx.AccountBalance = decimal.Decimal(toMarshal.AccountBalance)
 	trx.CreateTime = toMarshal.CreateTime
 	trx.CreateBy = toMarshal.CreateBy
 
@@ -381,38 +535,58 @@ type BaseAccount struct {
 	Description   string          `json:"description"`
 	Alignment     Alignment       `json:"alignment"`
 	Balance       decimal.Decimal `json:"balance"`
+	BalanceLimit  BalanceLimit    `json:"balance_limit"`
+	Overdraft     decimal.Decimal `json:"overdraft_limit"`
+	State         AccountState    `json:"state"`
 	COA           string          `json:"coa"`
 	CreateTime    time.Time       `json:"create_time"`
 	CreateBy      string          `json:"create_by"`
 	UpdateTime    time.Time       `json:"update_time"`
 	UpdateBy      string          `json:"update_by"`
+	Version       int64           `json:"version"`
 }
 
+// MarshalJSON writes the account in JSONFormatV2. Use MarshalJSONFormat to write it in another format.
 func (acc *BaseAccount) MarshalJSON() ([]byte, error) {
+	return acc.marshalJSONFormat(JSONFormatV2)
+}
+
+// marshalJSONFormat writes the account in the JSON format.
+func (acc *BaseAccount) marshalJSONFormat(format JSONFormat) ([]byte, error) {
 	toMarshal := struct {
-		Currency      string    `json:"currency"`
-		AccountNumber string    `json:"account_number"`
-		Name          string    `json:"name"`
-		Description   string    `json:"description"`
-		Alignment     Alignment `json:"alignment"`
-		Balance       float64   `json:"balance"`
-		COA           string    `json:"coa"`
-		CreateTime    time.Time `json:"create_time"`
-		CreateBy      string    `json:"create_by"`
-		UpdateTime    time.Time `json:"update_time"`
-		UpdateBy      string    `json:"update_by"`
+		FormatVersion JSONFormat     `json:"format_version,omitempty"`
+		Currency      string         `json:"currency"`
+		AccountNumber string         `json:"account_number"`
+		Name          string         `json:"name"`
+		Description   string         `json:"description"`
+		Alignment     Alignment      `json:"alignment"`
+		Balance       json.Marshaler `json:"balance"`
+		BalanceLimit  BalanceLimit   `json:"balance_limit"`
+		Overdraft     json.Marshaler `json:"overdraft_limit"`
+		State         AccountState   `json:"state"`
+		COA           string         `json:"coa"`
+		CreateTime    time.Time      `json:"create_time"`
+		CreateBy      string         `json:"create_by"`
+		UpdateTime    time.Time      `json:"update_time"`
+		UpdateBy      string         `json:"update_by"`
+		Version       int64          `json:"version"`
 	}{
+		FormatVersion: format.formatVersion(),
 		Currency:      acc.Currency,
 		AccountNumber: acc.AccountNumber,
 		Name:          acc.Name,
 		Description:   acc.Description,
 		Alignment:     acc.Alignment,
-		Balance:       acc.Balance.InexactFloat64(),
+		Balance:       format.decimal(acc.Balance),
+		BalanceLimit:  acc.BalanceLimit,
+		Overdraft:     format.decimal(acc.Overdraft),
+		State:         acc.State,
 		COA:           acc.COA,
 		CreateTime:    acc.CreateTime,
 		CreateBy:      acc.CreateBy,
 		UpdateTime:    acc.UpdateTime,
 		UpdateBy:      acc.UpdateBy,
+		Version:       acc.Version,
 	}
 	return json.Marshal(toMarshal)
 }
@@ -424,35 +598,47 @@ func (acc *BaseAccount) UnmarshalJSON(data []byte) error {
 	}
 
 	toMarshal := struct {
-		Currency      string    `json:"currency"`
-		AccountNumber string    `json:"account_number"`
-		Name          string    `json:"name"`
-		Description   string    `json:"description"`
-		Alignment     Alignment `json:"alignment"`
-		Balance       float64   `json:"balance"`
-		COA           string    `json:"coa"`
-		CreateTime    time.Time `json:"create_time"`
-		CreateBy      string    `json:"create_by"`
-		UpdateTime    time.Time `json:"update_time"`
-		UpdateBy      string    `json:"update_by"`
+		FormatVersion *JSONFormat  `json:"format_version,omitempty"`
+		Currency      string       `json:"currency"`
+		AccountNumber string       `json:"account_number"`
+		Name          string       `json:"name"`
+		Description   string       `json:"description"`
+		Alignment     Alignment    `json:"alignment"`
+		Balance       jsonDecimal  `json:"balance"`
+		BalanceLimit  BalanceLimit `json:"balance_limit"`
+		Overdraft     jsonDecimal  `json:"overdraft_limit"`
+		State         AccountState `json:"state"`
+		COA           string       `json:"coa"`
+		CreateTime    time.Time    `json:"create_time"`
+		CreateBy      string       `json:"create_by"`
+		UpdateTime    time.Time    `json:"update_time"`
+		UpdateBy      string       `json:"update_by"`
+		Version       int64        `json:"version"`
 	}{}
 
 	err := json.Unmarshal(data, &toMarshal)
 	if err != nil {
 		return err
 	}
+	if err := checkJSONFormat(toMarshal.FormatVersion); err != nil {
+		return err
+	}
 
 	acc.Currency = toMarshal.Currency
 	acc.AccountNumber = toMarshal.AccountNumber
 	acc.Name = toMarshal.Name
 	acc.Description = toMarshal.Description
 	acc.Alignment = toMarshal.Alignment
-	acc.Balance = decimal.NewFromFloat(toMarshal.Balance)
+	acc.Balance = decimal.Decimal(toMarshal.Balance)
+	acc.BalanceLimit = toMarshal.BalanceLimit
+	acc.Overdraft = decimal.Decimal(toMarshal.Overdraft)
+	acc.State = toMarshal.State
 	acc.COA = toMarshal.COA
 	acc.CreateTime = toMarshal.CreateTime
 	acc.CreateBy = toMarshal.CreateBy
 	acc.UpdateTime = toMarshal.UpdateTime
 	acc.UpdateBy = toMarshal.UpdateBy
+	acc.Version = toMarshal.Version
 
 	return nil
 }
@@ -526,6 +712,39 @@ func (acc *BaseAccount) SetBalance(newBalance decimal.Decimal) Account {
 	return acc
 }
 
+// GetBalanceLimit returns the constraint on the Balance of this account.
+func (acc *BaseAccount) GetBalanceLimit() BalanceLimit {
+	return acc.BalanceLimit
+}
+
+// SetBalanceLimit will set the constraint on the Balance
+func (acc *BaseAccount) SetBalanceLimit(limit BalanceLimit) Account {
+	acc.BalanceLimit = limit
+	return acc
+}
+
+// GetOverdraftLimit returns how far below zero the Balance may go, used when the balance limit is OverdraftBalance.
+func (acc *BaseAccount) GetOverdraftLimit() decimal.Decimal {
+	return acc.Overdraft
+}
+
+// SetOverdraftLimit will set how far below zero the Balance may go
+func (acc *BaseAccount) SetOverdraftLimit(limit decimal.Decimal) Account {
+	acc.Overdraft = limit
+	return acc
+}
+
+// GetState returns the lifecycle state of this account, which decides the transactions the account accepts.
+func (acc *BaseAccount) GetState() AccountState {
+	return acc.State
+}
+
+// SetState will set the lifecycle state
+func (acc *BaseAccount) SetState(state AccountState) Account {
+	acc.State = state
+	return acc
+}
+
 // GetCOA returns the COA code for this account, used for categorization of account.
 func (acc *BaseAccount) GetCOA() string {
 	return acc.COA
@@ -585,34 +804,161 @@ func (acc *BaseAccount) SetUpdateBy(editor string) Account {
 	return acc
 }
 
+// GetVersion returns the revision of this account record.
+func (acc *BaseAccount) GetVersion() int64 {
+	return acc.Version
+}
+
+// SetVersion will set the account revision
+func (acc *BaseAccount) SetVersion(newVersion int64) Account {
+	acc.Version = newVersion
+	return acc
+}
+
+// BaseCOA is the base implementation of COA
+type BaseCOA struct {
+	Code        string      `json:"code"`
+	ParentCode  string      `json:"parent_code"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Category    COACategory `json:"category"`
+	Alignment   Alignment   `json:"alignment"`
+	CreateTime  time.Time   `json:"create_time"`
+	CreateBy    string      `json:"create_by"`
+}
+
+// GetCode returns the unique COA code. e.g. `1.1`
+func (coa *BaseCOA) GetCode() string {
+	return coa.Code
+}
+
+// SetCode will set the COA code
+func (coa *BaseCOA) SetCode(code string) COA {
+	coa.Code = code
+	return coa
+}
+
+// GetParentCode returns the code of the parent node, empty if this node is a root.
+func (coa *BaseCOA) GetParentCode() string {
+	return coa.ParentCode
+}
+
+// SetParentCode will set the code of the parent node
+func (coa *BaseCOA) SetParentCode(parentCode string) COA {
+	coa.ParentCode = parentCode
+	return coa
+}
+
+// GetName returns the COA Name. e.g. `Current Assets`
+func (coa *BaseCOA) GetName() string {
+	return coa.Name
+}
+
+// SetName will set the COA Name
+func (coa *BaseCOA) SetName(name string) COA {
+	coa.Name = name
+	return coa
+}
+
+// GetDescription returns some Description text about this COA
+func (coa *BaseCOA) GetDescription() string {
+	return coa.Description
+}
+
+// SetDescription will set new Description
+func (coa *BaseCOA) SetDescription(newDesc string) COA {
+	coa.Description = newDesc
+	return coa
+}
+
+// GetCategory returns the category of this COA
+func (coa *BaseCOA) GetCategory() COACategory {
+	return coa.Category
+}
+
+// SetCategory will set the category of this COA
+func (coa *BaseCOA) SetCategory(category COACategory) COA {
+	coa.Category = category
+	return coa
+}
+
+// GetAlignment returns the Alignment expected from the accounts under this COA.
+func (coa *BaseCOA) GetAlignment() Alignment {
+	return coa.Alignment
+}
+
+// SetAlignment will set the expected Alignment
+func (coa *BaseCOA) SetAlignment(alignment Alignment) COA {
+	coa.Alignment = alignment
+	return coa
+}
+
+// GetCreateTime function should return the time when this COA is created/recorded.
+// this function serves as audit trail.
+func (coa *BaseCOA) GetCreateTime() time.Time {
+	return coa.CreateTime
+}
+
+// SetCreateTime will set new creation time
+func (coa *BaseCOA) SetCreateTime(newTime time.Time) COA {
+	coa.CreateTime = newTime
+	return coa
+}
+
+// GetCreateBy function should return the user AccountNumber or some identification of who is creating this COA.
+// this function serves as audit trail.
+func (coa *BaseCOA) GetCreateBy() string {
+	return coa.CreateBy
+}
+
+// SetCreateBy will set the creator Name
+func (coa *BaseCOA) SetCreateBy(creator string) COA {
+	coa.CreateBy = creator
+	return coa
+}
+
 // BaseCurrency is the currency object
 type BaseCurrency struct {
-	Code       string          `json:"code"`
-	Name       string          `json:"name"`
-	Exchange   decimal.Decimal `json:"exchange"`
-	CreateTime time.Time       `json:"create_time"`
-	CreateBy   string          `json:"create_by"`
-	UpdateTime time.Time       `json:"update_time"`
-	UpdateBy   string          `json:"update_by"`
+	Code         string          `json:"code"`
+	Name         string          `json:"name"`
+	Exchange     decimal.Decimal `json:"exchange"`
+	Scale        int32           `json:"scale"`
+	RoundingMode RoundingMode    `json:"rounding_mode"`
+	CreateTime   time.Time       `json:"create_time"`
+	CreateBy     string          `json:"create_by"`
+	UpdateTime   time.Time       `json:"update_time"`
+	UpdateBy     string          `json:"update_by"`
+}
+
+// MarshalJSON writes the currency in JSONFormatV2. Use MarshalJSONFormat to write it in another format.
+func (bc *BaseCurrency) MarshalJSON() ([]byte, error) {
+	return bc.marshalJSONFormat(JSONFormatV2)
 }
 
-func (bc *BaseCurrency) MarshalJSON() ([]byte, error) {
+// marshalJSONFormat writes the currency in the JSON format.
+func (bc *BaseCurrency) marshalJSONFormat(format JSONFormat) ([]byte, error) {
 	toMarshal := struct {
-		Code       string    `json:"code"`
-		Name       string    `json:"name"`
-		Exchange   float64   `json:"exchange"`
-		CreateTime time.Time `json:"create_time"`
-		CreateBy   string    `json:"create_by"`
-		UpdateTime time.Time `json:"update_time"`
-		UpdateBy   string    `json:"update_by"`
+		FormatVersion JSONFormat     `json:"format_version,omitempty"`
+		Code          string         `json:"code"`
+		Name          string         `json:"name"`
+		Exchange      json.Marshaler `json:"exchange"`
+		Scale         int32          `json:"scale"`
+		RoundingMode  RoundingMode   `json:"rounding_mode"`
+		CreateTime    time.Time      `json:"create_time"`
+		CreateBy      string         `json:"create_by"`
+		UpdateTime    time.Time      `json:"update_time"`
+		UpdateBy      string         `json:"update_by"`
 	}{
-		Code:       bc.Code,
-		Name:       bc.Name,
-		Exchange:   bc.Exchange.InexactFloat64(),
-		CreateTime: bc.CreateTime,
-		CreateBy:   bc.CreateBy,
-		UpdateTime: bc.UpdateTime,
-		UpdateBy:   bc.UpdateBy,
+		FormatVersion: format.formatVersion(),
+		Code:          bc.Code,
+		Name:          bc.Name,
+		Exchange:      format.decimal(bc.Exchange),
+		Scale:         bc.Scale,
+		RoundingMode:  bc.RoundingMode,
+		CreateTime:    bc.CreateTime,
+		CreateBy:      bc.CreateBy,
+		UpdateTime:    bc.UpdateTime,
+		UpdateBy:      bc.UpdateBy,
 	}
 	return json.Marshal(toMarshal)
 }
@@ -624,23 +970,31 @@ func (bc *BaseCurrency) UnmarshalJSON(data []byte) error {
 	}
 
 	toMarshal := struct {
-		Code       string    `json:"code"`
-		Name       string    `json:"name"`
-		Exchange   float64   `json:"exchange"`
-		CreateTime time.Time `json:"create_time"`
-		CreateBy   string    `json:"create_by"`
-		UpdateTime time.Time `json:"update_time"`
-		UpdateBy   string    `json:"update_by"`
+		FormatVersion *JSONFormat  `json:"format_version,omitempty"`
+		Code          string       `json:"code"`
+		Name          string       `json:"name"`
+		Exchange      jsonDecimal  `json:"exchange"`
+		Scale         int32        `json:"scale"`
+		RoundingMode  RoundingMode `json:"rounding_mode"`
+		CreateTime    time.Time    `json:"create_time"`
+		CreateBy      string       `json:"create_by"`
+		UpdateTime    time.Time    `json:"update_time"`
+		UpdateBy      string       `json:"update_by"`
 	}{}
 
 	err := json.Unmarshal(data, &toMarshal)
 	if err != nil {
 		return err
 	}
+	if err := checkJSONFormat(toMarshal.FormatVersion); err != nil {
+		return err
+	}
 
 	bc.Code = toMarshal.Code
 	bc.Name = toMarshal.Name
-	bc.Exchange = decimal.NewFromFloat(toMarshal.Exchange)
+	bc.Exchange = decimal.Decimal(toMarshal.Exchange)
+	bc.Scale = toMarshal.Scale
+	bc.RoundingMode = toMarshal.RoundingMode
 	bc.CreateTime = toMarshal.CreateTime
 	bc.CreateBy = toMarshal.CreateBy
 	bc.UpdateTime = toMarshal.UpdateTime
@@ -682,6 +1036,28 @@ func (bc *BaseCurrency) SetExchange(exchange decimal.Decimal) Currency {
 	return bc
 }
 
+// GetScale get the number of decimals of the currency minor unit. e.g. 2 for USD, 0 for IDR.
+func (bc *BaseCurrency) GetScale() int32 {
+	return bc.Scale
+}
+
+// SetScale set the number of decimals of the currency minor unit
+func (bc *BaseCurrency) SetScale(scale int32) Currency {
+	bc.Scale = scale
+	return bc
+}
+
+// GetRoundingMode get how amounts of this currency are rounded to its scale. RoundingNone if they are not.
+func (bc *BaseCurrency) GetRoundingMode() RoundingMode {
+	return bc.RoundingMode
+}
+
+// SetRoundingMode set how amounts of this currency are rounded to its scale
+func (bc *BaseCurrency) SetRoundingMode(mode RoundingMode) Currency {
+	bc.RoundingMode = mode
+	return bc
+}
+
 // GetCreateTime function should return the time when this account is created/recorded.
 // this function serves as audit trail.
 func (bc *BaseCurrency) GetCreateTime() time.Time {
@@ -729,3 +1105,224 @@ func (bc *BaseCurrency) SetUpdateBy(editor string) Currency {
 	bc.UpdateBy = editor
 	return bc
 }
+
+// BaseHold is the base implementation of Hold
+type BaseHold struct {
+	HoldID         string          `json:"hold_id"`
+	AccountNumber  string          `json:"account_number"`
+	Description    string          `json:"description"`
+	Amount         decimal.Decimal `json:"amount"`
+	CapturedAmount decimal.Decimal `json:"captured_amount"`
+	State          HoldState       `json:"state"`
+	ExpireTime     time.Time       `json:"expire_time"`
+	CreateTime     time.Time       `json:"create_time"`
+	CreateBy       string          `json:"create_by"`
+	UpdateTime     time.Time       `json:"update_time"`
+	UpdateBy       string          `json:"update_by"`
+}
+
+// MarshalJSON writes the hold in JSONFormatV2. Use MarshalJSONFormat to write it in another format.
+func (hold *BaseHold) MarshalJSON() ([]byte, error) {
+	return hold.marshalJSONFormat(JSONFormatV2)
+}
+
+// marshalJSONFormat writes the hold in the JSON format.
+func (hold *BaseHold) marshalJSONFormat(format JSONFormat) ([]byte, error) {
+	toMarshal := struct {
+		FormatVersion  JSONFormat     `json:"format_version,omitempty"`
+		HoldID         string         `json:"hold_id"`
+		AccountNumber  string         `json:"account_number"`
+		Description    string         `json:"description"`
+		Amount         json.Marshaler `json:"amount"`
+		CapturedAmount json.Marshaler `json:"captured_amount"`
+		State          HoldState      `json:"state"`
+		ExpireTime     time.Time      `json:"expire_time"`
+		CreateTime     time.Time      `json:"create_time"`
+		CreateBy       string         `json:"create_by"`
+		UpdateTime     time.Time      `json:"update_time"`
+		UpdateBy       string         `json:"update_by"`
+	}{
+		FormatVersion:  format.formatVersion(),
+		HoldID:         hold.HoldID,
+		AccountNumber:  hold.AccountNumber,
+		Description:    hold.Description,
+		Amount:         format.decimal(hold.Amount),
+		CapturedAmount: format.decimal(hold.CapturedAmount),
+		State:          hold.State,
+		ExpireTime:     hold.ExpireTime,
+		CreateTime:     hold.CreateTime,
+		CreateBy:       hold.CreateBy,
+		UpdateTime:     hold.UpdateTime,
+		UpdateBy:       hold.UpdateBy,
+	}
+	return json.Marshal(toMarshal)
+}
+
+func (hold *BaseHold) UnmarshalJSON(data []byte) error {
+	// Ignore null, like in the main JSON package.
+	if string(data) == "null" || string(data) == `""` {
+		return nil
+	}
+
+	toMarshal := struct {
+		FormatVersion  *JSONFormat `json:"format_version,omitempty"`
+		HoldID         string      `json:"hold_id"`
+		AccountNumber  string      `json:"account_number"`
+		Description    string      `json:"description"`
+		Amount         jsonDecimal `json:"amount"`
+		CapturedAmount jsonDecimal `json:"captured_amount"`
+		State          HoldState   `json:"state"`
+		ExpireTime     time.Time   `json:"expire_time"`
+		CreateTime     time.Time   `json:"create_time"`
+		CreateBy       string      `json:"create_by"`
+		UpdateTime     time.Time   `json:"update_time"`
+		UpdateBy       string      `json:"update_by"`
+	}{}
+
+	err := json.Unmarshal(data, &toMarshal)
+	if err != nil {
+		return err
+	}
+	if err := checkJSONFormat(toMarshal.FormatVersion); err != nil {
+		return err
+	}
+
+	hold.HoldID = toMarshal.HoldID
+	hold.AccountNumber = toMarshal.AccountNumber
+	hold.Description = toMarshal.Description
+	hold.Amount = decimal.Decimal(toMarshal.Amount)
+	hold.CapturedAmount = decimal.Decimal(toMarshal.CapturedAmount)
+	hold.State = toMarshal.State
+	hold.ExpireTime = toMarshal.ExpireTime
+	hold.CreateTime = toMarshal.CreateTime
+	hold.CreateBy = toMarshal.CreateBy
+	hold.UpdateTime = toMarshal.UpdateTime
+	hold.UpdateBy = toMarshal.UpdateBy
+
+	return nil
+}
+
+// GetHoldID returns the unique identifier of this hold
+func (hold *BaseHold) GetHoldID() string {
+	return hold.HoldID
+}
+
+// SetHoldID will set the hold identifier
+func (hold *BaseHold) SetHoldID(newID string) Hold {
+	hold.HoldID = newID
+	return hold
+}
+
+// GetAccountNumber returns the account number of the account this hold reserves from
+func (hold *BaseHold) GetAccountNumber() string {
+	return hold.AccountNumber
+}
+
+// SetAccountNumber will set the account number
+func (hold *BaseHold) SetAccountNumber(accountNumber string) Hold {
+	hold.AccountNumber = accountNumber
+	return hold
+}
+
+// GetDescription returns some Description text about this hold
+func (hold *BaseHold) GetDescription() string {
+	return hold.Description
+}
+
+// SetDescription will set new Description
+func (hold *BaseHold) SetDescription(newDesc string) Hold {
+	hold.Description = newDesc
+	return hold
+}
+
+// GetAmount returns the amount originally held
+func (hold *BaseHold) GetAmount() decimal.Decimal {
+	return hold.Amount
+}
+
+// SetAmount will set the amount held
+func (hold *BaseHold) SetAmount(newAmount decimal.Decimal) Hold {
+	hold.Amount = newAmount
+	return hold
+}
+
+// GetCapturedAmount returns the sum of the amounts captured from this hold so far.
+func (hold *BaseHold) GetCapturedAmount() decimal.Decimal {
+	return hold.CapturedAmount
+}
+
+// SetCapturedAmount will set the captured amount
+func (hold *BaseHold) SetCapturedAmount(newAmount decimal.Decimal) Hold {
+	hold.CapturedAmount = newAmount
+	return hold
+}
+
+// GetState returns the state of this hold. Only active holds reserve their remaining amount.
+func (hold *BaseHold) GetState() HoldState {
+	return hold.State
+}
+
+// SetState will set the hold state
+func (hold *BaseHold) SetState(state HoldState) Hold {
+	hold.State = state
+	return hold
+}
+
+// GetExpireTime returns the time this hold stops reserving its remaining amount
+func (hold *BaseHold) GetExpireTime() time.Time {
+	return hold.ExpireTime
+}
+
+// SetExpireTime will set the expiry time
+func (hold *BaseHold) SetExpireTime(newTime time.Time) Hold {
+	hold.ExpireTime = newTime
+	return hold
+}
+
+// GetCreateTime function should return the time when this hold is created/recorded.
+// this function serves as audit trail.
+func (hold *BaseHold) GetCreateTime() time.Time {
+	return hold.CreateTime
+}
+
+// SetCreateTime will set new creation time
+func (hold *BaseHold) SetCreateTime(newTime time.Time) Hold {
+	hold.CreateTime = newTime
+	return hold
+}
+
+// GetCreateBy function should return the user AccountNumber or some identification of who is creating this hold.
+// this function serves as audit trail.
+func (hold *BaseHold) GetCreateBy() string {
+	return hold.CreateBy
+}
+
+// SetCreateBy will set the creator Name
+func (hold *BaseHold) SetCreateBy(creator string) Hold {
+	hold.CreateBy = creator
+	return hold
+}
+
+// GetUpdateTime function should return the time when this hold is last captured, released or expired.
+// this function serves as audit trail.
+func (hold *BaseHold) GetUpdateTime() time.Time {
+	return hold.UpdateTime
+}
+
+// SetUpdateTime will set the last update time.
+func (hold *BaseHold) SetUpdateTime(newTime time.Time) Hold {
+	hold.UpdateTime = newTime
+	return hold
+}
+
+// GetUpdateBy function should return the user AccountNumber or some identification of who is last updating this hold.
+// this function serves as audit trail.
+func (hold *BaseHold) GetUpdateBy() string {
+	return hold.UpdateBy
+}
+
+// SetUpdateBy will set the updater Name
+func (hold *BaseHold) SetUpdateBy(editor string) Hold {
+	hold.UpdateBy = editor
+	return hold
+}
@@ -33,3 +33,78 @@ func TestBaseAccount_MarshalJSON(t *testing.T) {
 
 	assert.Equal(t, sample.Name, result.Name)
 }
+
+func TestModel_ExactDecimalJSON(t *testing.T) {
+	exact := decimal.RequireFromString("123456789012345678901234.123456789012")
+
+	account := &BaseAccount{AccountNumber: "1234", Balance: exact}
+	bytes, err := json.Marshal(account)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), `"format_version":2`)
+	assert.Contains(t, string(bytes), `"balance":"123456789012345678901234.123456789012"`)
+	accountResult := &BaseAccount{}
+	assert.NoError(t, json.Unmarshal(bytes, accountResult))
+	assert.True(t, exact.Equal(accountResult.Balance))
+
+	trx := &BaseTransaction{TransactionID: "TRX", Amount: exact, AccountBalance: exact.Neg()}
+	bytes, err = json.Marshal(trx)
+	assert.NoError(t, err)
+	trxResult := &BaseTransaction{}
+	assert.NoError(t, json.Unmarshal(bytes, trxResult))
+	assert.True(t, exact.Equal(trxResult.Amount))
+	assert.True(t, exact.Neg().Equal(trxResult.AccountBalance))
+
+	currency := &BaseCurrency{Code: "BTC", Exchange: decimal.RequireFromString("0.000000000001")}
+	bytes, err = json.Marshal(currency)
+	assert.NoError(t, err)
+	currencyResult := &BaseCurrency{}
+	assert.NoError(t, json.Unmarshal(bytes, currencyResult))
+	assert.True(t, currency.Exchange.Equal(currencyResult.Exchange))
+
+	journal := &BaseJournal{JournalID: "JRN", Amount: exact}
+	bytes, err = json.Marshal(journal)
+	assert.NoError(t, err)
+	journalResult := &BaseJournal{}
+	assert.NoError(t, json.Unmarshal(bytes, journalResult))
+	assert.True(t, exact.Equal(journalResult.Amount))
+}
+
+func TestModel_LegacyDecimalJSON(t *testing.T) {
+	// the legacy format have no version and the decimals are numbers.
+	legacy := `{"transaction_id":"TRX","amount":1234.5678,"account_balance":-0.1}`
+	trx := &BaseTransaction{}
+	assert.NoError(t, json.Unmarshal([]byte(legacy), trx))
+	assert.True(t, trx.Amount.Equal(decimal.RequireFromString("1234.5678")))
+	assert.True(t, trx.AccountBalance.Equal(decimal.RequireFromString("-0.1")))
+
+	bytes, err := MarshalJSONFormat(&BaseAccount{AccountNumber: "1234", Balance: decimal.RequireFromString("10.25")}, JSONFormatV1)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(bytes), "format_version")
+	assert.Contains(t, string(bytes), `"balance":10.25`)
+	// writing the legacy format leaves json.Marshal writing the current one.
+	bytes, err = json.Marshal(&BaseAccount{AccountNumber: "1234", Balance: decimal.RequireFromString("10.25")})
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), `"balance":"10.25"`)
+	_, err = MarshalJSONFormat(&BaseAccount{AccountNumber: "1234"}, JSONFormat(3))
+	assert.ErrorIs(t, err, ErrJSONFormatUnsupported)
+
+	// the transactions of a journal are written in the same format.
+	journal := &BaseJournal{JournalID: "JRN", Amount: decimal.RequireFromString("10.25"), Transactions: []Transaction{
+		&BaseTransaction{TransactionID: "TRX", Amount: decimal.RequireFromString("10.25")},
+	}}
+	bytes, err = MarshalJSONFormat(journal, JSONFormatV1)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(bytes), "format_version")
+	assert.NotContains(t, string(bytes), `"10.25"`)
+	bytes, err = NewJournalCodec().SetJSONFormat(JSONFormatV1).Marshal(journal)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(bytes), "format_version")
+	assert.NotContains(t, string(bytes), `"10.25"`)
+	_, err = NewJournalCodec().SetJSONFormat(JSONFormat(0)).Marshal(journal)
+	assert.ErrorIs(t, err, ErrJSONFormatUnsupported)
+
+	for _, version := range []string{"-1", "0", "3"} {
+		err = json.Unmarshal([]byte(`{"format_version":`+version+`,"account_number":"1234"}`), &BaseAccount{})
+		assert.ErrorIs(t, err, ErrJSONFormatUnsupported, "format version %s", version)
+	}
+}
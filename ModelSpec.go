@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// Alignment is the enum type of transaction type, DEBIT and CREDIT
+type Alignment int
+
 const (
 	// DEBIT is enum transaction type DEBIT
 	DEBIT Alignment = iota
@@ -12,8 +15,93 @@ const (
 	CREDIT
 )
 
-// Alignment is the enum type of transaction type, DEBIT and CREDIT
-type Alignment int
+// BalanceLimit is the enum type of the constraint on the Balance of an account, UnlimitedBalance, NonNegativeBalance and OverdraftBalance
+type BalanceLimit int
+
+const (
+	// UnlimitedBalance is enum balance limit of accounts whose Balance is not constrained, and may go below zero
+	UnlimitedBalance BalanceLimit = iota
+	// NonNegativeBalance is enum balance limit of accounts whose Balance may never go below zero
+	NonNegativeBalance
+	// OverdraftBalance is enum balance limit of accounts whose Balance may go below zero, down to their overdraft limit
+	OverdraftBalance
+)
+
+// AccountState is the enum type of the lifecycle state of an account, AccountActive, AccountDebitFrozen, AccountCreditFrozen, AccountFrozen and AccountClosed
+type AccountState int
+
+const (
+	// AccountActive is enum account state of accounts accepting all transactions
+	AccountActive AccountState = iota
+	// AccountDebitFrozen is enum account state of accounts rejecting DEBIT transactions
+	AccountDebitFrozen
+	// AccountCreditFrozen is enum account state of accounts rejecting CREDIT transactions
+	AccountCreditFrozen
+	// AccountFrozen is enum account state of accounts rejecting all transactions
+	AccountFrozen
+	// AccountClosed is enum account state of accounts rejecting all transactions for good, a closed account can not change its state anymore
+	AccountClosed
+)
+
+// HoldState is the enum type of the state of a hold, HoldActive, HoldCaptured, HoldReleased and HoldExpired
+type HoldState int
+
+const (
+	// HoldActive is enum hold state of holds still reserving their remaining amount
+	HoldActive HoldState = iota
+	// HoldCaptured is enum hold state of holds whose whole amount have been captured into journals
+	HoldCaptured
+	// HoldReleased is enum hold state of holds whose remaining amount have been released
+	HoldReleased
+	// HoldExpired is enum hold state of holds whose remaining amount have been released as they expired
+	HoldExpired
+)
+
+// RoundingMode is the enum type of how amounts of a Currency are rounded to its scale, RoundingNone, RoundHalfEven,
+// RoundHalfUp, RoundDown, RoundUp, RoundCeiling and RoundFloor
+type RoundingMode int
+
+const (
+	// RoundingNone is enum rounding mode of currencies whose amounts are not rounded, and may have any number of decimals
+	RoundingNone RoundingMode = iota
+	// RoundHalfEven is enum rounding mode rounding to the nearest, and ties to the even digit, also known as banker's rounding
+	RoundHalfEven
+	// RoundHalfUp is enum rounding mode rounding to the nearest, and ties away from zero
+	RoundHalfUp
+	// RoundDown is enum rounding mode rounding toward zero, truncating the extra decimals
+	RoundDown
+	// RoundUp is enum rounding mode rounding away from zero
+	RoundUp
+	// RoundCeiling is enum rounding mode rounding toward positive infinity
+	RoundCeiling
+	// RoundFloor is enum rounding mode rounding toward negative infinity
+	RoundFloor
+)
+
+// COACategory is the enum type of the chart of accounts categories, ASSET, LIABILITY, EQUITY, INCOME and EXPENSE
+type COACategory int
+
+const (
+	// ASSET is enum COA category of assets
+	ASSET COACategory = iota
+	// LIABILITY is enum COA category of liabilities
+	LIABILITY
+	// EQUITY is enum COA category of equities
+	EQUITY
+	// INCOME is enum COA category of incomes
+	INCOME
+	// EXPENSE is enum COA category of expenses
+	EXPENSE
+)
+
+// NormalAlignment returns the Alignment accounts of this category normally have.
+// ASSET and EXPENSE are DEBIT, while LIABILITY, EQUITY and INCOME are CREDIT.
+func (category COACategory) NormalAlignment() Alignment {
+	if category == ASSET || category == EXPENSE {
+		return DEBIT
+	}
+	return CREDIT
+}
 
 // Journal interface define a base Journal structure.
 // A journal depict an event where Transactions is happening.
@@ -70,6 +158,18 @@ type Journal interface {
 	GetCreateBy() string
 	// SetCreateBy will set the creator Name
 	SetCreateBy(creator string) Journal
+
+	// GetIdempotencyKey returns the client supplied key that identify the request creating this journal.
+	// Empty if the journal is not created with an idempotency key.
+	GetIdempotencyKey() string
+	// SetIdempotencyKey will set the idempotency key
+	SetIdempotencyKey(key string) Journal
+
+	// GetExchangeRate returns the exchange rate applied between the currencies of a cross-currency journal.
+	// Zero if the journal Transactions all belong to the same Currency.
+	GetExchangeRate() decimal.Decimal
+	// SetExchangeRate will set the applied exchange rate
+	SetExchangeRate(rate decimal.Decimal) Journal
 }
 
 // Transaction interface define a base Transaction structure
@@ -167,6 +267,23 @@ type Account interface {
 	// SetBalance will set new transaction Balance
 	SetBalance(newBalance decimal.Decimal) Account
 
+	// GetBalanceLimit returns the constraint on the Balance of this account.
+	// Transactions taking the Balance below what the limit allows are rejected when their journal is committed.
+	GetBalanceLimit() BalanceLimit
+	// SetBalanceLimit will set the constraint on the Balance
+	SetBalanceLimit(limit BalanceLimit) Account
+
+	// GetOverdraftLimit returns how far below zero the Balance may go, used when the balance limit is OverdraftBalance.
+	GetOverdraftLimit() decimal.Decimal
+	// SetOverdraftLimit will set how far below zero the Balance may go
+	SetOverdraftLimit(limit decimal.Decimal) Account
+
+	// GetState returns the lifecycle state of this account, which decides the transactions the account accepts.
+	// The state is changed using AccountManager.ChangeAccountState, it is not written by AccountManager.UpdateAccount.
+	GetState() AccountState
+	// SetState will set the lifecycle state
+	SetState(state AccountState) Account
+
 	// GetCOA returns the COA code for this account, used for categorization of account.
 	GetCOA() string
 	// SetCOA Will set new COA code
@@ -195,6 +312,61 @@ type Account interface {
 	GetUpdateBy() string
 	// SetUpdateBy will set the updater Name
 	SetUpdateBy(editor string) Account
+
+	// GetVersion returns the revision of this account record.
+	// The version is incremented on every write of the account, including every Balance change,
+	// the account is only updated if its version is still the one read.
+	GetVersion() int64
+	// SetVersion will set the account revision
+	SetVersion(newVersion int64) Account
+}
+
+// COA interface provides base structure of a node in the chart of accounts.
+// Each node is identified by its code, the code that accounts refer to using `Account.GetCOA()`.
+// Nodes form a tree, a node without parent code is a root of the chart. A node have the same category as its parent.
+type COA interface {
+	// GetCode returns the unique COA code. e.g. `1.1`
+	GetCode() string
+	// SetCode will set the COA code
+	SetCode(code string) COA
+
+	// GetParentCode returns the code of the parent node, empty if this node is a root.
+	GetParentCode() string
+	// SetParentCode will set the code of the parent node
+	SetParentCode(parentCode string) COA
+
+	// GetName returns the COA Name. e.g. `Current Assets`
+	GetName() string
+	// SetName will set the COA Name
+	SetName(name string) COA
+
+	// GetDescription returns some Description text about this COA
+	GetDescription() string
+	// SetDescription will set new Description
+	SetDescription(newDesc string) COA
+
+	// GetCategory returns the category of this COA
+	GetCategory() COACategory
+	// SetCategory will set the category of this COA
+	SetCategory(category COACategory) COA
+
+	// GetAlignment returns the Alignment expected from the accounts under this COA.
+	// Usually its the normal alignment of the category, except for contra accounts.
+	GetAlignment() Alignment
+	// SetAlignment will set the expected Alignment
+	SetAlignment(alignment Alignment) COA
+
+	// GetCreateTime function should return the time when this COA is created/recorded.
+	// this function serves as audit trail.
+	GetCreateTime() time.Time
+	// SetCreateTime will set new creation time
+	SetCreateTime(newTime time.Time) COA
+
+	// GetCreateBy function should return the user AccountNumber or some identification of who is creating this COA.
+	// this function serves as audit trail.
+	GetCreateBy() string
+	// SetCreateBy will set the creator Name
+	SetCreateBy(creator string) COA
 }
 
 // Currency interface provides base structure of Currency
@@ -214,6 +386,17 @@ type Currency interface {
 	// SetExchange set the exchange unit of this currency toward the denominator value
 	SetExchange(exchange decimal.Decimal) Currency
 
+	// GetScale get the number of decimals of the currency minor unit. e.g. 2 for USD, 0 for IDR.
+	// The scale is only enforced if the rounding mode is not RoundingNone.
+	GetScale() int32
+	// SetScale set the number of decimals of the currency minor unit
+	SetScale(scale int32) Currency
+
+	// GetRoundingMode get how amounts of this currency are rounded to its scale. RoundingNone if they are not.
+	GetRoundingMode() RoundingMode
+	// SetRoundingMode set how amounts of this currency are rounded to its scale
+	SetRoundingMode(mode RoundingMode) Currency
+
 	// GetCreateTime function should return the time when this account is created/recorded.
 	// this function serves as audit trail.
 	GetCreateTime() time.Time
@@ -238,3 +421,69 @@ type Currency interface {
 	// SetUpdateBy will set the updater Name
 	SetUpdateBy(editor string) Currency
 }
+
+// Hold interface provides base structure of a fund hold.
+// A hold reserves an amount of an account Balance until it expires, so the amount is no longer available
+// while the account Balance itself is left untouched. The held amount is either captured into journals,
+// partially or in full, or released.
+type Hold interface {
+	// GetHoldID returns the unique identifier of this hold
+	GetHoldID() string
+	// SetHoldID will set the hold identifier
+	SetHoldID(newID string) Hold
+
+	// GetAccountNumber returns the account number of the account this hold reserves from
+	GetAccountNumber() string
+	// SetAccountNumber will set the account number
+	SetAccountNumber(accountNumber string) Hold
+
+	// GetDescription returns some Description text about this hold
+	GetDescription() string
+	// SetDescription will set new Description
+	SetDescription(newDesc string) Hold
+
+	// GetAmount returns the amount originally held
+	GetAmount() decimal.Decimal
+	// SetAmount will set the amount held
+	SetAmount(newAmount decimal.Decimal) Hold
+
+	// GetCapturedAmount returns the sum of the amounts captured from this hold so far.
+	// The remaining amount held is the amount minus the captured amount.
+	GetCapturedAmount() decimal.Decimal
+	// SetCapturedAmount will set the captured amount
+	SetCapturedAmount(newAmount decimal.Decimal) Hold
+
+	// GetState returns the state of this hold. Only active holds reserve their remaining amount.
+	GetState() HoldState
+	// SetState will set the hold state
+	SetState(state HoldState) Hold
+
+	// GetExpireTime returns the time this hold stops reserving its remaining amount
+	GetExpireTime() time.Time
+	// SetExpireTime will set the expiry time
+	SetExpireTime(newTime time.Time) Hold
+
+	// GetCreateTime function should return the time when this hold is created/recorded.
+	// this function serves as audit trail.
+	GetCreateTime() time.Time
+	// SetCreateTime will set new creation time
+	SetCreateTime(newTime time.Time) Hold
+
+	// GetCreateBy function should return the user AccountNumber or some identification of who is creating this hold.
+	// this function serves as audit trail.
+	GetCreateBy() string
+	// SetCreateBy will set the creator Name
+	SetCreateBy(creator string) Hold
+
+	// GetUpdateTime function should return the time when this hold is last captured, released or expired.
+	// this function serves as audit trail.
+	GetUpdateTime() time.Time
+	// SetUpdateTime will set the last update time.
+	SetUpdateTime(newTime time.Time) Hold
+
+	// GetUpdateBy function should return the user AccountNumber or some identification of who is last updating this hold.
+	// this function serves as audit trail.
+	GetUpdateBy() string
+	// SetUpdateBy will set the updater Name
+	SetUpdateBy(editor string) Hold
+}
@@ -1,5 +1,15 @@
 package acccore
 
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
 // Sort define a sorting information, it specifies the column should be sorted and whether it should be ASCENDING or
 // DESCENDING
 type Sort struct {
@@ -140,3 +150,191 @@ type PageResult struct {
 	// If Request.RequestForItemSize is 10 then the 4nd offset is 30
 	Offset int
 }
+
+// UnknownSortColumnError is returned when a PageRequest sorts on a column the listed entity can not be sorted by.
+// It matches ErrUnknownSortColumn using errors.Is.
+type UnknownSortColumnError struct {
+	// Entity is the name of the listed entity
+	Entity string
+	// Column is the requested sort column
+	Column string
+}
+
+// Error returns the error message
+func (e *UnknownSortColumnError) Error() string {
+	return fmt.Sprintf("%s can not be sorted by column %q", e.Entity, e.Column)
+}
+
+// Is returns true if the target is ErrUnknownSortColumn
+func (e *UnknownSortColumnError) Is(target error) bool {
+	return target == ErrUnknownSortColumn
+}
+
+// sortColumn define a column an entity can be sorted by.
+type sortColumn[T any] struct {
+	// sql is the table column to order by
+	sql string
+	// value returns the column value of an entity, which can be a string, bool, int, Alignment, time.Time or decimal.Decimal
+	value func(entity T) any
+}
+
+// sortRegistry define all the columns an entity can be sorted by, keyed by the Sort.Column name.
+type sortRegistry[T any] struct {
+	// entity is the name of the entity, used in the errors
+	entity string
+	// columns are the sortable columns
+	columns map[string]sortColumn[T]
+	// defaults are the sorts used when the PageRequest have none
+	defaults []Sort
+	// key is the unique column, always sorted last so pages have a stable order
+	key string
+}
+
+var (
+	accountSortRegistry = &sortRegistry[Account]{
+		entity: "account",
+		columns: map[string]sortColumn[Account]{
+			"account_number": {sql: "account_number", value: func(a Account) any { return a.GetAccountNumber() }},
+			"name":           {sql: "name", value: func(a Account) any { return a.GetName() }},
+			"currency":       {sql: "currency", value: func(a Account) any { return a.GetCurrency() }},
+			"coa":            {sql: "coa", value: func(a Account) any { return a.GetCOA() }},
+			"alignment":      {sql: "alignment", value: func(a Account) any { return a.GetAlignment() }},
+			"balance":        {sql: "balance", value: func(a Account) any { return a.GetBalance() }},
+			"create_time":    {sql: "create_time", value: func(a Account) any { return a.GetCreateTime() }},
+			"update_time":    {sql: "update_time", value: func(a Account) any { return a.GetUpdateTime() }},
+		},
+		defaults: []Sort{{Column: "create_time", Ascending: true}},
+		key:      "account_number",
+	}
+	journalSortRegistry = &sortRegistry[Journal]{
+		entity: "journal",
+		columns: map[string]sortColumn[Journal]{
+			"journal_id":      {sql: "journal_id", value: func(j Journal) any { return j.GetJournalID() }},
+			"journaling_time": {sql: "journaling_time", value: func(j Journal) any { return j.GetJournalingTime() }},
+			"description":     {sql: "description", value: func(j Journal) any { return j.GetDescription() }},
+			"reversal":        {sql: "reversal", value: func(j Journal) any { return j.IsReversal() }},
+			"amount":          {sql: "amount", value: func(j Journal) any { return j.GetAmount() }},
+			"create_time":     {sql: "create_time", value: func(j Journal) any { return j.GetCreateTime() }},
+			"create_by":       {sql: "create_by", value: func(j Journal) any { return j.GetCreateBy() }},
+		},
+		defaults: []Sort{{Column: "journaling_time", Ascending: true}},
+		key:      "journal_id",
+	}
+	transactionSortRegistry = &sortRegistry[Transaction]{
+		entity: "transaction",
+		columns: map[string]sortColumn[Transaction]{
+			"transaction_id":   {sql: "transaction_id", value: func(t Transaction) any { return t.GetTransactionID() }},
+			"transaction_time": {sql: "transaction_time", value: func(t Transaction) any { return t.GetTransactionTime() }},
+			"journal_id":       {sql: "journal_id", value: func(t Transaction) any { return t.GetJournalID() }},
+			"description":      {sql: "description", value: func(t Transaction) any { return t.GetDescription() }},
+			"alignment":        {sql: "alignment", value: func(t Transaction) any { return t.GetAlignment() }},
+			"amount":           {sql: "amount", value: func(t Transaction) any { return t.GetAmount() }},
+			"account_balance":  {sql: "account_balance", value: func(t Transaction) any { return t.GetAccountBalance() }},
+			"create_time":      {sql: "create_time", value: func(t Transaction) any { return t.GetCreateTime() }},
+		},
+		defaults: []Sort{{Column: "transaction_time", Ascending: true}},
+		key:      "transaction_id",
+	}
+)
+
+// AccountSortColumns returns the columns accounts can be sorted by, in alphabetical order.
+func AccountSortColumns() []string {
+	return accountSortRegistry.columnNames()
+}
+
+// JournalSortColumns returns the columns journals can be sorted by, in alphabetical order.
+func JournalSortColumns() []string {
+	return journalSortRegistry.columnNames()
+}
+
+// TransactionSortColumns returns the columns transactions can be sorted by, in alphabetical order.
+func TransactionSortColumns() []string {
+	return transactionSortRegistry.columnNames()
+}
+
+// columnNames returns the sortable column names, in alphabetical order.
+func (registry *sortRegistry[T]) columnNames() []string {
+	names := make([]string, 0, len(registry.columns))
+	for name := range registry.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortsOf returns the sorts to apply for the request. It uses the default sorts if the request have none,
+// and always ends with the unique key column. An UnknownSortColumnError is returned for unknown columns.
+func (registry *sortRegistry[T]) sortsOf(request PageRequest) ([]Sort, error) {
+	sorts := request.Sorts
+	if len(sorts) == 0 {
+		sorts = registry.defaults
+	}
+	for _, s := range sorts {
+		if _, ok := registry.columns[s.Column]; !ok {
+			return nil, &UnknownSortColumnError{Entity: registry.entity, Column: s.Column}
+		}
+	}
+	return append(append(make([]Sort, 0, len(sorts)+1), sorts...), Sort{Column: registry.key, Ascending: true}), nil
+}
+
+// orderBy returns the SQL ORDER BY expression for the request, without the ORDER BY keyword.
+func (registry *sortRegistry[T]) orderBy(request PageRequest) (string, error) {
+	sorts, err := registry.sortsOf(request)
+	if err != nil {
+		return "", err
+	}
+	terms := make([]string, len(sorts))
+	for i, s := range sorts {
+		direction := "DESC"
+		if s.Ascending {
+			direction = "ASC"
+		}
+		terms[i] = registry.columns[s.Column].sql + " " + direction
+	}
+	return strings.Join(terms, ", "), nil
+}
+
+// sortSlice sorts the entities following the request.
+func (registry *sortRegistry[T]) sortSlice(request PageRequest, entities []T) error {
+	sorts, err := registry.sortsOf(request)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(entities, func(i, j int) bool {
+		for _, s := range sorts {
+			column := registry.columns[s.Column]
+			c := compareSortValues(column.value(entities[i]), column.value(entities[j]))
+			if c != 0 {
+				return (c < 0) == s.Ascending
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// compareSortValues compares two column values of the same type, returning -1, 0 or +1.
+func compareSortValues(a, b any) int {
+	switch av := a.(type) {
+	case string:
+		return cmp.Compare(av, b.(string))
+	case int:
+		return cmp.Compare(av, b.(int))
+	case Alignment:
+		return cmp.Compare(av, b.(Alignment))
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if !av {
+			return -1
+		}
+		return 1
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	case decimal.Decimal:
+		return av.Cmp(b.(decimal.Decimal))
+	}
+	return 0
+}
@@ -2,6 +2,8 @@ package acccore
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 type PageTest struct {
@@ -198,3 +200,17 @@ func TestPageResultFor(t *testing.T) {
 		}
 	}
 }
+
+func TestSortRegistry_orderBy(t *testing.T) {
+	orderBy, err := accountSortRegistry.orderBy(PageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "create_time ASC, account_number ASC", orderBy)
+	orderBy, err = accountSortRegistry.orderBy(PageRequest{Sorts: []Sort{{Column: "coa", Ascending: true}, {Column: "balance", Ascending: false}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "coa ASC, balance DESC, account_number ASC", orderBy)
+	_, err = journalSortRegistry.orderBy(PageRequest{Sorts: []Sort{{Column: "unknown"}}})
+	assert.ErrorIs(t, err, ErrUnknownSortColumn)
+	assert.Contains(t, JournalSortColumns(), "journaling_time")
+	assert.Contains(t, TransactionSortColumns(), "account_balance")
+	assert.Contains(t, AccountSortColumns(), "balance")
+}
@@ -0,0 +1,278 @@
+package acccore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// reportPageSize is the number of items fetched per page when a report walks through the records.
+	reportPageSize = 100
+)
+
+// TrialBalanceLine is the balance of a single account in a trial balance.
+// The balance is put on the DEBIT or CREDIT column following the account Alignment,
+// a negative balance is put on the opposite column.
+type TrialBalanceLine struct {
+	// Account is the account of this line
+	Account Account
+	// Debit is the debit balance of the account, zero if it have credit balance
+	Debit decimal.Decimal
+	// Credit is the credit balance of the account, zero if it have debit balance
+	Credit decimal.Decimal
+}
+
+// TrialBalanceReport lists the balance of all accounts of a Currency at a point in time.
+// The books are balanced if the total debit equals the total credit.
+type TrialBalanceReport struct {
+	// AsOf is the time of the balances
+	AsOf time.Time
+	// Currency is the currency of all the accounts in this report
+	Currency string
+	// Lines are the account balances, ordered by the account COA and number
+	Lines []*TrialBalanceLine
+	// TotalDebit is the sum of all the debit balances
+	TotalDebit decimal.Decimal
+	// TotalCredit is the sum of all the credit balances
+	TotalCredit decimal.Decimal
+}
+
+// IsBalanced returns true if the total debit equals the total credit.
+func (report *TrialBalanceReport) IsBalanced() bool {
+	return report.TotalDebit.Equal(report.TotalCredit)
+}
+
+// TrialBalance creates the trial balance of all accounts of the specified currency, using their balance at the `asOf` time.
+// Accounts created after the `asOf` time are not included.
+func (acc *Accounting) TrialBalance(context context.Context, asOf time.Time, currency string) (*TrialBalanceReport, error) {
+	if len(currency) == 0 {
+		return nil, ErrReportMissingCurrency
+	}
+	accounts, err := acc.listAccountsOfCurrency(context, currency, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TrialBalanceReport{
+		AsOf:        asOf,
+		Currency:    currency,
+		Lines:       make([]*TrialBalanceLine, 0, len(accounts)),
+		TotalDebit:  decimal.Zero,
+		TotalCredit: decimal.Zero,
+	}
+	for _, account := range accounts {
+		balance, err := acc.accountBalanceAt(context, account, asOf)
+		if err != nil {
+			return nil, err
+		}
+		line := &TrialBalanceLine{
+			Account: account,
+			Debit:   decimal.Zero,
+			Credit:  decimal.Zero,
+		}
+		// a negative balance goes to the column opposite of the account alignment.
+		alignment := account.GetAlignment()
+		if balance.IsNegative() {
+			balance = balance.Neg()
+			if alignment == DEBIT {
+				alignment = CREDIT
+			} else {
+				alignment = DEBIT
+			}
+		}
+		if alignment == DEBIT {
+			line.Debit = balance
+		} else {
+			line.Credit = balance
+		}
+		report.TotalDebit = report.TotalDebit.Add(line.Debit)
+		report.TotalCredit = report.TotalCredit.Add(line.Credit)
+		report.Lines = append(report.Lines, line)
+	}
+	return report, nil
+}
+
+// RenderTrialBalance will render the trial balance into string for easy inspection
+func (acc *Accounting) RenderTrialBalance(context context.Context, report *TrialBalanceReport) string {
+	var buff bytes.Buffer
+	table := tablewriter.NewWriter(&buff)
+	table.SetHeader([]string{"Account", "Name", "COA", "DEBIT", "CREDIT"})
+	table.SetFooter([]string{"", "", "", report.TotalDebit.String(), report.TotalCredit.String()})
+
+	for _, line := range report.Lines {
+		debit, credit := "", ""
+		if !line.Debit.IsZero() {
+			debit = line.Debit.String()
+		}
+		if !line.Credit.IsZero() {
+			credit = line.Credit.String()
+		}
+		table.Append([]string{line.Account.GetAccountNumber(), line.Account.GetName(), line.Account.GetCOA(), debit, credit})
+	}
+	buff.WriteString(fmt.Sprintf("Trial Balance : %s\n", report.Currency))
+	buff.WriteString(fmt.Sprintf("As Of         : %s\n", report.AsOf.String()))
+	buff.WriteString(fmt.Sprintf("Balanced      : %t\n", report.IsBalanced()))
+	table.Render()
+	return buff.String()
+}
+
+// listAccountsOfCurrency lists all accounts of the currency that are created not after the `asOf` time,
+// ordered by their COA and account number.
+func (acc *Accounting) listAccountsOfCurrency(context context.Context, currency string, asOf time.Time) ([]Account, error) {
+	all, err := acc.listAccountsAsOf(context, asOf)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]Account, 0, len(all))
+	for _, account := range all {
+		if account.GetCurrency() == currency {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+// listAccountsAsOf lists all accounts that are created not after the `asOf` time,
+// ordered by their COA and account number.
+func (acc *Accounting) listAccountsAsOf(context context.Context, asOf time.Time) ([]Account, error) {
+	accounts := make([]Account, 0)
+	request := PageRequest{PageNo: 1, ItemSize: reportPageSize}
+	for {
+		result, page, err := acc.GetAccountManager().ListAccounts(context, request)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range page {
+			if !account.GetCreateTime().After(asOf) {
+				accounts = append(accounts, account)
+			}
+		}
+		if !result.HaveNext {
+			break
+		}
+		request.PageNo = result.NextPage
+	}
+	sort.SliceStable(accounts, func(i, j int) bool {
+		if accounts[i].GetCOA() != accounts[j].GetCOA() {
+			return accounts[i].GetCOA() < accounts[j].GetCOA()
+		}
+		return accounts[i].GetAccountNumber() < accounts[j].GetAccountNumber()
+	})
+	return accounts, nil
+}
+
+// accountBalanceAt returns the balance of the account at the specified time.
+func (acc *Accounting) accountBalanceAt(context context.Context, account Account, at time.Time) (decimal.Decimal, error) {
+	return acc.GetTransactionManager().GetBalanceAt(context, account.GetAccountNumber(), at)
+}
+
+// accountMovement calculates the change of the account balance between the `from` and `until` time range inclusive,
+// by summing all of its transactions within that time range.
+func (acc *Accounting) accountMovement(context context.Context, account Account, from, until time.Time) (decimal.Decimal, error) {
+	balance := decimal.Zero
+	request := PageRequest{PageNo: 1, ItemSize: reportPageSize}
+	for {
+		result, transactions, err := acc.GetTransactionManager().ListTransactionsOnAccount(context, from, until, account, request)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		for _, trx := range transactions {
+			if trx.GetAlignment() == account.GetAlignment() {
+				balance = balance.Add(trx.GetAmount())
+			} else {
+				balance = balance.Sub(trx.GetAmount())
+			}
+		}
+		if !result.HaveNext {
+			break
+		}
+		request.PageNo = result.NextPage
+	}
+	return balance, nil
+}
+
+// COASubtreeBalance rolls up the balance at the `asOf` time of all accounts of the currency,
+// under the COA with the specified code and all of its descendants.
+// The balance is expressed in the Alignment expected by the COA, so accounts with the opposite Alignment
+// such as contra accounts reduce the balance.
+func (acc *Accounting) COASubtreeBalance(context context.Context, code, currency string, asOf time.Time) (decimal.Decimal, error) {
+	if acc.GetCOAManager() == nil {
+		return decimal.Zero, ErrCOAManagerNotSet
+	}
+	if len(currency) == 0 {
+		return decimal.Zero, ErrReportMissingCurrency
+	}
+	root, err := acc.GetCOAManager().GetCOAByCode(context, code)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	subtree, err := acc.coaSubtree(context, root)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	total := decimal.Zero
+	for _, coa := range subtree {
+		accounts, err := acc.listAccountsOfCOA(context, coa.GetCode(), currency, asOf)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		for _, account := range accounts {
+			balance, err := acc.accountBalanceAt(context, account, asOf)
+			if err != nil {
+				return decimal.Zero, err
+			}
+			if account.GetAlignment() == root.GetAlignment() {
+				total = total.Add(balance)
+			} else {
+				total = total.Sub(balance)
+			}
+		}
+	}
+	return total, nil
+}
+
+// coaSubtree returns the COA and all of its descendants, depth first.
+func (acc *Accounting) coaSubtree(context context.Context, coa COA) ([]COA, error) {
+	subtree := []COA{coa}
+	children, err := acc.GetCOAManager().ListCOAChildren(context, coa.GetCode())
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		descendants, err := acc.coaSubtree(context, child)
+		if err != nil {
+			return nil, err
+		}
+		subtree = append(subtree, descendants...)
+	}
+	return subtree, nil
+}
+
+// listAccountsOfCOA lists all accounts of the currency directly under the COA, that are created not after the `asOf` time.
+func (acc *Accounting) listAccountsOfCOA(context context.Context, code, currency string, asOf time.Time) ([]Account, error) {
+	accounts := make([]Account, 0)
+	request := PageRequest{PageNo: 1, ItemSize: reportPageSize}
+	for {
+		result, page, err := acc.GetAccountManager().ListAccountByCOA(context, code, request)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range page {
+			if account.GetCurrency() == currency && !account.GetCreateTime().After(asOf) {
+				accounts = append(accounts, account)
+			}
+		}
+		if !result.HaveNext {
+			break
+		}
+		request.PageNo = result.NextPage
+	}
+	return accounts, nil
+}
@@ -0,0 +1,75 @@
+package acccore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTrialBalance(t *testing.T, acc *Accounting) {
+	ctx := context.Background()
+
+	cash, err := acc.CreateNewAccount(ctx, "CASH", "Cash", "Cash on hand", "1.1", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	receivable, err := acc.CreateNewAccount(ctx, "RECEIVABLE", "Receivable", "Account receivable", "1.2", "IDR", DEBIT, "aCreator")
+	assert.NoError(t, err)
+	equity, err := acc.CreateNewAccount(ctx, "EQUITY", "Equity", "Owner equity", "3.1", "IDR", CREDIT, "aCreator")
+	assert.NoError(t, err)
+	_, err = acc.CreateNewAccount(ctx, "GOLD", "Gold", "Gold reserve", "1.1", "GOLD", DEBIT, "aCreator")
+	assert.NoError(t, err)
+
+	_, err = acc.CreateNewJournal(ctx, "Capital", []TransactionInfo{
+		{AccountNumber: cash.GetAccountNumber(), Description: "Capital", TxType: DEBIT, Amount: decimal.NewFromInt(1000)},
+		{AccountNumber: equity.GetAccountNumber(), Description: "Capital", TxType: CREDIT, Amount: decimal.NewFromInt(1000)},
+	}, "aCreator")
+	assert.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	asOf := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	// cash goes negative, so it shows up on the credit column.
+	_, err = acc.CreateNewJournal(ctx, "Loan", []TransactionInfo{
+		{AccountNumber: receivable.GetAccountNumber(), Description: "Loan", TxType: DEBIT, Amount: decimal.NewFromInt(1500)},
+		{AccountNumber: cash.GetAccountNumber(), Description: "Loan", TxType: CREDIT, Amount: decimal.NewFromInt(1500)},
+	}, "aCreator")
+	assert.NoError(t, err)
+
+	report, err := acc.TrialBalance(ctx, time.Now(), "IDR")
+	assert.NoError(t, err)
+	t.Log(acc.RenderTrialBalance(ctx, report))
+	assert.True(t, report.IsBalanced())
+	assert.Len(t, report.Lines, 3)
+	assert.Equal(t, "CASH", report.Lines[0].Account.GetAccountNumber())
+	assert.True(t, report.Lines[0].Debit.IsZero())
+	assert.True(t, report.Lines[0].Credit.Equal(decimal.NewFromInt(500)))
+	assert.True(t, report.Lines[1].Debit.Equal(decimal.NewFromInt(1500)))
+	assert.True(t, report.Lines[2].Credit.Equal(decimal.NewFromInt(1000)))
+	assert.True(t, report.TotalDebit.Equal(decimal.NewFromInt(1500)))
+	assert.True(t, report.TotalCredit.Equal(decimal.NewFromInt(1500)))
+
+	report, err = acc.TrialBalance(ctx, asOf, "IDR")
+	assert.NoError(t, err)
+	assert.True(t, report.IsBalanced())
+	assert.True(t, report.Lines[0].Debit.Equal(decimal.NewFromInt(1000)))
+	assert.True(t, report.Lines[1].Debit.IsZero())
+	assert.True(t, report.TotalDebit.Equal(decimal.NewFromInt(1000)))
+
+	report, err = acc.TrialBalance(ctx, time.Now(), "GOLD")
+	assert.NoError(t, err)
+	assert.Len(t, report.Lines, 1)
+	assert.True(t, report.IsBalanced())
+
+	_, err = acc.TrialBalance(ctx, time.Now(), "")
+	assert.ErrorIs(t, err, ErrReportMissingCurrency)
+}
+
+func TestAccounting_TrialBalance(t *testing.T) {
+	testTrialBalance(t, newTestAccounting(NewInMemoryStore()))
+}
+
+func TestAccounting_TrialBalanceSQL(t *testing.T) {
+	testTrialBalance(t, newTestSQLAccounting(newTestSQLStore(t)))
+}
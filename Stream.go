@@ -0,0 +1,78 @@
+package acccore
+
+import (
+	"context"
+	"iter"
+)
+
+const (
+	// streamBatchSize is the number of items fetched at once from the store when streaming.
+	streamBatchSize = 100
+)
+
+// streamBatches streams the items of the batches returned by the next function, until it reports there are no more batches.
+// The context is checked before fetching each batch and before yielding each item, a cancelled context
+// yields the context error and ends the stream. So does any error returned by the next function.
+func streamBatches[T any](context context.Context, next func() (batch []T, more bool, err error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for {
+			if err := context.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			batch, more, err := next()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range batch {
+				if err := context.Err(); err != nil {
+					yield(zero, err)
+					return
+				}
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !more {
+				return
+			}
+		}
+	}
+}
+
+// streamCursor streams all the items of a cursor paginated listing, fetching them page by page using the list function.
+// Every range over the stream starts again from the first page.
+func streamCursor[T any](context context.Context, list func(request CursorRequest) (CursorResult, []T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		request := CursorRequest{ItemSize: streamBatchSize}
+		streamBatches(context, func() ([]T, bool, error) {
+			result, items, err := list(request)
+			if err != nil {
+				return nil, false, err
+			}
+			request.Cursor = result.NextCursor
+			return items, result.HaveNext, nil
+		})(yield)
+	}
+}
+
+// streamAccounts streams all the accounts ordered by their account number, fetching them in batches
+// of the accounts after an account number using the list function.
+// Every range over the stream starts again from the first account.
+func streamAccounts(context context.Context, list func(afterAccountNumber string, limit int) ([]Account, error)) iter.Seq2[Account, error] {
+	return func(yield func(Account, error) bool) {
+		after := ""
+		streamBatches(context, func() ([]Account, bool, error) {
+			accounts, err := list(after, streamBatchSize)
+			if err != nil {
+				return nil, false, err
+			}
+			if len(accounts) > 0 {
+				after = accounts[len(accounts)-1].GetAccountNumber()
+			}
+			return accounts, len(accounts) == streamBatchSize, nil
+		})(yield)
+	}
+}
@@ -1,9 +1,10 @@
 module github.com/hyperjumptech/acccore
 
-go 1.22
+go 1.23
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/shopspring/decimal v1.4.0
 	github.com/sirupsen/logrus v1.9.3
@@ -0,0 +1,7 @@
+DROP TABLE acccore_currency;
+
+DROP TABLE acccore_transaction;
+
+DROP TABLE acccore_account;
+
+DROP TABLE acccore_journal;
@@ -0,0 +1,59 @@
+-- Creates the journal, account, transaction and currency tables.
+
+CREATE TABLE acccore_journal (
+    journal_id          VARCHAR(64)     NOT NULL PRIMARY KEY,
+    journaling_time     TIMESTAMP       NOT NULL,
+    description         VARCHAR(255)    NOT NULL,
+    reversal            BOOLEAN         NOT NULL,
+    reversed_journal_id VARCHAR(64)     NOT NULL,
+    amount              DECIMAL(38, 12) NOT NULL,
+    create_time         TIMESTAMP       NOT NULL,
+    create_by           VARCHAR(64)     NOT NULL
+);
+
+CREATE INDEX acccore_journal_time_idx ON acccore_journal (journaling_time);
+
+CREATE INDEX acccore_journal_reversed_idx ON acccore_journal (reversed_journal_id);
+
+CREATE TABLE acccore_account (
+    account_number VARCHAR(64)     NOT NULL PRIMARY KEY,
+    currency       VARCHAR(16)     NOT NULL,
+    name           VARCHAR(255)    NOT NULL,
+    description    VARCHAR(255)    NOT NULL,
+    alignment      INTEGER         NOT NULL,
+    balance        DECIMAL(38, 12) NOT NULL,
+    coa            VARCHAR(64)     NOT NULL,
+    create_time    TIMESTAMP       NOT NULL,
+    create_by      VARCHAR(64)     NOT NULL,
+    update_time    TIMESTAMP       NOT NULL,
+    update_by      VARCHAR(64)     NOT NULL
+);
+
+CREATE INDEX acccore_account_coa_idx ON acccore_account (coa);
+
+CREATE TABLE acccore_transaction (
+    transaction_id   VARCHAR(64)     NOT NULL PRIMARY KEY,
+    transaction_time TIMESTAMP       NOT NULL,
+    account_number   VARCHAR(64)     NOT NULL,
+    journal_id       VARCHAR(64)     NOT NULL,
+    description      VARCHAR(255)    NOT NULL,
+    alignment        INTEGER         NOT NULL,
+    amount           DECIMAL(38, 12) NOT NULL,
+    account_balance  DECIMAL(38, 12) NOT NULL,
+    create_time      TIMESTAMP       NOT NULL,
+    create_by        VARCHAR(64)     NOT NULL
+);
+
+CREATE INDEX acccore_transaction_account_idx ON acccore_transaction (account_number, transaction_time);
+
+CREATE INDEX acccore_transaction_journal_idx ON acccore_transaction (journal_id);
+
+CREATE TABLE acccore_currency (
+    code        VARCHAR(16)     NOT NULL PRIMARY KEY,
+    name        VARCHAR(255)    NOT NULL,
+    exchange    DECIMAL(38, 12) NOT NULL,
+    create_time TIMESTAMP       NOT NULL,
+    create_by   VARCHAR(64)     NOT NULL,
+    update_time TIMESTAMP       NOT NULL,
+    update_by   VARCHAR(64)     NOT NULL
+);
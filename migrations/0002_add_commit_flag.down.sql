@@ -0,0 +1,7 @@
+DELETE FROM acccore_transaction WHERE committed = FALSE;
+
+DELETE FROM acccore_journal WHERE committed = FALSE;
+
+ALTER TABLE acccore_transaction DROP COLUMN committed;
+
+ALTER TABLE acccore_journal DROP COLUMN committed;
@@ -0,0 +1,6 @@
+-- Adds the committed flag on journals and transactions, to support two phase commit.
+-- Records written before this migration were already committed.
+
+ALTER TABLE acccore_journal ADD COLUMN committed BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE acccore_transaction ADD COLUMN committed BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1 @@
+ALTER TABLE acccore_account DROP COLUMN version;
@@ -0,0 +1,3 @@
+-- Adds the version of accounts, used for optimistic concurrency control.
+
+ALTER TABLE acccore_account ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
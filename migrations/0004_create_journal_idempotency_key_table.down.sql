@@ -0,0 +1 @@
+DROP TABLE acccore_journal_idempotency_key;
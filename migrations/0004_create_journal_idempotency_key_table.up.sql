@@ -0,0 +1,8 @@
+-- Creates the table of journal idempotency keys, the primary key keeps each key used by one journal only.
+
+CREATE TABLE acccore_journal_idempotency_key (
+    idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY,
+    journal_id      VARCHAR(64)  NOT NULL
+);
+
+CREATE INDEX acccore_journal_idempotency_key_journal_idx ON acccore_journal_idempotency_key (journal_id);
@@ -0,0 +1,14 @@
+-- Creates the chart of accounts table.
+
+CREATE TABLE acccore_coa (
+    code        VARCHAR(64)  NOT NULL PRIMARY KEY,
+    parent_code VARCHAR(64)  NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    category    INTEGER      NOT NULL,
+    alignment   INTEGER      NOT NULL,
+    create_time TIMESTAMP    NOT NULL,
+    create_by   VARCHAR(64)  NOT NULL
+);
+
+CREATE INDEX acccore_coa_parent_idx ON acccore_coa (parent_code);
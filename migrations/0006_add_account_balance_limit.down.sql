@@ -0,0 +1,3 @@
+ALTER TABLE acccore_account DROP COLUMN overdraft_limit;
+
+ALTER TABLE acccore_account DROP COLUMN balance_limit;
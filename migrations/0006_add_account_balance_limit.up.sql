@@ -0,0 +1,6 @@
+-- Adds the balance limit and overdraft limit of accounts.
+-- Accounts created before this migration keep an unlimited balance.
+
+ALTER TABLE acccore_account ADD COLUMN balance_limit INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE acccore_account ADD COLUMN overdraft_limit DECIMAL(38, 12) NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE acccore_account_state_change;
+
+ALTER TABLE acccore_account DROP COLUMN state;
@@ -0,0 +1,15 @@
+-- Adds the lifecycle state of accounts, and the audit trail of their state changes.
+-- Accounts created before this migration are active.
+
+ALTER TABLE acccore_account ADD COLUMN state INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE acccore_account_state_change (
+    account_number VARCHAR(64)  NOT NULL,
+    version        BIGINT       NOT NULL,
+    from_state     INTEGER      NOT NULL,
+    to_state       INTEGER      NOT NULL,
+    reason         VARCHAR(255) NOT NULL,
+    change_time    TIMESTAMP    NOT NULL,
+    change_by      VARCHAR(64)  NOT NULL,
+    PRIMARY KEY (account_number, version)
+);
@@ -0,0 +1,19 @@
+-- Creates the fund hold table.
+
+CREATE TABLE acccore_hold (
+    hold_id         VARCHAR(64)     NOT NULL PRIMARY KEY,
+    account_number  VARCHAR(64)     NOT NULL,
+    description     VARCHAR(255)    NOT NULL,
+    amount          DECIMAL(38, 12) NOT NULL,
+    captured_amount DECIMAL(38, 12) NOT NULL,
+    state           INTEGER         NOT NULL,
+    expire_time     TIMESTAMP       NOT NULL,
+    create_time     TIMESTAMP       NOT NULL,
+    create_by       VARCHAR(64)     NOT NULL,
+    update_time     TIMESTAMP       NOT NULL,
+    update_by       VARCHAR(64)     NOT NULL
+);
+
+CREATE INDEX acccore_hold_account_idx ON acccore_hold (account_number, state, expire_time);
+
+CREATE INDEX acccore_hold_expire_idx ON acccore_hold (state, expire_time);
@@ -0,0 +1 @@
+ALTER TABLE acccore_journal DROP COLUMN exchange_rate;
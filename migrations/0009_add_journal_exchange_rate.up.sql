@@ -0,0 +1,4 @@
+-- Adds the exchange rate applied by cross-currency journals.
+-- Journals created before this migration are single currency, and keep a zero rate.
+
+ALTER TABLE acccore_journal ADD COLUMN exchange_rate DECIMAL(38, 12) NOT NULL DEFAULT 0;
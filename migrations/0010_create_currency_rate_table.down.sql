@@ -0,0 +1 @@
+DROP TABLE acccore_currency_rate;
@@ -0,0 +1,14 @@
+-- Adds the history of the currencies exchange value.
+-- The exchange value of the currencies created before this migration is known to be in effect since their last update.
+
+CREATE TABLE acccore_currency_rate (
+    code       VARCHAR(16)     NOT NULL,
+    exchange   DECIMAL(38, 12) NOT NULL,
+    valid_from TIMESTAMP       NOT NULL,
+    valid_to   TIMESTAMP,
+    create_by  VARCHAR(64)     NOT NULL,
+    PRIMARY KEY (code, valid_from)
+);
+
+INSERT INTO acccore_currency_rate (code, exchange, valid_from, valid_to, create_by)
+SELECT code, exchange, update_time, NULL, update_by FROM acccore_currency;
@@ -0,0 +1,3 @@
+ALTER TABLE acccore_currency DROP COLUMN rounding_mode;
+
+ALTER TABLE acccore_currency DROP COLUMN scale;
@@ -0,0 +1,6 @@
+-- Adds the scale and rounding mode of currencies.
+-- Currencies created before this migration are not rounded.
+
+ALTER TABLE acccore_currency ADD COLUMN scale INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE acccore_currency ADD COLUMN rounding_mode INTEGER NOT NULL DEFAULT 0;
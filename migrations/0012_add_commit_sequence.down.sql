@@ -0,0 +1,5 @@
+ALTER TABLE acccore_transaction DROP COLUMN commit_sequence;
+
+ALTER TABLE acccore_journal DROP COLUMN commit_sequence;
+
+DROP TABLE acccore_commit_sequence;
@@ -0,0 +1,15 @@
+-- Adds the commit sequence of journals and transactions, the order they became visible in, used to list them by cursor.
+-- The sequence is drawn from a single counter row that stays locked until the committing transaction ends,
+-- so sequences are handed out in the same order the commits become visible.
+-- This serializes all the commits on the counter row, which bounds the commit throughput of the database.
+-- Records committed before this migration keep a zero sequence, and are listed first by their time.
+
+CREATE TABLE acccore_commit_sequence (
+    last_sequence BIGINT NOT NULL
+);
+
+INSERT INTO acccore_commit_sequence (last_sequence) VALUES (0);
+
+ALTER TABLE acccore_journal ADD COLUMN commit_sequence BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE acccore_transaction ADD COLUMN commit_sequence BIGINT NOT NULL DEFAULT 0;